@@ -9,6 +9,7 @@ import (
 	"backend/internal/app"
 	"backend/internal/config"
 	"backend/internal/config/database"
+	"backend/internal/shared/crecimiento"
 )
 
 func main() {
@@ -32,6 +33,16 @@ func main() {
 	// Crear una nueva instancia de la aplicación, delegando toda la configuración.
 	servidor := app.New(cfg, gestor)
 
+	// Las tablas propias de la API se crean con migraciones\aplicar.bat
+	if err := servidor.VerificarEsquema(); err != nil {
+		log.Fatalf("Error al verificar el esquema de la base de datos: %v", err)
+	}
+
+	// Las tablas de la OMS se embeben al compilar (ver internal/shared/crecimiento/tablas/LEEME.md)
+	if err := crecimiento.VerificarTablas(); err != nil {
+		log.Fatalf("Error al cargar las tablas de crecimiento: %v", err)
+	}
+
 	// Cerrar el servidor al recibir una señal de terminación.
 	detener := make(chan os.Signal, 1)
 	signal.Notify(detener, os.Interrupt, syscall.SIGTERM)
@@ -4,5 +4,7 @@ set DB_PASSWORD=contrasena_segura_db
 set JWT_ACCESS_SECRET=mi_secreto_para_access_tokens_muy_seguro_123456
 set JWT_REFRESH_SECRET=mi_secreto_para_refresh_tokens_super_seguro_789012
 set SESSION_SECRET=clave_sesion_segura
+REM Solo si jwt.claves apunta a un juego de claves con claves HS256
+set JWT_CLAVE_2026_10=secreto_hs256_de_al_menos_32_caracteres_2026_10
 
 echo [env] cargadas con exito.
@@ -3,7 +3,10 @@ package app
 import (
 	"backend/internal/config"
 	"backend/internal/config/database"
+	"backend/internal/shared/errores"
+	"backend/internal/shared/services/auditoria"
 	"context"
+	"errors"
 	"log"
 	"time"
 
@@ -27,6 +30,14 @@ func ErroresGlobales(c *fiber.Ctx, err error) error {
 		}
 	}
 
+	// Si el error es de la API, respetar su código, tipo y detalles
+	var apiErr *errores.ErrorApi
+	if errors.As(err, &apiErr) {
+		code = apiErr.Status
+		tipo = apiErr.Tipo
+		mensaje = apiErr.Mensaje
+	}
+
 	// Log interno (solo para servidor)
 	if code == fiber.StatusInternalServerError {
 		log.Printf(
@@ -48,6 +59,10 @@ func ErroresGlobales(c *fiber.Ctx, err error) error {
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
 
+	if apiErr != nil && apiErr.Detalles != nil {
+		respuesta["detalles"] = apiErr.Detalles
+	}
+
 	// Añadir detalles del error solo en entorno de desarrollo para errores 500
 	cfg := config.Obtener()
 	if cfg != nil && cfg.App.AppEnv == "dev" && code == fiber.StatusInternalServerError {
@@ -82,6 +97,7 @@ func VerificarApi(db *database.GestorDB) fiber.Handler {
 						"DB_SIGH":         ESTADO_DB_PRINCIPAL,
 						"DB_SIGH_EXTERNA": "lazy",
 					},
+					"auditoria": auditoria.ObtenerMetricas(),
 				},
 			},
 		})
@@ -2,6 +2,8 @@ package app
 
 import (
 	"backend/internal/config"
+	"backend/internal/shared/estaciones"
+	"backend/internal/shared/middlewares"
 	"strings"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -17,6 +19,31 @@ func ConfigurarMiddlewares(app *fiber.App, cfg *config.Config) {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: strings.Join(cfg.App.CorsOrigins, ","),
 		AllowMethods: "GET,POST,PUT,DELETE,PATCH,OPTIONS",
-		AllowHeaders: "Origin,Content-Type,Accept,Authorization",
+		AllowHeaders: strings.Join(encabezadosPermitidos(cfg), ","),
+		// El modo cookie necesita credenciales; el navegador no las admite con el comodín "*"
+		AllowCredentials: permiteCredenciales(cfg.App.CorsOrigins),
 	}))
 }
+
+// encabezadosPermitidos incluye los encabezados propios de la API que envían los clientes
+// web: el token CSRF del modo cookie, la clave de API y el nombre de la estación
+func encabezadosPermitidos(cfg *config.Config) []string {
+	estacion := cfg.Estacion.Encabezado
+	if estacion == "" {
+		estacion = estaciones.EncabezadoPorDefecto
+	}
+	return []string{
+		"Origin", "Content-Type", "Accept", "Authorization",
+		middlewares.EncabezadoCsrf, middlewares.EncabezadoClaveApi, estacion,
+	}
+}
+
+// permiteCredenciales indica si los orígenes configurados son explícitos
+func permiteCredenciales(origenes []string) bool {
+	for _, origen := range origenes {
+		if strings.TrimSpace(origen) == "*" {
+			return false
+		}
+	}
+	return len(origenes) > 0
+}
@@ -1,16 +1,90 @@
 package app
 
 import (
+	"log"
+	"time"
+
+	"backend/internal/config"
 	"backend/internal/config/database"
+	"backend/internal/modules/anemia"
+	"backend/internal/modules/auditoria"
+	"backend/internal/modules/autenticacion"
+	"backend/internal/modules/clavesapi"
+	"backend/internal/modules/elegibilidad"
 	"backend/internal/modules/triaje"
+	sharedDB "backend/internal/shared/database"
+	"backend/internal/shared/estaciones"
+	"backend/internal/shared/middlewares"
+	sharedAtenciones "backend/internal/shared/services/atenciones"
+	sharedAuditoria "backend/internal/shared/services/auditoria"
+	sharedClaves "backend/internal/shared/services/clavesapi"
+	sharedElegibilidad "backend/internal/shared/services/elegibilidad"
+	"backend/internal/shared/services/permisos"
+	"backend/internal/shared/sesiones"
+	"backend/internal/shared/tokens"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func ConfigurarRutas(router *fiber.App, db *database.GestorDB) {
+func ConfigurarRutas(router *fiber.App, cfg *config.Config, db *database.GestorDB) {
+	gestorSesiones := sesiones.NuevoGestor(tokens.NuevoGestor(cfg.JWT), almacenSesiones(cfg, db), sesiones.ConfigCookies{
+		Secreto:     cfg.Security.SessionSecret,
+		Inactividad: time.Duration(cfg.JWT.AccessTokenExpiration) * time.Second,
+		Maxima:      time.Duration(cfg.JWT.RefreshTokenExpiration) * time.Second,
+	})
+	permisosServicio := servicioPermisos(cfg, db)
+	servicioDB := sharedDB.NuevoServicio(db)
+	clavesServicio := sharedClaves.NuevoServicio(servicioDB, sharedAuditoria.NuevoServicio(servicioDB), permisosServicio, cfg.ClavesApi.IdEmpleadoServicio)
+	moduloAutenticacion := autenticacion.NuevoModulo(db, gestorSesiones, permisosServicio)
+	elegibilidadServicio := servicioElegibilidad(cfg, db)
+
 	api := router.Group("/api")
+
+	// La estación de trabajo se registra en la auditoría de toda solicitud
+	api.Use(middlewares.Estacion(estaciones.NuevaCadena(cfg.Estacion, nil)))
+
+	// Rutas públicas
 	api.Get("/", VerificarApi(db))
+	moduloAutenticacion.RegistrarRutas(api)
+
+	// Toda ruta registrada después requiere un token de acceso válido
+	api.Use(middlewares.Autenticacion(gestorSesiones, middlewares.NuevasOpcionesCookie(cfg.Security), clavesServicio))
+	requiere := middlewares.Autorizacion(permisosServicio)
+	audita := middlewares.Auditoria(sharedAuditoria.NuevoServicio(servicioDB))
 
 	// Registro de módulos de la API
-	triaje.NuevoModulo(db).RegistrarRutas(api)
+	moduloAutenticacion.RegistrarRutasProtegidas(api, requiere, audita)
+	triaje.NuevoModulo(db, elegibilidadServicio).RegistrarRutas(api, requiere, audita)
+	anemia.NuevoModulo(db, elegibilidadServicio).RegistrarRutas(api, requiere)
+	elegibilidad.NuevoModulo(elegibilidadServicio).RegistrarRutas(api, requiere)
+	clavesapi.NuevoModulo(clavesServicio).RegistrarRutas(api, requiere)
+	auditoria.NuevoModulo(db).RegistrarRutas(api, requiere)
+}
+
+// almacenSesiones elige dónde se persisten las sesiones según security.almacen_sesiones
+func almacenSesiones(cfg *config.Config, db *database.GestorDB) sesiones.Almacen {
+	if cfg.Security.AlmacenSesiones == "memoria" {
+		log.Println("[Sesiones] Usando almacén en memoria: las sesiones se pierden al reiniciar")
+		return sesiones.NuevoAlmacenMemoria()
+	}
+	return sesiones.NuevoAlmacenSQL(sharedDB.NuevoServicio(db))
+}
+
+// servicioPermisos crea el servicio de permisos según la sección permisos de config.yml
+func servicioPermisos(cfg *config.Config, db *database.GestorDB) *permisos.PermisosServicio {
+	ruta := permisos.RutaPorDefecto
+	if cfg.Permisos.Mapa != "" {
+		ruta = cfg.Permisos.Mapa
+	}
+	return permisos.NuevoServicio(sharedDB.NuevoServicio(db), ruta, time.Duration(cfg.Permisos.CacheSegundos)*time.Second)
+}
+
+// servicioElegibilidad crea el único servicio de elegibilidad, y con él un solo recargador
+// del archivo de políticas, que comparten triaje, anemia y la consulta de elegibilidad
+func servicioElegibilidad(cfg *config.Config, db *database.GestorDB) *sharedElegibilidad.ElegibilidadServicio {
+	ruta := sharedElegibilidad.RutaPorDefecto
+	if cfg.Elegibilidad.Politicas != "" {
+		ruta = cfg.Elegibilidad.Politicas
+	}
+	return sharedElegibilidad.NuevoServicio(sharedAtenciones.NuevoServicio(sharedDB.NuevoServicio(db)), ruta)
 }
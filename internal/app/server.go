@@ -1,19 +1,32 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"backend/internal/config"
 	"backend/internal/config/database"
+	sharedDB "backend/internal/shared/database"
+	"backend/internal/shared/services/auditoria"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// tiempoApagado es el plazo para que terminen las solicitudes en curso al apagar
+const tiempoApagado = 10 * time.Second
+
+// plazoVerificacion limita la verificación del esquema al iniciar
+const plazoVerificacion = 15 * time.Second
+
 type App struct {
 	Fiber  *fiber.App
 	Config *config.Config
 	Db     *database.GestorDB
+	// Auditoria es la cola asíncrona de auditoría; nil si se registra de forma síncrona
+	Auditoria *auditoria.ColaAuditoria
 }
 
 func New(cfg *config.Config, db *database.GestorDB) *App {
@@ -24,13 +37,60 @@ func New(cfg *config.Config, db *database.GestorDB) *App {
 	})
 
 	ConfigurarMiddlewares(app, cfg)
-	ConfigurarRutas(app, db)
+	auditoria.ConfigurarCambios(auditoria.ConfigCambios{
+		Longitud:  cfg.Auditoria.LongitudObservaciones,
+		Sensibles: cfg.Auditoria.CamposSensibles,
+	})
+	ConfigurarRutas(app, cfg, db)
 
 	return &App{
-		Fiber:  app,
-		Config: cfg,
-		Db:     db,
+		Fiber:     app,
+		Config:    cfg,
+		Db:        db,
+		Auditoria: colaAuditoria(cfg, db),
+	}
+}
+
+// colaAuditoria inicia la cola asíncrona según la sección auditoria de config.yml
+func colaAuditoria(cfg *config.Config, db *database.GestorDB) *auditoria.ColaAuditoria {
+	if !cfg.Auditoria.Asincrona {
+		return nil
+	}
+
+	cola, err := auditoria.IniciarCola(sharedDB.NuevoServicio(db), auditoria.ConfigCola{
+		Capacidad:    cfg.Auditoria.Capacidad,
+		Trabajadores: cfg.Auditoria.Trabajadores,
+		Lote:         cfg.Auditoria.Lote,
+		Spool:        cfg.Auditoria.Spool,
+	})
+	if err != nil {
+		log.Printf("[Auditoria] No se pudo iniciar la cola; la auditoría será síncrona: %v", err)
+		return nil
 	}
+	return cola
+}
+
+// VerificarEsquema comprueba que existan las tablas propias de la API, que se crean con
+// migraciones/aplicar.bat. Retorna error solo si faltan tablas; si la base de datos no
+// responde lo registra y deja iniciar la API, como con el resto de las dependencias.
+func (a *App) VerificarEsquema() error {
+	tablas := make([]sharedDB.Tabla, 0, len(sharedDB.TablasApi))
+	for _, tabla := range sharedDB.TablasApi {
+		if tabla.Nombre == "SesionesRefresco" && a.Config.Security.AlmacenSesiones == "memoria" {
+			continue
+		}
+		tablas = append(tablas, tabla)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), plazoVerificacion)
+	defer cancel()
+
+	err := sharedDB.NuevoServicio(a.Db).VerificarTablas(ctx, tablas, false)
+	if err != nil && !errors.Is(err, sharedDB.ErrEsquemaIncompleto) {
+		log.Printf("[Database] No se pudo verificar el esquema: %v", err)
+		return nil
+	}
+	return err
 }
 
 // Run inicia el servidor escuchando en el puerto configurado
@@ -42,5 +102,16 @@ func (a *App) Run() error {
 
 func (a *App) Shutdown() error {
 	log.Println("🛑 Apagando servidor HTTP...")
-	return a.Fiber.Shutdown()
+	// Las conexiones SSE de la cola de triaje no terminan solas; se cierran tras el plazo
+	err := a.Fiber.ShutdownWithTimeout(tiempoApagado)
+
+	// La auditoría de las últimas solicitudes se envía o queda en el spool
+	if a.Auditoria != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), tiempoApagado)
+		defer cancel()
+		if errCola := a.Auditoria.Cerrar(ctx); errCola != nil && err == nil {
+			err = errCola
+		}
+	}
+	return err
 }
\ No newline at end of file
@@ -9,21 +9,42 @@ import (
 )
 
 type Config struct {
-	JWT      JWTConfig      `yaml:"jwt"`
-	Security SecurityConfig `yaml:"security"`
-	App      AppConfig      `yaml:"app"`
+	JWT             JWTConfig             `yaml:"jwt"`
+	Security        SecurityConfig        `yaml:"security"`
+	App             AppConfig             `yaml:"app"`
+	Triaje          TriajeConfig          `yaml:"triaje"`
+	Establecimiento EstablecimientoConfig `yaml:"establecimiento"`
+	Elegibilidad    ElegibilidadConfig    `yaml:"elegibilidad"`
+	Permisos        PermisosConfig        `yaml:"permisos"`
+	ClavesApi       ClavesApiConfig       `yaml:"claves_api"`
+	Auditoria       AuditoriaConfig       `yaml:"auditoria"`
+	Estacion        EstacionConfig        `yaml:"estacion"`
 }
 
 type JWTConfig struct {
-	AccessSecret            string `yaml:"access_secret"`
-	RefreshSecret           string `yaml:"refresh_secret"`
-	AccessTokenExpiration   int    `yaml:"access_token_expiration_seconds"`
-	RefreshTokenExpiration  int    `yaml:"refresh_token_expiration_seconds"`
+	AccessSecret           string `yaml:"access_secret"`
+	RefreshSecret          string `yaml:"refresh_secret"`
+	AccessTokenExpiration  int    `yaml:"access_token_expiration_seconds"`
+	RefreshTokenExpiration int    `yaml:"refresh_token_expiration_seconds"`
+	// Claves es la ruta opcional del juego de claves con kid (ver claves_jwt.example.yml);
+	// si se define, los tokens nuevos se firman con su clave activa y los secretos
+	// anteriores solo verifican los tokens sin kid aún vigentes
+	Claves string `yaml:"claves"`
 }
 
 type SecurityConfig struct {
 	SessionSecret  string `yaml:"session_secret"`
 	HashSaltRounds int    `yaml:"hash_salt_rounds"`
+	// AlmacenSesiones es "sqlserver" (por defecto) o "memoria" para pruebas
+	AlmacenSesiones string `yaml:"almacen_sesiones"`
+	// Atributos de las cookies del modo cookie; SameSite es Lax, Strict o None
+	CookieSegura   bool   `yaml:"cookie_segura"`
+	CookieSameSite string `yaml:"cookie_same_site"`
+	// Fallos de login seguidos que bloquean la cuenta y duración del bloqueo
+	IntentosLogin  int `yaml:"intentos_login"`
+	BloqueoMinutos int `yaml:"bloqueo_minutos"`
+	// Duración máxima de una suplencia de un supervisor a nombre de su personal
+	SuplenciaMinutos int `yaml:"suplencia_minutos"`
 }
 
 type AppConfig struct {
@@ -33,6 +54,45 @@ type AppConfig struct {
 	AppEnv      string   `yaml:"app_env"`
 }
 
+type TriajeConfig struct {
+	ReglasPrioridad string `yaml:"reglas_prioridad"`
+	RangosSignos    string `yaml:"rangos_signos"`
+}
+
+type EstablecimientoConfig struct {
+	AltitudMsnm int `yaml:"altitud_msnm"`
+}
+
+type ElegibilidadConfig struct {
+	Politicas string `yaml:"politicas"`
+}
+
+type ClavesApiConfig struct {
+	// IdEmpleadoServicio es la cuenta de SIGH a cuyo nombre se auditan las solicitudes
+	// autenticadas con clave de API; con 0 las claves quedan deshabilitadas
+	IdEmpleadoServicio int `yaml:"id_empleado_servicio"`
+}
+
+// AuditoriaConfig define la cola asíncrona de auditoría y las observaciones con
+// diferencias; con Asincrona en false cada registro ejecuta AuditoriaAgregarV dentro de
+// la solicitud
+type AuditoriaConfig struct {
+	Asincrona    bool   `yaml:"asincrona"`
+	Capacidad    int    `yaml:"capacidad"`
+	Trabajadores int    `yaml:"trabajadores"`
+	Lote         int    `yaml:"lote"`
+	Spool        string `yaml:"spool"`
+	// LongitudObservaciones es el tamaño de la columna observaciones de Auditoria
+	LongitudObservaciones int `yaml:"longitud_observaciones"`
+	// CamposSensibles se enmascaran en las diferencias de las modificaciones
+	CamposSensibles []string `yaml:"campos_sensibles"`
+}
+
+type PermisosConfig struct {
+	Mapa          string `yaml:"mapa"`
+	CacheSegundos int    `yaml:"cache_segundos"`
+}
+
 var (
 	cfg     *Config
 	cfgOnce sync.Once
@@ -69,3 +129,19 @@ func Cargar(ruta string) (*Config, error) {
 func Obtener() *Config {
 	return cfg
 }
+
+// EstacionConfig define cómo se resuelve el nombrePC de la auditoría
+type EstacionConfig struct {
+	// Encabezado con el nombre de la PC que envían los clientes (por defecto X-Nombre-PC)
+	Encabezado string `yaml:"encabezado"`
+	// RedesClientes son las redes desde las que se acepta el encabezado; vacío no lo acepta
+	RedesClientes []string `yaml:"redes_clientes"`
+	// ProxiesConfiables son las IPs o redes CIDR de los proxies cuyo X-Forwarded-For se acepta
+	ProxiesConfiables []string `yaml:"proxies_confiables"`
+	// DnsInverso habilita la búsqueda del nombre del equipo por su IP
+	DnsInverso           bool `yaml:"dns_inverso"`
+	CacheDnsMinutos      int  `yaml:"cache_dns_minutos"`
+	PlazoDnsMilisegundos int  `yaml:"plazo_dns_milisegundos"`
+	// LongitudMaxima es el tamaño del parámetro nombrePC de AuditoriaAgregarV
+	LongitudMaxima int `yaml:"longitud_maxima"`
+}
@@ -0,0 +1,79 @@
+package anemia
+
+import (
+	"errors"
+
+	"backend/internal/shared/services/anemia"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	servicio *anemia.AnemiaServicio
+}
+
+func NuevoHandler(servicio *anemia.AnemiaServicio) *Handler {
+	return &Handler{servicio: servicio}
+}
+
+// Obtener retorna el resultado de hemoglobina de la atención para triaje y consulta
+func (h *Handler) Obtener(c *fiber.Ctx) error {
+	idAtencion, err := obtenerIdAtencion(c)
+	if err != nil {
+		return err
+	}
+
+	resultado, err := h.servicio.Obtener(c.UserContext(), idAtencion)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   resultado,
+	})
+}
+
+// Registrar guarda o corrige el resultado de hemoglobina de la atención
+func (h *Handler) Registrar(c *fiber.Ctx) error {
+	idAtencion, err := obtenerIdAtencion(c)
+	if err != nil {
+		return err
+	}
+
+	var solicitud anemia.SolicitudHemoglobina
+	if err := c.BodyParser(&solicitud); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "El cuerpo de la solicitud no es válido.")
+	}
+
+	resultado, err := h.servicio.Registrar(c.UserContext(), idAtencion, solicitud)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": true,
+		"data":   resultado,
+	})
+}
+
+func obtenerIdAtencion(c *fiber.Ctx) (int, error) {
+	idAtencion, err := c.ParamsInt("idAtencion")
+	if err != nil || idAtencion <= 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "El N° de cuenta no es válido.")
+	}
+	return idAtencion, nil
+}
+
+// traducirError convierte los errores del servicio en errores HTTP
+func traducirError(err error) error {
+	switch {
+	case errors.Is(err, anemia.ErrTamizajeNoRegistrado):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, anemia.ErrSinPruebaHemoglobina):
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, anemia.ErrHemoglobinaInvalida), errors.Is(err, anemia.ErrGestanteInvalida):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return err
+}
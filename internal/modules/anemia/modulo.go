@@ -0,0 +1,45 @@
+package anemia
+
+import (
+	"backend/internal/config"
+	"backend/internal/config/database"
+	sharedDB "backend/internal/shared/database"
+	"backend/internal/shared/middlewares"
+	"backend/internal/shared/services/anemia"
+	"backend/internal/shared/services/atenciones"
+	"backend/internal/shared/services/auditoria"
+	"backend/internal/shared/services/elegibilidad"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Modulo struct {
+	handler *Handler
+}
+
+// NuevoModulo construye el módulo de tamizaje de anemia
+func NuevoModulo(db *database.GestorDB, elegibilidadServicio *elegibilidad.ElegibilidadServicio) *Modulo {
+	altitud := 0
+	if cfg := config.Obtener(); cfg != nil {
+		altitud = cfg.Establecimiento.AltitudMsnm
+	}
+
+	servicioDB := sharedDB.NuevoServicio(db)
+	atencionesServicio := atenciones.NuevoServicio(servicioDB)
+	servicio := anemia.NuevoServicio(
+		servicioDB,
+		atencionesServicio,
+		auditoria.NuevoServicio(servicioDB),
+		elegibilidadServicio,
+		altitud,
+	)
+
+	return &Modulo{handler: NuevoHandler(servicio)}
+}
+
+// RegistrarRutas registra los endpoints del módulo bajo /anemia
+func (m *Modulo) RegistrarRutas(router fiber.Router, requiere middlewares.Requiere) {
+	grupo := router.Group("/anemia")
+	grupo.Get("/:idAtencion<int>", requiere("anemia:leer"), m.handler.Obtener)
+	grupo.Post("/:idAtencion<int>", requiere("anemia:escribir"), m.handler.Registrar)
+}
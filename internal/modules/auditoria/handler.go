@@ -0,0 +1,97 @@
+package auditoria
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"backend/internal/shared/services/auditoria"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	servicio *auditoria.AuditoriaServicio
+}
+
+func NuevoHandler(servicio *auditoria.AuditoriaServicio) *Handler {
+	return &Handler{servicio: servicio}
+}
+
+// Buscar consulta la auditoría del más reciente al más antiguo. Acepta los filtros
+// idEmpleado, tabla, idRegistro, accion (A|M|E) y el rango desde/hasta (AAAA-MM-DD),
+// además de tamanio y antesDe, el cursor "siguiente" de la página anterior.
+func (h *Handler) Buscar(c *fiber.Ctx) error {
+	filtro := auditoria.FiltroAuditoria{
+		Tabla:   c.Query("tabla"),
+		Accion:  c.Query("accion"),
+		Tamanio: c.QueryInt("tamanio", auditoria.TamanioPaginaPorDefecto),
+	}
+
+	var err error
+	if filtro.IdEmpleado, err = obtenerEntero(c, "idEmpleado"); err != nil {
+		return err
+	}
+	if filtro.IdRegistro, err = obtenerEntero(c, "idRegistro"); err != nil {
+		return err
+	}
+	antesDe, err := obtenerEntero(c, "antesDe")
+	if err != nil {
+		return err
+	}
+	if antesDe != nil {
+		filtro.AntesDe = *antesDe
+	}
+
+	if filtro.Desde, err = obtenerFecha(c, "desde"); err != nil {
+		return err
+	}
+	if filtro.Hasta, err = obtenerFecha(c, "hasta"); err != nil {
+		return err
+	}
+	if filtro.Hasta != nil {
+		// La fecha final se incluye completa
+		siguiente := filtro.Hasta.AddDate(0, 0, 1)
+		filtro.Hasta = &siguiente
+	}
+
+	pagina, err := h.servicio.Buscar(c.UserContext(), filtro)
+	if err != nil {
+		if errors.Is(err, auditoria.ErrFiltroAuditoriaInvalido) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   pagina,
+	})
+}
+
+func obtenerEntero(c *fiber.Ctx, parametro string) (*int, error) {
+	valor := c.Query(parametro)
+	if valor == "" {
+		return nil, nil
+	}
+
+	entero, err := strconv.Atoi(valor)
+	if err != nil || entero <= 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("El parámetro %s debe ser un entero positivo.", parametro))
+	}
+	return &entero, nil
+}
+
+func obtenerFecha(c *fiber.Ctx, parametro string) (*time.Time, error) {
+	valor := c.Query(parametro)
+	if valor == "" {
+		return nil, nil
+	}
+
+	fecha, err := time.ParseInLocation("2006-01-02", valor, time.Local)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("La fecha %s debe tener el formato AAAA-MM-DD.", parametro))
+	}
+	return &fecha, nil
+}
@@ -0,0 +1,26 @@
+package auditoria
+
+import (
+	"backend/internal/config/database"
+	sharedDB "backend/internal/shared/database"
+	"backend/internal/shared/middlewares"
+	"backend/internal/shared/services/auditoria"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Modulo struct {
+	handler *Handler
+}
+
+// NuevoModulo construye el módulo de consulta de la auditoría de SIGH
+func NuevoModulo(db *database.GestorDB) *Modulo {
+	servicio := auditoria.NuevoServicio(sharedDB.NuevoServicio(db))
+	return &Modulo{handler: NuevoHandler(servicio)}
+}
+
+// RegistrarRutas registra los endpoints del módulo bajo /auditoria
+func (m *Modulo) RegistrarRutas(router fiber.Router, requiere middlewares.Requiere) {
+	grupo := router.Group("/auditoria", requiere("auditoria:consultar"))
+	grupo.Get("/", m.handler.Buscar)
+}
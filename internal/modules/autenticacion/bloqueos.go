@@ -0,0 +1,164 @@
+package autenticacion
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/services/auditoria"
+)
+
+// Auditoría de los bloqueos y desbloqueos de cuentas
+const (
+	TablaEmpleados      = "Empleados"
+	IdListItemSeguridad = 1305
+)
+
+// intervaloVencimientos es cada cuánto se auditan los bloqueos vencidos sin nuevo intento
+const intervaloVencimientos = time.Minute
+
+// mensajeDesbloqueoAutomatico es la observación de auditoría al vencer un bloqueo
+const mensajeDesbloqueoAutomatico = "Desbloqueo automático de la cuenta al vencer el bloqueo por intentos fallidos"
+
+var ErrCuentaNoBloqueada = errors.New("la cuenta del empleado no está bloqueada")
+
+// ErrorIntentos rechaza un login por bloqueo de la cuenta o por intentos fallidos recientes
+type ErrorIntentos struct {
+	Bloqueada bool
+	Hasta     time.Time
+	Espera    time.Duration
+}
+
+func (e *ErrorIntentos) Error() string {
+	if e.Bloqueada {
+		return fmt.Sprintf("la cuenta está bloqueada hasta las %s por intentos fallidos", e.Hasta.Format("15:04"))
+	}
+	return "demasiados intentos fallidos; espere antes de volver a intentar"
+}
+
+// autenticar aplica el control de intentos alrededor de la verificación de credenciales:
+// rechaza las cuentas bloqueadas y los reintentos antes de la espera exponencial, cuenta
+// los fallos por usuario e IP y audita el bloqueo y el desbloqueo por vencimiento. El
+// intento queda contado desde Consultar hasta que se conoce el resultado.
+func (s *AutenticacionServicio) autenticar(ctx context.Context, ip string, solicitud SolicitudLogin) (*identidad.Empleado, error) {
+	usuario := strings.TrimSpace(solicitud.Usuario)
+	if usuario == "" || solicitud.Clave == "" {
+		return nil, ErrCredencialesInvalidas
+	}
+
+	estado := s.intentos.Consultar(usuario, ip)
+	if estado.Expirado {
+		s.auditarSistema(ctx, usuario, mensajeDesbloqueoAutomatico)
+	}
+	if estado.Bloqueada {
+		return nil, &ErrorIntentos{Bloqueada: true, Hasta: estado.Hasta, Espera: time.Until(estado.Hasta)}
+	}
+	if estado.Espera > 0 {
+		return nil, &ErrorIntentos{Espera: estado.Espera}
+	}
+
+	empleado, err := s.verificarCredenciales(ctx, usuario, solicitud.Clave)
+	if errors.Is(err, ErrCredencialesInvalidas) {
+		if bloqueada, hasta := s.intentos.RegistrarFallo(usuario, ip); bloqueada {
+			log.Printf("[Autenticacion] Cuenta %q bloqueada hasta %s por intentos fallidos desde %s", usuario, hasta.Format(time.RFC3339), ip)
+			s.auditarSistema(ctx, usuario, fmt.Sprintf(
+				"Cuenta bloqueada tras %d intentos fallidos; último intento desde %s; bloqueada hasta %s",
+				s.intentos.maximo, ip, hasta.Format("2006-01-02 15:04:05"),
+			))
+		}
+		return nil, err
+	}
+	if err != nil {
+		s.intentos.Liberar(usuario, ip)
+		return nil, err
+	}
+
+	s.intentos.RegistrarExito(usuario, ip)
+	return empleado, nil
+}
+
+// VigilarBloqueos audita periódicamente el desbloqueo de las cuentas cuyo bloqueo venció
+// sin que el usuario volviera a intentar; los que sí lo intentan se auditan en autenticar.
+// Se ejecuta hasta que ctx termina.
+func (s *AutenticacionServicio) VigilarBloqueos(ctx context.Context) {
+	temporizador := time.NewTicker(intervaloVencimientos)
+	defer temporizador.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ahora := <-temporizador.C:
+			for _, bloqueo := range s.intentos.Vencidos(ahora) {
+				s.auditarSistema(ctx, bloqueo.Usuario, fmt.Sprintf(
+					"%s (bloqueada hasta %s)", mensajeDesbloqueoAutomatico, bloqueo.Hasta.Format("2006-01-02 15:04:05"),
+				))
+			}
+		}
+	}
+}
+
+// Bloqueos lista las cuentas bloqueadas vigentes
+func (s *AutenticacionServicio) Bloqueos() []Bloqueo {
+	return s.intentos.Bloqueos()
+}
+
+// DesbloquearEmpleado levanta el bloqueo de la cuenta a nombre del administrador del contexto
+func (s *AutenticacionServicio) DesbloquearEmpleado(ctx context.Context, idEmpleado int) error {
+	row := s.db.EjecutarQueryRow(ctx, QueryObtenerUsuarioEmpleado, false, sql.Named("idEmpleado", idEmpleado))
+	if row == nil {
+		return fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	var usuario sql.NullString
+	err := row.Scan(&usuario)
+	if err == sql.ErrNoRows || (err == nil && !usuario.Valid) {
+		return ErrCuentaNoBloqueada
+	}
+	if err != nil {
+		return err
+	}
+
+	if !s.intentos.Desbloquear(usuario.String) {
+		return ErrCuentaNoBloqueada
+	}
+
+	if err := s.auditoria.RegistrarAuditoria(
+		ctx, auditoria.AccionModificar, idEmpleado, TablaEmpleados, IdListItemSeguridad,
+		"Desbloqueo manual de la cuenta bloqueada por intentos fallidos",
+	); err != nil {
+		return fmt.Errorf("error al registrar auditoría: %w", err)
+	}
+	return nil
+}
+
+// auditarSistema registra un evento de seguridad de la cuenta como proceso del sistema,
+// pues no hay empleado autenticado. Los usuarios que no existen en SIGH no se auditan.
+func (s *AutenticacionServicio) auditarSistema(ctx context.Context, usuario string, observaciones string) {
+	row := s.db.EjecutarQueryRow(ctx, QueryObtenerIdEmpleadoPorUsuario, false, sql.Named("usuario", usuario))
+	if row == nil {
+		log.Printf("[Autenticacion] No se pudo auditar el evento de la cuenta %q: sin conexión a la base de datos", usuario)
+		return
+	}
+
+	var idEmpleado int
+	if err := row.Scan(&idEmpleado); err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("[Autenticacion] No se pudo auditar el evento de la cuenta %q: %v", usuario, err)
+		}
+		return
+	}
+
+	err := s.auditoria.RegistrarAuditoria(
+		identidad.ComoSistema(ctx, idEmpleado), auditoria.AccionModificar, idEmpleado, TablaEmpleados, IdListItemSeguridad,
+		observaciones,
+	)
+	if err != nil {
+		log.Printf("[Autenticacion] No se pudo auditar el evento de la cuenta %q: %v", usuario, err)
+	}
+}
@@ -0,0 +1,89 @@
+package autenticacion
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Formatos de clave reconocidos en Empleados.Clave
+const (
+	FormatoBcrypt = "bcrypt"
+	FormatoSHA256 = "sha256"
+	FormatoSHA1   = "sha1"
+	FormatoMD5    = "md5"
+	FormatoPlano  = "plano"
+)
+
+// DetectarFormato identifica cómo está almacenada una clave de SIGH
+func DetectarFormato(almacenada string) string {
+	switch {
+	case strings.HasPrefix(almacenada, "$2a$"), strings.HasPrefix(almacenada, "$2b$"), strings.HasPrefix(almacenada, "$2y$"):
+		return FormatoBcrypt
+	case esHexadecimal(almacenada, sha256.Size*2):
+		return FormatoSHA256
+	case esHexadecimal(almacenada, sha1.Size*2):
+		return FormatoSHA1
+	case esHexadecimal(almacenada, md5.Size*2):
+		return FormatoMD5
+	}
+	return FormatoPlano
+}
+
+func esHexadecimal(valor string, longitud int) bool {
+	if len(valor) != longitud {
+		return false
+	}
+	_, err := hex.DecodeString(valor)
+	return err == nil
+}
+
+// VerificarClave compara la clave ingresada con la almacenada en cualquiera de los formatos reconocidos
+func VerificarClave(almacenada string, clave string) bool {
+	almacenada = strings.TrimSpace(almacenada)
+
+	var esperado, recibido []byte
+	switch DetectarFormato(almacenada) {
+	case FormatoBcrypt:
+		return bcrypt.CompareHashAndPassword([]byte(almacenada), []byte(clave)) == nil
+	case FormatoSHA256:
+		suma := sha256.Sum256([]byte(clave))
+		esperado, recibido = []byte(strings.ToLower(almacenada)), []byte(hex.EncodeToString(suma[:]))
+	case FormatoSHA1:
+		suma := sha1.Sum([]byte(clave))
+		esperado, recibido = []byte(strings.ToLower(almacenada)), []byte(hex.EncodeToString(suma[:]))
+	case FormatoMD5:
+		suma := md5.Sum([]byte(clave))
+		esperado, recibido = []byte(strings.ToLower(almacenada)), []byte(hex.EncodeToString(suma[:]))
+	default:
+		esperado, recibido = []byte(almacenada), []byte(clave)
+	}
+
+	return subtle.ConstantTimeCompare(esperado, recibido) == 1
+}
+
+// HuellaClave resume la clave almacenada en SIGH para detectar si fue cambiada desde
+// la última migración a bcrypt
+func HuellaClave(almacenada string) string {
+	suma := sha256.Sum256([]byte(strings.TrimSpace(almacenada)))
+	return hex.EncodeToString(suma[:])
+}
+
+// costoBcrypt normaliza SecurityConfig.HashSaltRounds al rango aceptado por bcrypt
+func costoBcrypt(rondas int) int {
+	if rondas < bcrypt.MinCost || rondas > bcrypt.MaxCost {
+		return bcrypt.DefaultCost
+	}
+	return rondas
+}
+
+// requiereRehash indica si el hash bcrypt fue generado con un costo distinto al configurado
+func requiereRehash(hash string, costo int) bool {
+	actual, err := bcrypt.Cost([]byte(hash))
+	return err != nil || actual != costo
+}
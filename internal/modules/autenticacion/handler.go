@@ -0,0 +1,264 @@
+package autenticacion
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"backend/internal/shared/errores"
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/middlewares"
+	"backend/internal/shared/services/permisos"
+	"backend/internal/shared/sesiones"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	servicio *AutenticacionServicio
+	permisos *permisos.PermisosServicio
+	cookies  middlewares.OpcionesCookie
+}
+
+func NuevoHandler(servicio *AutenticacionServicio, permisosServicio *permisos.PermisosServicio, cookies middlewares.OpcionesCookie) *Handler {
+	return &Handler{servicio: servicio, permisos: permisosServicio, cookies: cookies}
+}
+
+// Login autentica al empleado con su usuario y clave de SIGH
+func (h *Handler) Login(c *fiber.Ctx) error {
+	var solicitud SolicitudLogin
+	if err := c.BodyParser(&solicitud); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "El cuerpo de la solicitud no es válido.")
+	}
+
+	sesion, err := h.servicio.Login(c.UserContext(), middlewares.IpCliente(c), solicitud)
+	if err != nil {
+		return traducirErrorLogin(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   sesion,
+	})
+}
+
+// Refrescar rota el token de refresco y emite un nuevo par de tokens
+func (h *Handler) Refrescar(c *fiber.Ctx) error {
+	var solicitud SolicitudRefresco
+	if err := c.BodyParser(&solicitud); err != nil || solicitud.RefreshToken == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Debe enviar el refreshToken.")
+	}
+
+	sesion, err := h.servicio.Refrescar(c.UserContext(), solicitud.RefreshToken)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   sesion,
+	})
+}
+
+// Logout revoca la sesión del token de refresco
+func (h *Handler) Logout(c *fiber.Ctx) error {
+	var solicitud SolicitudRefresco
+	if err := c.BodyParser(&solicitud); err != nil || solicitud.RefreshToken == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Debe enviar el refreshToken.")
+	}
+
+	if err := h.servicio.CerrarSesion(c.UserContext(), solicitud.RefreshToken); err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   fiber.Map{"mensaje": "Sesión cerrada correctamente."},
+	})
+}
+
+// Jwks publica las claves RS256 y EdDSA vigentes en formato JWKS (RFC 7517) para que
+// otros sistemas del hospital verifiquen los tokens. Responde el formato estándar, sin
+// la envoltura status/data.
+func (h *Handler) Jwks(c *fiber.Ctx) error {
+	claves, err := h.servicio.ClavesPublicas()
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"keys": claves})
+}
+
+// LoginCookie autentica al empleado y abre una sesión en modo cookie para las páginas
+// de la intranet que no pueden manejar tokens bearer
+func (h *Handler) LoginCookie(c *fiber.Ctx) error {
+	var solicitud SolicitudLogin
+	if err := c.BodyParser(&solicitud); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "El cuerpo de la solicitud no es válido.")
+	}
+
+	valor, sesion, err := h.servicio.LoginCookie(c.UserContext(), middlewares.IpCliente(c), solicitud)
+	if err != nil {
+		return traducirErrorLogin(c, err)
+	}
+
+	h.cookies.EstablecerSesion(c, valor, sesion.CsrfToken, sesion.Expira)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   sesion,
+	})
+}
+
+// LogoutCookie revoca la sesión de la cookie y la elimina del navegador
+func (h *Handler) LogoutCookie(c *fiber.Ctx) error {
+	if !middlewares.CsrfDobleEnvio(c) {
+		return errores.Nuevo(fiber.StatusForbidden, errores.TipoProhibido, "El token CSRF no es válido. Recargue la página e intente nuevamente.")
+	}
+
+	if err := h.servicio.CerrarSesionCookie(c.UserContext(), c.Cookies(middlewares.CookieSesion)); err != nil {
+		return traducirError(err)
+	}
+
+	h.cookies.Limpiar(c)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   fiber.Map{"mensaje": "Sesión cerrada correctamente."},
+	})
+}
+
+// Permisos retorna los roles y permisos del empleado autenticado (o el alcance de la
+// clave de API) para que el cliente muestre solo las opciones que puede usar
+func (h *Handler) Permisos(c *fiber.Ctx) error {
+	empleado, ok := identidad.EmpleadoDesde(c.UserContext())
+	if !ok {
+		return errores.Nuevo(fiber.StatusUnauthorized, errores.TipoNoAutenticado, "Debe iniciar sesión para acceder a este recurso.")
+	}
+
+	conjunto, err := h.permisos.Efectivos(c.UserContext(), empleado)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   conjunto,
+	})
+}
+
+// RevocarSesiones cierra todas las sesiones de un empleado (p. ej. al cesarlo o si
+// reporta el robo de su equipo)
+func (h *Handler) RevocarSesiones(c *fiber.Ctx) error {
+	idEmpleado, err := c.ParamsInt("idEmpleado")
+	if err != nil || idEmpleado <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "El id del empleado no es válido.")
+	}
+
+	resultado, err := h.servicio.RevocarSesionesEmpleado(c.UserContext(), idEmpleado)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   resultado,
+	})
+}
+
+// Bloqueos lista las cuentas bloqueadas por intentos fallidos
+func (h *Handler) Bloqueos(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   h.servicio.Bloqueos(),
+	})
+}
+
+// Desbloquear levanta el bloqueo de la cuenta de un empleado antes de su vencimiento
+func (h *Handler) Desbloquear(c *fiber.Ctx) error {
+	idEmpleado, err := c.ParamsInt("idEmpleado")
+	if err != nil || idEmpleado <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "El id del empleado no es válido.")
+	}
+
+	if err := h.servicio.DesbloquearEmpleado(c.UserContext(), idEmpleado); err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   fiber.Map{"mensaje": "Cuenta desbloqueada correctamente."},
+	})
+}
+
+// IniciarSuplencia emite un token de acceso corto para actuar a nombre de un empleado
+// del servicio que supervisa el empleado autenticado
+func (h *Handler) IniciarSuplencia(c *fiber.Ctx) error {
+	var solicitud SolicitudSuplencia
+	if err := c.BodyParser(&solicitud); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "El cuerpo de la solicitud no es válido.")
+	}
+
+	suplencia, err := h.servicio.IniciarSuplencia(c.UserContext(), solicitud)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   suplencia,
+	})
+}
+
+// FinalizarSuplencia revoca el token de suplencia con el que se autenticó la solicitud
+func (h *Handler) FinalizarSuplencia(c *fiber.Ctx) error {
+	token := middlewares.TokenBearer(c.Get(fiber.HeaderAuthorization))
+	if err := h.servicio.FinalizarSuplencia(c.UserContext(), token); err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   fiber.Map{"mensaje": "Suplencia finalizada correctamente."},
+	})
+}
+
+// traducirErrorLogin agrega a traducirError el rechazo por intentos fallidos, que indica
+// en Retry-After cuántos segundos esperar
+func traducirErrorLogin(c *fiber.Ctx, err error) error {
+	var intentos *ErrorIntentos
+	if !errors.As(err, &intentos) {
+		return traducirError(err)
+	}
+
+	segundos := int(math.Ceil(intentos.Espera.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(segundos))
+
+	if intentos.Bloqueada {
+		return errores.Nuevo(fiber.StatusLocked, errores.TipoCuentaBloqueada,
+			fmt.Sprintf("La cuenta está bloqueada por intentos fallidos hasta las %s. Solicite el desbloqueo al administrador o espere.", intentos.Hasta.Format("15:04")),
+		).ConDetalles(fiber.Map{"hasta": intentos.Hasta, "reintentarEnSegundos": segundos})
+	}
+	return errores.Nuevo(fiber.StatusTooManyRequests, errores.TipoDemasiadosIntentos,
+		fmt.Sprintf("Demasiados intentos fallidos. Espere %d segundos antes de volver a intentar.", segundos),
+	).ConDetalles(fiber.Map{"reintentarEnSegundos": segundos})
+}
+
+// traducirError convierte los errores del servicio en errores HTTP
+func traducirError(err error) error {
+	switch {
+	case errors.Is(err, ErrCredencialesInvalidas), errors.Is(err, sesiones.ErrSesionInvalida):
+		return errores.Nuevo(fiber.StatusUnauthorized, errores.TipoNoAutenticado, err.Error())
+	case errors.Is(err, ErrCuentaNoBloqueada):
+		return fiber.NewError(fiber.StatusNotFound, "La cuenta del empleado no está bloqueada.")
+	case errors.Is(err, ErrMotivoSuplencia):
+		return errores.Nuevo(fiber.StatusBadRequest, errores.TipoValidacion, err.Error())
+	case errors.Is(err, ErrSuplenciaNoPermitida), errors.Is(err, ErrSuplenciaAnidada):
+		return errores.Nuevo(fiber.StatusForbidden, errores.TipoProhibido, err.Error())
+	case errors.Is(err, sesiones.ErrNoEsSuplencia):
+		return fiber.NewError(fiber.StatusBadRequest, "La sesión actual no es una suplencia.")
+	case errors.Is(err, sesiones.ErrReutilizacion):
+		return errores.Nuevo(fiber.StatusUnauthorized, errores.TipoNoAutenticado, "La sesión fue revocada por seguridad. Inicie sesión nuevamente.")
+	}
+	return err
+}
@@ -0,0 +1,258 @@
+package autenticacion
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Valores por defecto cuando config.yml no define security.intentos_login o bloqueo_minutos
+const (
+	intentosPorDefecto = 5
+	bloqueoPorDefecto  = 15 * time.Minute
+)
+
+// La espera entre intentos fallidos se duplica desde el segundo fallo: 1s, 2s, 4s...
+const (
+	retardoBase   = time.Second
+	retardoMaximo = 5 * time.Minute
+)
+
+type registroIntentos struct {
+	fallos         int
+	ultimoFallo    time.Time
+	bloqueadoHasta time.Time
+}
+
+// espera retorna cuánto falta para aceptar el siguiente intento
+func (r *registroIntentos) espera(ahora time.Time) time.Duration {
+	if r.fallos < 2 {
+		return 0
+	}
+
+	retardo := retardoMaximo
+	if r.fallos-2 < 16 {
+		retardo = min(retardoBase<<(r.fallos-2), retardoMaximo)
+	}
+	return max(r.ultimoFallo.Add(retardo).Sub(ahora), 0)
+}
+
+// Bloqueo describe una cuenta bloqueada temporalmente
+type Bloqueo struct {
+	Usuario string    `json:"usuario"`
+	Fallos  int       `json:"fallos"`
+	Hasta   time.Time `json:"hasta"`
+}
+
+// EstadoIntento es el resultado de consultar si se admite un intento de login
+type EstadoIntento struct {
+	// Bloqueada indica que la cuenta está bloqueada hasta Hasta
+	Bloqueada bool
+	Hasta     time.Time
+	// Espera es el tiempo que falta para admitir el intento por los fallos recientes
+	// del usuario o de la dirección IP
+	Espera time.Duration
+	// Expirado indica que un bloqueo anterior de la cuenta venció en esta consulta
+	Expirado bool
+}
+
+// ControlIntentos lleva en memoria los intentos fallidos de login por usuario y por IP.
+// Tras intentosMaximos fallos seguidos la cuenta queda bloqueada durante el tiempo de
+// bloqueo; los contadores se descartan tras ese mismo tiempo sin fallos.
+type ControlIntentos struct {
+	mu       sync.Mutex
+	usuarios map[string]*registroIntentos
+	ips      map[string]*registroIntentos
+	maximo   int
+	bloqueo  time.Duration
+}
+
+func NuevoControlIntentos(intentosMaximos int, bloqueo time.Duration) *ControlIntentos {
+	if intentosMaximos <= 0 {
+		intentosMaximos = intentosPorDefecto
+	}
+	if bloqueo <= 0 {
+		bloqueo = bloqueoPorDefecto
+	}
+	return &ControlIntentos{
+		usuarios: make(map[string]*registroIntentos),
+		ips:      make(map[string]*registroIntentos),
+		maximo:   intentosMaximos,
+		bloqueo:  bloqueo,
+	}
+}
+
+// normalizarUsuario evita eludir el bloqueo cambiando mayúsculas o espacios
+func normalizarUsuario(usuario string) string {
+	return strings.ToLower(strings.TrimSpace(usuario))
+}
+
+// Consultar indica si se admite un intento de login del usuario desde la IP. El intento
+// admitido se cuenta de inmediato como fallido, antes de verificar la clave, para que
+// los intentos simultáneos respeten la espera exponencial; RegistrarFallo lo confirma y
+// RegistrarExito o Liberar lo descuentan.
+func (c *ControlIntentos) Consultar(usuario string, ip string) EstadoIntento {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ahora := time.Now()
+	clave := normalizarUsuario(usuario)
+	var estado EstadoIntento
+
+	if registro, ok := c.usuarios[clave]; ok {
+		if !registro.bloqueadoHasta.IsZero() {
+			if ahora.Before(registro.bloqueadoHasta) {
+				estado.Bloqueada = true
+				estado.Hasta = registro.bloqueadoHasta
+				return estado
+			}
+			delete(c.usuarios, clave)
+			estado.Expirado = true
+		} else {
+			estado.Espera = registro.espera(ahora)
+		}
+	}
+
+	if registro, ok := c.ips[ip]; ok {
+		estado.Espera = max(estado.Espera, registro.espera(ahora))
+	}
+	if estado.Espera > 0 {
+		return estado
+	}
+
+	c.purgar(ahora)
+	for _, registro := range []*registroIntentos{c.registro(c.usuarios, clave), c.registro(c.ips, ip)} {
+		registro.fallos++
+		registro.ultimoFallo = ahora
+	}
+	return estado
+}
+
+// RegistrarFallo confirma como fallido el intento que admitió Consultar. Retorna true y
+// el vencimiento si con este fallo la cuenta quedó bloqueada.
+func (c *ControlIntentos) RegistrarFallo(usuario string, ip string) (bool, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ahora := time.Now()
+
+	// Si el registro se descartó mientras se verificaba la clave, p. ej. por un
+	// desbloqueo manual, el fallo vuelve a contarse
+	porIp := c.registro(c.ips, ip)
+	porIp.fallos = max(porIp.fallos, 1)
+	porIp.ultimoFallo = ahora
+
+	porUsuario := c.registro(c.usuarios, normalizarUsuario(usuario))
+	porUsuario.fallos = max(porUsuario.fallos, 1)
+	porUsuario.ultimoFallo = ahora
+	if porUsuario.fallos >= c.maximo && porUsuario.bloqueadoHasta.IsZero() {
+		porUsuario.bloqueadoHasta = ahora.Add(c.bloqueo)
+		return true, porUsuario.bloqueadoHasta
+	}
+	return false, time.Time{}
+}
+
+// RegistrarExito reinicia los contadores del usuario. De la IP solo descuenta el intento
+// admitido: sus fallos anteriores vencen solos, para que iniciar sesión con una cuenta
+// válida no reinicie la espera de quien prueba claves de otras cuentas desde esa IP.
+func (c *ControlIntentos) RegistrarExito(usuario string, ip string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.usuarios, normalizarUsuario(usuario))
+	descontar(c.ips, ip)
+}
+
+// Liberar descuenta el intento admitido cuya clave no se pudo verificar, p. ej. por un
+// error de la base de datos
+func (c *ControlIntentos) Liberar(usuario string, ip string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	descontar(c.usuarios, normalizarUsuario(usuario))
+	descontar(c.ips, ip)
+}
+
+func descontar(registros map[string]*registroIntentos, clave string) {
+	registro, ok := registros[clave]
+	if !ok || registro.fallos == 0 {
+		return
+	}
+	registro.fallos--
+	if registro.fallos == 0 && registro.bloqueadoHasta.IsZero() {
+		delete(registros, clave)
+	}
+}
+
+// Vencidos retira los bloqueos que vencieron sin un nuevo intento del usuario y los
+// retorna para auditar su desbloqueo automático
+func (c *ControlIntentos) Vencidos(ahora time.Time) []Bloqueo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var vencidos []Bloqueo
+	for usuario, registro := range c.usuarios {
+		if !registro.bloqueadoHasta.IsZero() && !ahora.Before(registro.bloqueadoHasta) {
+			vencidos = append(vencidos, Bloqueo{Usuario: usuario, Fallos: registro.fallos, Hasta: registro.bloqueadoHasta})
+			delete(c.usuarios, usuario)
+		}
+	}
+	return vencidos
+}
+
+// Desbloquear elimina el bloqueo y los fallos del usuario. Retorna false si no estaba bloqueado.
+func (c *ControlIntentos) Desbloquear(usuario string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clave := normalizarUsuario(usuario)
+	registro, ok := c.usuarios[clave]
+	if !ok || registro.bloqueadoHasta.IsZero() || time.Now().After(registro.bloqueadoHasta) {
+		return false
+	}
+
+	delete(c.usuarios, clave)
+	return true
+}
+
+// Bloqueos lista las cuentas bloqueadas vigentes
+func (c *ControlIntentos) Bloqueos() []Bloqueo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ahora := time.Now()
+	bloqueos := []Bloqueo{}
+	for usuario, registro := range c.usuarios {
+		if ahora.Before(registro.bloqueadoHasta) {
+			bloqueos = append(bloqueos, Bloqueo{Usuario: usuario, Fallos: registro.fallos, Hasta: registro.bloqueadoHasta})
+		}
+	}
+	sort.Slice(bloqueos, func(i, j int) bool { return bloqueos[i].Hasta.Before(bloqueos[j].Hasta) })
+	return bloqueos
+}
+
+func (c *ControlIntentos) registro(registros map[string]*registroIntentos, clave string) *registroIntentos {
+	registro, ok := registros[clave]
+	if !ok {
+		registro = &registroIntentos{}
+		registros[clave] = registro
+	}
+	return registro
+}
+
+// purgar descarta los contadores sin fallos recientes. Los bloqueos solo los retiran
+// Consultar y Vencidos, que informan su desbloqueo para auditarlo. Requiere c.mu tomado.
+func (c *ControlIntentos) purgar(ahora time.Time) {
+	limite := ahora.Add(-c.bloqueo)
+	for clave, registro := range c.ips {
+		if registro.ultimoFallo.Before(limite) {
+			delete(c.ips, clave)
+		}
+	}
+	for clave, registro := range c.usuarios {
+		if registro.bloqueadoHasta.IsZero() && registro.ultimoFallo.Before(limite) {
+			delete(c.usuarios, clave)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package autenticacion
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// retroceder adelanta el reloj de los contadores para saltar la espera exponencial
+func retroceder(c *ControlIntentos, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, registros := range []map[string]*registroIntentos{c.usuarios, c.ips} {
+		for _, registro := range registros {
+			registro.ultimoFallo = registro.ultimoFallo.Add(-d)
+		}
+	}
+}
+
+func fallosDe(registros map[string]*registroIntentos, clave string) int {
+	if registro, ok := registros[clave]; ok {
+		return registro.fallos
+	}
+	return 0
+}
+
+func TestConsultarReservaLosIntentosSimultaneos(t *testing.T) {
+	control := NuevoControlIntentos(5, time.Hour)
+
+	var (
+		espera    sync.WaitGroup
+		mu        sync.Mutex
+		admitidos int
+	)
+	for range 20 {
+		espera.Add(1)
+		go func() {
+			defer espera.Done()
+			if estado := control.Consultar("usuario", "10.0.0.9"); !estado.Bloqueada && estado.Espera == 0 {
+				mu.Lock()
+				admitidos++
+				mu.Unlock()
+			}
+		}()
+	}
+	espera.Wait()
+
+	// La espera empieza desde el segundo fallo, aunque ninguno se haya confirmado aún
+	if admitidos != 2 {
+		t.Fatalf("%d intentos admitidos, se esperaban 2", admitidos)
+	}
+}
+
+func TestRegistrarExitoConservaLosFallosDeLaIp(t *testing.T) {
+	control := NuevoControlIntentos(5, time.Hour)
+	const ip = "10.0.0.9"
+
+	for range 3 {
+		retroceder(control, retardoMaximo)
+		if estado := control.Consultar("victima", ip); estado.Espera > 0 {
+			t.Fatalf("intento rechazado con espera %s", estado.Espera)
+		}
+		control.RegistrarFallo("victima", ip)
+	}
+
+	retroceder(control, retardoMaximo)
+	if estado := control.Consultar("propia", ip); estado.Espera > 0 {
+		t.Fatalf("intento rechazado con espera %s", estado.Espera)
+	}
+	control.RegistrarExito("propia", ip)
+
+	if fallos := fallosDe(control.ips, ip); fallos != 3 {
+		t.Fatalf("%d fallos de la IP tras el login correcto, se esperaban 3", fallos)
+	}
+	if fallos := fallosDe(control.usuarios, "propia"); fallos != 0 {
+		t.Fatalf("%d fallos del usuario que inició sesión, se esperaban 0", fallos)
+	}
+	if estado := control.Consultar("otra", ip); estado.Espera == 0 {
+		t.Fatal("la espera de la IP no debe reiniciarse con el login de otra cuenta")
+	}
+}
+
+func TestLiberarDescuentaElIntento(t *testing.T) {
+	control := NuevoControlIntentos(5, time.Hour)
+
+	control.Consultar("usuario", "10.0.0.9")
+	control.Liberar("usuario", "10.0.0.9")
+
+	if len(control.usuarios) != 0 || len(control.ips) != 0 {
+		t.Fatalf("quedaron %d usuarios y %d IPs con intentos", len(control.usuarios), len(control.ips))
+	}
+}
+
+func TestRegistrarFalloBloqueaAlAlcanzarElMaximo(t *testing.T) {
+	control := NuevoControlIntentos(3, time.Hour)
+
+	for i := 1; i <= 3; i++ {
+		retroceder(control, retardoMaximo)
+		if estado := control.Consultar("Usuario ", "10.0.0.9"); estado.Bloqueada || estado.Espera > 0 {
+			t.Fatalf("intento %d rechazado: %+v", i, estado)
+		}
+		bloqueada, _ := control.RegistrarFallo("Usuario ", "10.0.0.9")
+		if bloqueada != (i == 3) {
+			t.Fatalf("intento %d: bloqueada = %v", i, bloqueada)
+		}
+	}
+
+	if estado := control.Consultar("usuario", "10.0.0.10"); !estado.Bloqueada {
+		t.Fatal("la cuenta debe quedar bloqueada desde cualquier IP")
+	}
+	if fallos := fallosDe(control.usuarios, "usuario"); fallos != 3 {
+		t.Fatalf("%d fallos tras el bloqueo, se esperaban 3", fallos)
+	}
+}
@@ -0,0 +1,72 @@
+package autenticacion
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/config/database"
+	sharedDB "backend/internal/shared/database"
+	"backend/internal/shared/middlewares"
+	"backend/internal/shared/services/auditoria"
+	"backend/internal/shared/services/permisos"
+	"backend/internal/shared/sesiones"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Modulo struct {
+	handler *Handler
+}
+
+// NuevoModulo construye el módulo de autenticación con el gestor de sesiones y el servicio
+// de permisos compartidos por los middlewares de autenticación y autorización
+func NuevoModulo(db *database.GestorDB, gestorSesiones *sesiones.GestorSesiones, permisosServicio *permisos.PermisosServicio) *Modulo {
+	var seguridad config.SecurityConfig
+	if cfg := config.Obtener(); cfg != nil {
+		seguridad = cfg.Security
+	}
+
+	servicioDB := sharedDB.NuevoServicio(db)
+	servicio := NuevoServicio(
+		servicioDB,
+		gestorSesiones,
+		auditoria.NuevoServicio(servicioDB),
+		NuevoControlIntentos(seguridad.IntentosLogin, time.Duration(seguridad.BloqueoMinutos)*time.Minute),
+		seguridad.HashSaltRounds,
+		time.Duration(seguridad.SuplenciaMinutos)*time.Minute,
+	)
+	go servicio.VigilarBloqueos(context.Background())
+	return &Modulo{handler: NuevoHandler(servicio, permisosServicio, middlewares.NuevasOpcionesCookie(seguridad))}
+}
+
+// RegistrarRutas registra los endpoints públicos de sesión bajo /auth
+func (m *Modulo) RegistrarRutas(router fiber.Router) {
+	grupo := router.Group("/auth")
+	grupo.Post("/login", m.handler.Login)
+	grupo.Post("/refresh", m.handler.Refrescar)
+	grupo.Post("/logout", m.handler.Logout)
+	grupo.Post("/cookie/login", m.handler.LoginCookie)
+	grupo.Post("/cookie/logout", m.handler.LogoutCookie)
+	grupo.Get("/jwks", m.handler.Jwks)
+}
+
+// RegistrarRutasProtegidas registra los endpoints del empleado autenticado y la
+// administración de sesiones; deben montarse después del middleware de autenticación
+func (m *Modulo) RegistrarRutasProtegidas(router fiber.Router, requiere middlewares.Requiere, audita middlewares.Audita) {
+	grupo := router.Group("/auth")
+	grupo.Get("/permisos", m.handler.Permisos)
+	grupo.Post("/sesiones/:idEmpleado<int>/revocar", requiere("sesiones:administrar"), audita(middlewares.RutaAuditada{
+		Tabla:      TablaEmpleados,
+		IdListItem: IdListItemSeguridad,
+		IdRegistro: middlewares.IdDeParametro("idEmpleado"),
+		Accion:     auditoria.AccionModificar,
+		Observaciones: func(*fiber.Ctx) string {
+			return "Revocación administrativa de las sesiones del empleado"
+		},
+	}), m.handler.RevocarSesiones)
+	grupo.Get("/bloqueos", requiere("cuentas:administrar"), m.handler.Bloqueos)
+	grupo.Post("/bloqueos/:idEmpleado<int>/desbloquear", requiere("cuentas:administrar"), m.handler.Desbloquear)
+	grupo.Post("/suplencia", requiere("suplencia:iniciar"), m.handler.IniciarSuplencia)
+	grupo.Post("/suplencia/finalizar", middlewares.EnSuplencia, m.handler.FinalizarSuplencia)
+}
@@ -0,0 +1,63 @@
+package autenticacion
+
+const (
+	QueryObtenerCredenciales = `
+  SELECT TOP 1
+    e.IdEmpleado,
+    e.Usuario,
+    e.Clave,
+    LTRIM(RTRIM(e.ApellidoPaterno + ' ' + ISNULL(e.ApellidoMaterno, '') + ' ' + e.Nombres)) AS Nombre,
+    ce.HashClave,
+    ce.HuellaClaveSigh
+  FROM Empleados e
+  LEFT JOIN dbo.CredencialesEmpleado ce ON e.IdEmpleado = ce.IdEmpleado
+  WHERE e.Usuario = @usuario AND ISNULL(e.EsActivo, 1) = 1`
+
+	QueryObtenerEmpleadoActivo = `
+  SELECT
+    e.IdEmpleado,
+    e.Usuario,
+    LTRIM(RTRIM(e.ApellidoPaterno + ' ' + ISNULL(e.ApellidoMaterno, '') + ' ' + e.Nombres)) AS Nombre
+  FROM Empleados e
+  WHERE e.IdEmpleado = @idEmpleado AND ISNULL(e.EsActivo, 1) = 1`
+)
+
+const (
+	QueryGuardarCredenciales = `
+  MERGE dbo.CredencialesEmpleado AS destino
+  USING (SELECT @idEmpleado AS IdEmpleado) AS origen
+  ON destino.IdEmpleado = origen.IdEmpleado
+  WHEN MATCHED THEN UPDATE SET
+    HashClave = @hashClave,
+    HuellaClaveSigh = @huella,
+    FechaActualizacion = GETDATE()
+  WHEN NOT MATCHED THEN
+    INSERT (IdEmpleado, HashClave, HuellaClaveSigh)
+    VALUES (@idEmpleado, @hashClave, @huella);`
+)
+
+const (
+	QueryObtenerIdEmpleadoPorUsuario = `
+  SELECT TOP 1 IdEmpleado
+  FROM Empleados
+  WHERE Usuario = @usuario`
+
+	QueryObtenerUsuarioEmpleado = `
+  SELECT Usuario
+  FROM Empleados
+  WHERE IdEmpleado = @idEmpleado`
+)
+
+const (
+	// QueryObtenerServicioSupervisado retorna un servicio en el que @idSupervisor es jefe
+	// y @idEmpleado forma parte del personal
+	QueryObtenerServicioSupervisado = `
+  SELECT TOP 1 sup.IdServicio
+  FROM dbo.EmpleadosServicio sup
+  INNER JOIN dbo.EmpleadosServicio sub ON sub.IdServicio = sup.IdServicio
+  WHERE sup.IdEmpleado = @idSupervisor
+    AND sup.EsSupervisor = 1
+    AND sub.IdEmpleado = @idEmpleado
+    AND sub.IdEmpleado <> sup.IdEmpleado
+  ORDER BY sup.IdServicio`
+)
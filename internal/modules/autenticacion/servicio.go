@@ -0,0 +1,255 @@
+package autenticacion
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/shared/database"
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/services/auditoria"
+	"backend/internal/shared/sesiones"
+	"backend/internal/shared/tokens"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrCredencialesInvalidas = errors.New("usuario o contraseña incorrectos")
+
+// SolicitudLogin representa las credenciales de SIGH enviadas al iniciar sesión
+type SolicitudLogin struct {
+	Usuario string `json:"usuario"`
+	Clave   string `json:"clave"`
+}
+
+// SolicitudRefresco representa el cuerpo de refresh y logout
+type SolicitudRefresco struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Sesion es la respuesta de login y refresh
+type Sesion struct {
+	tokens.ParTokens
+	Empleado *identidad.Empleado `json:"empleado"`
+}
+
+// SesionCookie es la respuesta del login en modo cookie; la sesión viaja en una cookie
+// HttpOnly y el cliente debe reenviar CsrfToken en el encabezado X-CSRF-Token
+type SesionCookie struct {
+	Empleado  *identidad.Empleado `json:"empleado"`
+	CsrfToken string              `json:"csrfToken"`
+	Expira    time.Time           `json:"expira"`
+}
+
+// SesionesRevocadas es la respuesta de la revocación administrativa
+type SesionesRevocadas struct {
+	IdEmpleado int `json:"idEmpleado"`
+	Revocadas  int `json:"revocadas"`
+}
+
+type AutenticacionServicio struct {
+	db        *database.ServicioDB
+	sesiones  *sesiones.GestorSesiones
+	auditoria *auditoria.AuditoriaServicio
+	intentos  *ControlIntentos
+	costo     int
+	suplencia time.Duration
+	// hashFicticio se compara en cada fallo que no pasó por bcrypt (usuario inexistente o
+	// clave de SIGH aún no migrada), para que todos los fallos tarden lo mismo
+	hashFicticio []byte
+}
+
+// NuevoServicio crea el servicio; hashSaltRounds es el costo bcrypt de SecurityConfig y
+// suplenciaMaxima la duración máxima de una suplencia (por defecto 15 minutos)
+func NuevoServicio(
+	db *database.ServicioDB,
+	gestorSesiones *sesiones.GestorSesiones,
+	auditoriaServicio *auditoria.AuditoriaServicio,
+	intentos *ControlIntentos,
+	hashSaltRounds int,
+	suplenciaMaxima time.Duration,
+) *AutenticacionServicio {
+	if suplenciaMaxima <= 0 {
+		suplenciaMaxima = suplenciaPorDefecto
+	}
+	costo := costoBcrypt(hashSaltRounds)
+	hashFicticio, _ := bcrypt.GenerateFromPassword([]byte("usuario-inexistente"), costo)
+	return &AutenticacionServicio{
+		db:        db,
+		sesiones:  gestorSesiones,
+		auditoria: auditoriaServicio,
+		intentos:  intentos,
+		costo:     costo,
+		suplencia: suplenciaMaxima,
+
+		hashFicticio: hashFicticio,
+	}
+}
+
+// Login verifica las credenciales del empleado en SIGH y emite sus tokens.
+// ip es la dirección del cliente, usada para limitar los intentos fallidos.
+func (s *AutenticacionServicio) Login(ctx context.Context, ip string, solicitud SolicitudLogin) (*Sesion, error) {
+	empleado, err := s.autenticar(ctx, ip, solicitud)
+	if err != nil {
+		return nil, err
+	}
+
+	par, err := s.sesiones.Iniciar(ctx, empleado)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sesion{ParTokens: *par, Empleado: empleado}, nil
+}
+
+// LoginCookie verifica las credenciales y abre una sesión del modo cookie. Retorna el
+// valor firmado de la cookie y los datos de la sesión, que incluyen el token CSRF.
+func (s *AutenticacionServicio) LoginCookie(ctx context.Context, ip string, solicitud SolicitudLogin) (string, *SesionCookie, error) {
+	empleado, err := s.autenticar(ctx, ip, solicitud)
+	if err != nil {
+		return "", nil, err
+	}
+
+	valor, sesion, err := s.sesiones.IniciarCookie(ctx, empleado)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return valor, &SesionCookie{
+		Empleado:  empleado,
+		CsrfToken: s.sesiones.TokenCsrf(sesion),
+		Expira:    sesion.ExpiraEn(),
+	}, nil
+}
+
+// CerrarSesionCookie revoca la sesión de la cookie
+func (s *AutenticacionServicio) CerrarSesionCookie(ctx context.Context, valor string) error {
+	return s.sesiones.CerrarCookie(ctx, valor)
+}
+
+// ClavesPublicas lista las claves públicas vigentes de firma de los tokens
+func (s *AutenticacionServicio) ClavesPublicas() ([]tokens.ClavePublica, error) {
+	return s.sesiones.ClavesPublicas()
+}
+
+// verificarCredenciales comprueba la clave contra SIGH y la migra a bcrypt. El usuario
+// inexistente y la clave incorrecta, en bcrypt o en un formato antiguo de SIGH, responden
+// el mismo error tras una comparación bcrypt con el costo configurado.
+func (s *AutenticacionServicio) verificarCredenciales(ctx context.Context, usuario string, clave string) (*identidad.Empleado, error) {
+	row := s.db.EjecutarQueryRow(ctx, QueryObtenerCredenciales, false, sql.Named("usuario", usuario))
+	if row == nil {
+		return nil, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	var (
+		empleado          identidad.Empleado
+		claveSigh         sql.NullString
+		hashClave, huella sql.NullString
+	)
+	err := row.Scan(&empleado.IdEmpleado, &empleado.Usuario, &claveSigh, &empleado.Nombre, &hashClave, &huella)
+	if err == sql.ErrNoRows || (err == nil && !claveSigh.Valid) {
+		bcrypt.CompareHashAndPassword(s.hashFicticio, []byte(clave))
+		return nil, ErrCredencialesInvalidas
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	huellaActual := HuellaClave(claveSigh.String)
+	migrada := hashClave.Valid && huella.Valid && huella.String == huellaActual
+
+	var valida, rehash bool
+	if migrada {
+		valida = bcrypt.CompareHashAndPassword([]byte(hashClave.String), []byte(clave)) == nil
+		rehash = valida && requiereRehash(hashClave.String, s.costo)
+	} else {
+		// Primera vez o clave cambiada en SIGH: se verifica contra el formato de SIGH.
+		// Si es válida, la migración cuesta un bcrypt; si no, se iguala con el ficticio.
+		valida = VerificarClave(claveSigh.String, clave)
+		rehash = valida
+		if !valida && DetectarFormato(claveSigh.String) != FormatoBcrypt {
+			bcrypt.CompareHashAndPassword(s.hashFicticio, []byte(clave))
+		}
+	}
+	if !valida {
+		return nil, ErrCredencialesInvalidas
+	}
+
+	if rehash {
+		if err := s.migrarClave(ctx, empleado.IdEmpleado, clave, huellaActual); err != nil {
+			// La sesión no depende de la migración; se reintentará en el siguiente login
+			log.Printf("[Autenticacion] No se pudo migrar la clave del empleado %d: %v", empleado.IdEmpleado, err)
+		}
+	}
+
+	return &empleado, nil
+}
+
+// migrarClave guarda el hash bcrypt de la clave con el costo configurado
+func (s *AutenticacionServicio) migrarClave(ctx context.Context, idEmpleado int, clave string, huella string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(clave), s.costo)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.EjecutarExec(ctx, QueryGuardarCredenciales, false,
+		sql.Named("idEmpleado", idEmpleado),
+		sql.Named("hashClave", string(hash)),
+		sql.Named("huella", huella),
+	)
+	return err
+}
+
+// Refrescar rota el token de refresco: lo invalida y emite un nuevo par de la misma sesión,
+// siempre que el empleado siga activo en SIGH. Presentar un token ya rotado revoca la sesión.
+func (s *AutenticacionServicio) Refrescar(ctx context.Context, refreshToken string) (*Sesion, error) {
+	var empleado *identidad.Empleado
+	par, err := s.sesiones.Rotar(ctx, refreshToken, func(claims *tokens.Claims) (*identidad.Empleado, error) {
+		var err error
+		empleado, err = s.obtenerEmpleadoActivo(ctx, claims.IdEmpleado)
+		return empleado, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sesion{ParTokens: *par, Empleado: empleado}, nil
+}
+
+// CerrarSesion revoca la sesión del token de refresco; los tokens de acceso emitidos en
+// ella dejan de aceptarse
+func (s *AutenticacionServicio) CerrarSesion(ctx context.Context, refreshToken string) error {
+	return s.sesiones.Cerrar(ctx, refreshToken)
+}
+
+// RevocarSesionesEmpleado cierra todas las sesiones abiertas del empleado
+func (s *AutenticacionServicio) RevocarSesionesEmpleado(ctx context.Context, idEmpleado int) (*SesionesRevocadas, error) {
+	revocadas, err := s.sesiones.RevocarEmpleado(ctx, idEmpleado)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Autenticacion] Se revocaron %d sesiones del empleado %d", revocadas, idEmpleado)
+	return &SesionesRevocadas{IdEmpleado: idEmpleado, Revocadas: revocadas}, nil
+}
+
+func (s *AutenticacionServicio) obtenerEmpleadoActivo(ctx context.Context, idEmpleado int) (*identidad.Empleado, error) {
+	row := s.db.EjecutarQueryRow(ctx, QueryObtenerEmpleadoActivo, false, sql.Named("idEmpleado", idEmpleado))
+	if row == nil {
+		return nil, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	var empleado identidad.Empleado
+	err := row.Scan(&empleado.IdEmpleado, &empleado.Usuario, &empleado.Nombre)
+	if err == sql.ErrNoRows {
+		return nil, sesiones.ErrSesionInvalida
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &empleado, nil
+}
@@ -0,0 +1,142 @@
+package autenticacion
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/services/auditoria"
+	"backend/internal/shared/sesiones"
+)
+
+// suplenciaPorDefecto es la duración máxima cuando config.yml no define
+// security.suplencia_minutos
+const suplenciaPorDefecto = 15 * time.Minute
+
+var (
+	ErrSuplenciaNoPermitida = errors.New("solo puede actuar a nombre del personal del servicio que supervisa")
+	ErrSuplenciaAnidada     = errors.New("no puede iniciar una suplencia desde otra suplencia")
+	ErrMotivoSuplencia      = errors.New("debe indicar el motivo de la suplencia")
+)
+
+// SolicitudSuplencia es el cuerpo para actuar a nombre de un empleado del servicio
+type SolicitudSuplencia struct {
+	IdEmpleado int    `json:"idEmpleado"`
+	Motivo     string `json:"motivo"`
+	// Minutos es opcional; no puede superar la duración máxima configurada
+	Minutos int `json:"minutos"`
+}
+
+// Suplencia es la respuesta al iniciar una suplencia. El token de acceso identifica al
+// empleado suplido y conserva al supervisor como actor; no tiene token de refresco.
+type Suplencia struct {
+	AccessToken string              `json:"accessToken"`
+	Expira      time.Time           `json:"expira"`
+	Empleado    *identidad.Empleado `json:"empleado"`
+}
+
+// IniciarSuplencia permite al supervisor del contexto actuar a nombre de un empleado de su
+// servicio (p. ej. la jefa de enfermería que corrige un registro). Se audita el inicio a
+// nombre del supervisor; durante la suplencia, RegistrarAuditoria anota a ambos. Si el
+// inicio no se puede auditar el token se revoca: no hay suplencias sin registro.
+func (s *AutenticacionServicio) IniciarSuplencia(ctx context.Context, solicitud SolicitudSuplencia) (*Suplencia, error) {
+	supervisor, ok := identidad.EmpleadoDesde(ctx)
+	if !ok {
+		return nil, sesiones.ErrSesionInvalida
+	}
+	if supervisor.Actor != nil {
+		return nil, ErrSuplenciaAnidada
+	}
+
+	motivo := strings.TrimSpace(solicitud.Motivo)
+	if motivo == "" {
+		return nil, ErrMotivoSuplencia
+	}
+	if solicitud.IdEmpleado <= 0 || solicitud.IdEmpleado == supervisor.IdEmpleado {
+		return nil, ErrSuplenciaNoPermitida
+	}
+
+	idServicio, err := s.servicioSupervisado(ctx, supervisor.IdEmpleado, solicitud.IdEmpleado)
+	if err != nil {
+		return nil, err
+	}
+
+	empleado, err := s.obtenerEmpleadoActivo(ctx, solicitud.IdEmpleado)
+	if errors.Is(err, sesiones.ErrSesionInvalida) {
+		return nil, ErrSuplenciaNoPermitida
+	}
+	if err != nil {
+		return nil, err
+	}
+	empleado.Actor = &identidad.Empleado{IdEmpleado: supervisor.IdEmpleado, Usuario: supervisor.Usuario, Nombre: supervisor.Nombre}
+
+	duracion := s.suplencia
+	if solicitud.Minutos > 0 {
+		duracion = min(time.Duration(solicitud.Minutos)*time.Minute, s.suplencia)
+	}
+
+	token, expira, err := s.sesiones.IniciarSuplencia(ctx, empleado, duracion)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Autenticacion] El empleado %d actúa a nombre del empleado %d (servicio %d) hasta %s",
+		supervisor.IdEmpleado, empleado.IdEmpleado, idServicio, expira.Format(time.RFC3339))
+	if err := s.auditoria.RegistrarAuditoria(
+		ctx, auditoria.AccionModificar, empleado.IdEmpleado, TablaEmpleados, IdListItemSeguridad,
+		fmt.Sprintf("Inicio de suplencia: el empleado %d actúa a nombre del empleado %d (servicio %d) hasta %s. Motivo: %s",
+			supervisor.IdEmpleado, empleado.IdEmpleado, idServicio, expira.Format("2006-01-02 15:04:05"), motivo),
+	); err != nil {
+		if errRevocar := s.sesiones.FinalizarSuplencia(ctx, token); errRevocar != nil {
+			log.Printf("[Autenticacion] No se pudo revocar la suplencia sin auditar del empleado %d: %v", supervisor.IdEmpleado, errRevocar)
+		}
+		return nil, fmt.Errorf("error al registrar auditoría: %w", err)
+	}
+
+	return &Suplencia{AccessToken: token, Expira: expira, Empleado: empleado}, nil
+}
+
+// FinalizarSuplencia revoca la suplencia del token de acceso antes de su vencimiento
+func (s *AutenticacionServicio) FinalizarSuplencia(ctx context.Context, token string) error {
+	if err := s.sesiones.FinalizarSuplencia(ctx, token); err != nil {
+		return err
+	}
+
+	empleado, ok := identidad.EmpleadoDesde(ctx)
+	if !ok {
+		return nil
+	}
+	if err := s.auditoria.RegistrarAuditoria(
+		ctx, auditoria.AccionModificar, empleado.IdEmpleado, TablaEmpleados, IdListItemSeguridad,
+		"Fin de suplencia",
+	); err != nil {
+		return fmt.Errorf("error al registrar auditoría: %w", err)
+	}
+	return nil
+}
+
+// servicioSupervisado retorna el servicio en el que idSupervisor es jefe de idEmpleado
+func (s *AutenticacionServicio) servicioSupervisado(ctx context.Context, idSupervisor int, idEmpleado int) (int, error) {
+	row := s.db.EjecutarQueryRow(ctx, QueryObtenerServicioSupervisado, false,
+		sql.Named("idSupervisor", idSupervisor),
+		sql.Named("idEmpleado", idEmpleado),
+	)
+	if row == nil {
+		return 0, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	var idServicio int
+	err := row.Scan(&idServicio)
+	if err == sql.ErrNoRows {
+		return 0, ErrSuplenciaNoPermitida
+	}
+	if err != nil {
+		return 0, err
+	}
+	return idServicio, nil
+}
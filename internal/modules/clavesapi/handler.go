@@ -0,0 +1,68 @@
+package clavesapi
+
+import (
+	"errors"
+
+	"backend/internal/shared/services/clavesapi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	servicio *clavesapi.ClavesApiServicio
+}
+
+func NuevoHandler(servicio *clavesapi.ClavesApiServicio) *Handler {
+	return &Handler{servicio: servicio}
+}
+
+// Crear genera una clave de API; la clave completa solo se muestra en esta respuesta
+func (h *Handler) Crear(c *fiber.Ctx) error {
+	var solicitud clavesapi.SolicitudClave
+	if err := c.BodyParser(&solicitud); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "El cuerpo de la solicitud no es válido.")
+	}
+
+	clave, err := h.servicio.Crear(c.UserContext(), solicitud)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": true,
+		"data":   clave,
+	})
+}
+
+// Listar retorna las claves vigentes; con todas=true incluye las vencidas y revocadas
+func (h *Handler) Listar(c *fiber.Ctx) error {
+	claves, err := h.servicio.Listar(c.UserContext(), c.QueryBool("todas"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   claves,
+	})
+}
+
+// Revocar invalida una clave de API
+func (h *Handler) Revocar(c *fiber.Ctx) error {
+	idClave, err := c.ParamsInt("idClave")
+	if err != nil || idClave <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "El id de la clave no es válido.")
+	}
+
+	if err := h.servicio.Revocar(c.UserContext(), idClave); err != nil {
+		if errors.Is(err, clavesapi.ErrClaveNoEncontrada) {
+			return fiber.NewError(fiber.StatusNotFound, "La clave de API no existe o ya fue revocada.")
+		}
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   fiber.Map{"mensaje": "Clave de API revocada correctamente."},
+	})
+}
@@ -0,0 +1,26 @@
+package clavesapi
+
+import (
+	"backend/internal/shared/middlewares"
+	"backend/internal/shared/services/clavesapi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Modulo struct {
+	handler *Handler
+}
+
+// NuevoModulo construye el módulo de administración de claves de API con el servicio
+// compartido por el middleware de autenticación, para que una revocación aplique de inmediato
+func NuevoModulo(servicio *clavesapi.ClavesApiServicio) *Modulo {
+	return &Modulo{handler: NuevoHandler(servicio)}
+}
+
+// RegistrarRutas registra los endpoints del módulo bajo /claves-api
+func (m *Modulo) RegistrarRutas(router fiber.Router, requiere middlewares.Requiere) {
+	grupo := router.Group("/claves-api", requiere("claves:administrar"))
+	grupo.Get("/", m.handler.Listar)
+	grupo.Post("/", m.handler.Crear)
+	grupo.Delete("/:idClave<int>", m.handler.Revocar)
+}
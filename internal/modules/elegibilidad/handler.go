@@ -0,0 +1,39 @@
+package elegibilidad
+
+import (
+	"errors"
+
+	"backend/internal/shared/services/elegibilidad"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	servicio *elegibilidad.ElegibilidadServicio
+}
+
+func NuevoHandler(servicio *elegibilidad.ElegibilidadServicio) *Handler {
+	return &Handler{servicio: servicio}
+}
+
+// Evaluar indica si la acción clínica (parámetro accion) está permitida para la atención,
+// para que el cliente pueda advertirlo antes de iniciar el registro
+func (h *Handler) Evaluar(c *fiber.Ctx) error {
+	idAtencion, err := c.ParamsInt("idAtencion")
+	if err != nil || idAtencion <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "El N° de cuenta no es válido.")
+	}
+
+	decision, err := h.servicio.Evaluar(c.UserContext(), idAtencion, c.Query("accion"))
+	if err != nil {
+		if errors.Is(err, elegibilidad.ErrAccionDesconocida) {
+			return fiber.NewError(fiber.StatusBadRequest, "La acción debe ser triaje, consulta o laboratorio.")
+		}
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   decision,
+	})
+}
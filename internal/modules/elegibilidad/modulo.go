@@ -0,0 +1,23 @@
+package elegibilidad
+
+import (
+	"backend/internal/shared/middlewares"
+	"backend/internal/shared/services/elegibilidad"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Modulo struct {
+	handler *Handler
+}
+
+// NuevoModulo construye el módulo de consulta de elegibilidad por financiamiento
+func NuevoModulo(servicio *elegibilidad.ElegibilidadServicio) *Modulo {
+	return &Modulo{handler: NuevoHandler(servicio)}
+}
+
+// RegistrarRutas registra los endpoints del módulo bajo /elegibilidad
+func (m *Modulo) RegistrarRutas(router fiber.Router, requiere middlewares.Requiere) {
+	grupo := router.Group("/elegibilidad")
+	grupo.Get("/:idAtencion<int>", requiere("elegibilidad:leer"), m.handler.Evaluar)
+}
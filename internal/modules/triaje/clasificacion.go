@@ -0,0 +1,317 @@
+package triaje
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"backend/internal/shared/recarga"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrDiscriminadorDesconocido = errors.New("discriminador no reconocido")
+
+// NivelPrioridad describe uno de los niveles de prioridad (I a IV)
+type NivelPrioridad struct {
+	Nivel               int    `yaml:"nivel" json:"nivel"`
+	Nombre              string `yaml:"nombre" json:"nombre"`
+	Color               string `yaml:"color" json:"color"`
+	TiempoMaximoMinutos int    `yaml:"tiempo_maximo_minutos" json:"tiempoMaximoMinutos"`
+}
+
+// Discriminador representa un hallazgo del motivo de consulta que eleva la prioridad
+type Discriminador struct {
+	Codigo      string `yaml:"codigo" json:"codigo"`
+	Descripcion string `yaml:"descripcion" json:"descripcion"`
+}
+
+// Condicion compara un signo vital contra un umbral
+type Condicion struct {
+	Campo    string   `yaml:"campo"`
+	Operador string   `yaml:"operador"`
+	Valor    float64  `yaml:"valor"`
+	ValorMax *float64 `yaml:"valor_max"`
+}
+
+// ReglaPrioridad asigna un nivel cuando se cumplen la edad, los discriminadores y las condiciones
+type ReglaPrioridad struct {
+	Id              string      `yaml:"id"`
+	Descripcion     string      `yaml:"descripcion"`
+	Nivel           int         `yaml:"nivel"`
+	EdadMin         *int        `yaml:"edad_min"`
+	EdadMax         *int        `yaml:"edad_max"`
+	Discriminadores []string    `yaml:"discriminadores"`
+	Condiciones     []Condicion `yaml:"condiciones"`
+}
+
+// ReglasPrioridad representa el archivo versionado de reglas de clasificación
+type ReglasPrioridad struct {
+	Version         string           `yaml:"version"`
+	Niveles         []NivelPrioridad `yaml:"niveles"`
+	NivelPorDefecto int              `yaml:"nivel_por_defecto"`
+	Discriminadores []Discriminador  `yaml:"discriminadores"`
+	Reglas          []ReglaPrioridad `yaml:"reglas"`
+}
+
+// ReglaAplicada identifica una regla que se cumplió durante la clasificación
+type ReglaAplicada struct {
+	Id          string `json:"id"`
+	Descripcion string `json:"descripcion"`
+	Nivel       int    `json:"nivel"`
+}
+
+// Clasificacion es el resultado de evaluar las reglas para un triaje
+type Clasificacion struct {
+	NivelPrioridad
+	VersionReglas   string          `json:"versionReglas"`
+	Regla           *ReglaAplicada  `json:"regla"`
+	ReglasCumplidas []ReglaAplicada `json:"reglasCumplidas,omitempty"`
+	Explicacion     string          `json:"explicacion"`
+}
+
+// CargarReglasPrioridad lee y valida el archivo de reglas
+func CargarReglasPrioridad(ruta string) (*ReglasPrioridad, error) {
+	contenido, err := os.ReadFile(ruta)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer reglas de prioridad: %w", err)
+	}
+
+	var reglas ReglasPrioridad
+	if err := yaml.Unmarshal(contenido, &reglas); err != nil {
+		return nil, fmt.Errorf("error al parsear reglas de prioridad: %w", err)
+	}
+
+	if err := reglas.validar(); err != nil {
+		return nil, fmt.Errorf("reglas de prioridad inválidas: %w", err)
+	}
+
+	return &reglas, nil
+}
+
+func (r *ReglasPrioridad) validar() error {
+	if r.Version == "" {
+		return fmt.Errorf("falta la versión")
+	}
+
+	if r.nivel(r.NivelPorDefecto) == nil {
+		return fmt.Errorf("el nivel por defecto %d no está definido", r.NivelPorDefecto)
+	}
+
+	for _, regla := range r.Reglas {
+		if regla.Id == "" {
+			return fmt.Errorf("existe una regla sin id")
+		}
+		if r.nivel(regla.Nivel) == nil {
+			return fmt.Errorf("regla %s: el nivel %d no está definido", regla.Id, regla.Nivel)
+		}
+		if len(regla.Condiciones) == 0 && len(regla.Discriminadores) == 0 {
+			return fmt.Errorf("regla %s: debe tener condiciones o discriminadores", regla.Id)
+		}
+		for _, codigo := range regla.Discriminadores {
+			if r.discriminador(codigo) == nil {
+				return fmt.Errorf("regla %s: el discriminador %q no está definido", regla.Id, codigo)
+			}
+		}
+		for _, condicion := range regla.Condiciones {
+			if _, ok := camposSignos[condicion.Campo]; !ok {
+				return fmt.Errorf("regla %s: campo %q desconocido", regla.Id, condicion.Campo)
+			}
+			switch condicion.Operador {
+			case "<", "<=", ">", ">=", "==":
+			case "entre", "fuera_de":
+				if condicion.ValorMax == nil {
+					return fmt.Errorf("regla %s: el operador %s requiere valor_max", regla.Id, condicion.Operador)
+				}
+			default:
+				return fmt.Errorf("regla %s: operador %q desconocido", regla.Id, condicion.Operador)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *ReglasPrioridad) nivel(nivel int) *NivelPrioridad {
+	for i := range r.Niveles {
+		if r.Niveles[i].Nivel == nivel {
+			return &r.Niveles[i]
+		}
+	}
+	return nil
+}
+
+func (r *ReglasPrioridad) discriminador(codigo string) *Discriminador {
+	for i := range r.Discriminadores {
+		if r.Discriminadores[i].Codigo == codigo {
+			return &r.Discriminadores[i]
+		}
+	}
+	return nil
+}
+
+// camposSignos expone los signos vitales que pueden usarse en reglas y rangos
+var camposSignos = map[string]func(SignosVitales) *float64{
+	"presionSistolica":       func(s SignosVitales) *float64 { return aDecimal(s.PresionSistolica) },
+	"presionDiastolica":      func(s SignosVitales) *float64 { return aDecimal(s.PresionDiastolica) },
+	"frecuenciaCardiaca":     func(s SignosVitales) *float64 { return aDecimal(s.FrecuenciaCardiaca) },
+	"frecuenciaRespiratoria": func(s SignosVitales) *float64 { return aDecimal(s.FrecuenciaRespiratoria) },
+	"temperatura":            func(s SignosVitales) *float64 { return s.Temperatura },
+	"saturacionOxigeno":      func(s SignosVitales) *float64 { return aDecimal(s.SaturacionOxigeno) },
+	"peso":                   func(s SignosVitales) *float64 { return s.Peso },
+	"talla":                  func(s SignosVitales) *float64 { return s.Talla },
+	"perimetroCefalico":      func(s SignosVitales) *float64 { return s.PerimetroCefalico },
+	"perimetroAbdominal":     func(s SignosVitales) *float64 { return s.PerimetroAbdominal },
+	"imc":                    func(s SignosVitales) *float64 { return s.CalcularIMC() },
+}
+
+func aDecimal(valor *int) *float64 {
+	if valor == nil {
+		return nil
+	}
+	decimal := float64(*valor)
+	return &decimal
+}
+
+func (c Condicion) cumple(signos SignosVitales) bool {
+	valor := camposSignos[c.Campo](signos)
+	if valor == nil {
+		return false
+	}
+
+	switch c.Operador {
+	case "<":
+		return *valor < c.Valor
+	case "<=":
+		return *valor <= c.Valor
+	case ">":
+		return *valor > c.Valor
+	case ">=":
+		return *valor >= c.Valor
+	case "==":
+		return *valor == c.Valor
+	case "entre":
+		return *valor >= c.Valor && *valor <= *c.ValorMax
+	case "fuera_de":
+		return *valor < c.Valor || *valor > *c.ValorMax
+	}
+	return false
+}
+
+func (r ReglaPrioridad) cumple(signos SignosVitales, edad *int, discriminadores map[string]bool) bool {
+	if r.EdadMin != nil || r.EdadMax != nil {
+		if edad == nil {
+			return false
+		}
+		if r.EdadMin != nil && *edad < *r.EdadMin {
+			return false
+		}
+		if r.EdadMax != nil && *edad > *r.EdadMax {
+			return false
+		}
+	}
+
+	if len(r.Discriminadores) > 0 {
+		presente := false
+		for _, codigo := range r.Discriminadores {
+			if discriminadores[codigo] {
+				presente = true
+				break
+			}
+		}
+		if !presente {
+			return false
+		}
+	}
+
+	for _, condicion := range r.Condiciones {
+		if !condicion.cumple(signos) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Clasificar evalúa todas las reglas y retorna la de mayor prioridad que se cumplió
+func (r *ReglasPrioridad) Clasificar(signos SignosVitales, edad *int, discriminadores []string) (*Clasificacion, error) {
+	presentes := make(map[string]bool, len(discriminadores))
+	for _, codigo := range discriminadores {
+		if r.discriminador(codigo) == nil {
+			return nil, fmt.Errorf("%w: %s", ErrDiscriminadorDesconocido, codigo)
+		}
+		presentes[codigo] = true
+	}
+
+	var cumplidas []ReglaAplicada
+	for _, regla := range r.Reglas {
+		if regla.cumple(signos, edad, presentes) {
+			cumplidas = append(cumplidas, ReglaAplicada{
+				Id:          regla.Id,
+				Descripcion: regla.Descripcion,
+				Nivel:       regla.Nivel,
+			})
+		}
+	}
+
+	// Orden estable: ante empate de nivel prevalece la regla declarada primero
+	sort.SliceStable(cumplidas, func(i, j int) bool {
+		return cumplidas[i].Nivel < cumplidas[j].Nivel
+	})
+
+	clasificacion := &Clasificacion{
+		VersionReglas:   r.Version,
+		ReglasCumplidas: cumplidas,
+	}
+
+	if len(cumplidas) == 0 {
+		clasificacion.NivelPrioridad = *r.nivel(r.NivelPorDefecto)
+		clasificacion.Explicacion = "Ninguna regla se cumplió; se asigna la prioridad por defecto."
+		return clasificacion, nil
+	}
+
+	decisiva := cumplidas[0]
+	clasificacion.NivelPrioridad = *r.nivel(decisiva.Nivel)
+	clasificacion.Regla = &decisiva
+	clasificacion.Explicacion = fmt.Sprintf("%s: %s", decisiva.Id, decisiva.Descripcion)
+	return clasificacion, nil
+}
+
+// MotorPrioridad evalúa los triajes con las reglas vigentes del archivo configurado
+type MotorPrioridad struct {
+	archivo *recarga.Archivo[ReglasPrioridad]
+}
+
+func NuevoMotorPrioridad(ruta string) *MotorPrioridad {
+	return &MotorPrioridad{
+		archivo: recarga.Nuevo("reglas de prioridad", ruta, CargarReglasPrioridad),
+	}
+}
+
+// Reglas retorna las reglas vigentes, recargándolas si el archivo fue modificado
+func (m *MotorPrioridad) Reglas() (*ReglasPrioridad, error) {
+	return m.archivo.Obtener()
+}
+
+// Clasificar evalúa el triaje con las reglas vigentes
+func (m *MotorPrioridad) Clasificar(signos SignosVitales, edad *int, discriminadores []string) (*Clasificacion, error) {
+	reglas, err := m.Reglas()
+	if err != nil {
+		return nil, err
+	}
+	return reglas.Clasificar(signos, edad, discriminadores)
+}
+
+// unirDiscriminadores serializa los códigos para almacenarlos en una sola columna
+func unirDiscriminadores(codigos []string) string {
+	return strings.Join(codigos, ",")
+}
+
+func separarDiscriminadores(valor string) []string {
+	if strings.TrimSpace(valor) == "" {
+		return nil
+	}
+	return strings.Split(valor, ",")
+}
@@ -0,0 +1,179 @@
+package triaje
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// rutaReglas es el archivo de reglas que se distribuye con la API
+const rutaReglas = "../../../triaje_prioridad.yml"
+
+func entero(v int) *int {
+	return &v
+}
+
+func decimal(v float64) *float64 {
+	return &v
+}
+
+func TestClasificarReglasDistribuidas(t *testing.T) {
+	reglas, err := CargarReglasPrioridad(rutaReglas)
+	if err != nil {
+		t.Fatalf("CargarReglasPrioridad: %v", err)
+	}
+
+	casos := []struct {
+		nombre          string
+		signos          SignosVitales
+		edad            *int
+		discriminadores []string
+		nivel           int
+		regla           string
+	}{
+		{"sin hallazgos", SignosVitales{SaturacionOxigeno: entero(98)}, entero(30), nil, 4, ""},
+		{"empate de nivel gana la regla declarada primero", SignosVitales{SaturacionOxigeno: entero(80)}, entero(30), []string{"inconsciente"}, 1, "I-DISC-CRITICO"},
+		{"saturación bajo 85", SignosVitales{SaturacionOxigeno: entero(84)}, entero(30), nil, 1, "I-SAT-O2"},
+		{"saturación en 85", SignosVitales{SaturacionOxigeno: entero(85)}, entero(30), nil, 2, "II-SAT-O2"},
+		{"saturación en 92", SignosVitales{SaturacionOxigeno: entero(92)}, entero(30), nil, 4, ""},
+		{"fuera_de incluye el máximo", SignosVitales{FrecuenciaCardiaca: entero(150)}, entero(30), nil, 3, "III-FC-ADULTO"},
+		{"fuera_de sobre el máximo", SignosVitales{FrecuenciaCardiaca: entero(151)}, entero(30), nil, 1, "I-FC-ADULTO"},
+		{"fuera_de incluye el mínimo", SignosVitales{FrecuenciaCardiaca: entero(40)}, entero(30), nil, 4, ""},
+		{"fuera_de bajo el mínimo", SignosVitales{FrecuenciaCardiaca: entero(39)}, entero(30), nil, 1, "I-FC-ADULTO"},
+		{"sin edad no aplica reglas por edad", SignosVitales{FrecuenciaCardiaca: entero(30)}, nil, nil, 4, ""},
+		{"sin edad aplica reglas sin edad", SignosVitales{FrecuenciaCardiaca: entero(30), SaturacionOxigeno: entero(90)}, nil, nil, 2, "II-SAT-O2"},
+		{"lactante en el límite de edad", SignosVitales{FrecuenciaCardiaca: entero(79)}, entero(1), nil, 1, "I-FC-LACTANTE"},
+		{"lactante fuera del límite de edad", SignosVitales{FrecuenciaCardiaca: entero(79)}, entero(2), nil, 4, ""},
+		{"fiebre en menor de un año", SignosVitales{Temperatura: decimal(38)}, entero(0), nil, 2, "II-FIEBRE-LACTANTE"},
+		{"fiebre de 38 en mayor de un año", SignosVitales{Temperatura: decimal(38)}, entero(5), nil, 4, ""},
+		{"fiebre de 38.5", SignosVitales{Temperatura: decimal(38.5)}, entero(5), nil, 3, "III-FIEBRE"},
+		{"crisis hipertensiva en adulto", SignosVitales{PresionSistolica: entero(180)}, entero(18), nil, 2, "II-PA-CRISIS"},
+		{"presión alta en menor de 18", SignosVitales{PresionSistolica: entero(180)}, entero(17), nil, 4, ""},
+		{"discriminador prevalece sobre signos de menor nivel", SignosVitales{Temperatura: decimal(38.5)}, entero(30), []string{"dolor_severo"}, 2, "II-DISC-URGENTE"},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			clasificacion, err := reglas.Clasificar(caso.signos, caso.edad, caso.discriminadores)
+			if err != nil {
+				t.Fatalf("Clasificar: %v", err)
+			}
+			if clasificacion.Nivel != caso.nivel {
+				t.Fatalf("nivel %d, se esperaba %d (%s)", clasificacion.Nivel, caso.nivel, clasificacion.Explicacion)
+			}
+
+			regla := ""
+			if clasificacion.Regla != nil {
+				regla = clasificacion.Regla.Id
+			}
+			if regla != caso.regla {
+				t.Fatalf("regla %q, se esperaba %q", regla, caso.regla)
+			}
+			if clasificacion.VersionReglas != reglas.Version {
+				t.Fatalf("versión %q, se esperaba %q", clasificacion.VersionReglas, reglas.Version)
+			}
+		})
+	}
+}
+
+func TestClasificarReglasCumplidasOrdenadas(t *testing.T) {
+	reglas, err := CargarReglasPrioridad(rutaReglas)
+	if err != nil {
+		t.Fatalf("CargarReglasPrioridad: %v", err)
+	}
+
+	clasificacion, err := reglas.Clasificar(SignosVitales{Temperatura: decimal(40), SaturacionOxigeno: entero(84)}, entero(30), []string{"dolor_moderado"})
+	if err != nil {
+		t.Fatalf("Clasificar: %v", err)
+	}
+
+	esperadas := []string{"I-SAT-O2", "II-SAT-O2", "II-HIPERTERMIA", "III-DISC", "III-FIEBRE"}
+	if len(clasificacion.ReglasCumplidas) != len(esperadas) {
+		t.Fatalf("%d reglas cumplidas, se esperaban %v", len(clasificacion.ReglasCumplidas), esperadas)
+	}
+	for i, regla := range clasificacion.ReglasCumplidas {
+		if regla.Id != esperadas[i] {
+			t.Fatalf("regla %d: %s, se esperaba %s", i, regla.Id, esperadas[i])
+		}
+	}
+}
+
+func TestClasificarDiscriminadorDesconocido(t *testing.T) {
+	reglas, err := CargarReglasPrioridad(rutaReglas)
+	if err != nil {
+		t.Fatalf("CargarReglasPrioridad: %v", err)
+	}
+
+	if _, err := reglas.Clasificar(SignosVitales{}, entero(30), []string{"dolor_toracico", "mareo"}); !errors.Is(err, ErrDiscriminadorDesconocido) {
+		t.Fatalf("error %v, se esperaba ErrDiscriminadorDesconocido", err)
+	}
+}
+
+func TestCondicionEntreIncluyeLimites(t *testing.T) {
+	condicion := Condicion{Campo: "temperatura", Operador: "entre", Valor: 36, ValorMax: decimal(37.5)}
+
+	casos := []struct {
+		temperatura float64
+		cumple      bool
+	}{
+		{35.9, false},
+		{36, true},
+		{37.5, true},
+		{37.6, false},
+	}
+	for _, caso := range casos {
+		if cumple := condicion.cumple(SignosVitales{Temperatura: decimal(caso.temperatura)}); cumple != caso.cumple {
+			t.Fatalf("temperatura %.1f: cumple = %v, se esperaba %v", caso.temperatura, cumple, caso.cumple)
+		}
+	}
+	if condicion.cumple(SignosVitales{}) {
+		t.Fatal("una condición sin el signo vital no debe cumplirse")
+	}
+}
+
+func TestCargarReglasRechazaArchivosInvalidos(t *testing.T) {
+	const niveles = `
+version: "prueba"
+nivel_por_defecto: 4
+niveles:
+  - { nivel: 1, nombre: "I" }
+  - { nivel: 4, nombre: "IV" }
+discriminadores:
+  - { codigo: "inconsciente", descripcion: "Inconsciente" }
+`
+	casos := []struct {
+		nombre    string
+		contenido string
+	}{
+		{"yaml mal formado", "version: [\n"},
+		{"sin versión", "nivel_por_defecto: 4\nniveles:\n  - { nivel: 4, nombre: \"IV\" }\n"},
+		{"nivel por defecto no definido", "version: \"prueba\"\nnivel_por_defecto: 3\nniveles:\n  - { nivel: 4, nombre: \"IV\" }\n"},
+		{"regla sin id", niveles + "reglas:\n  - { nivel: 1, discriminadores: [\"inconsciente\"] }\n"},
+		{"regla con nivel no definido", niveles + "reglas:\n  - { id: \"R\", nivel: 2, discriminadores: [\"inconsciente\"] }\n"},
+		{"regla sin condiciones ni discriminadores", niveles + "reglas:\n  - { id: \"R\", nivel: 1 }\n"},
+		{"discriminador no definido", niveles + "reglas:\n  - { id: \"R\", nivel: 1, discriminadores: [\"mareo\"] }\n"},
+		{"campo desconocido", niveles + "reglas:\n  - { id: \"R\", nivel: 1, condiciones: [{ campo: \"glucosa\", operador: \">\", valor: 300 }] }\n"},
+		{"operador desconocido", niveles + "reglas:\n  - { id: \"R\", nivel: 1, condiciones: [{ campo: \"temperatura\", operador: \"!=\", valor: 37 }] }\n"},
+		{"entre sin valor_max", niveles + "reglas:\n  - { id: \"R\", nivel: 1, condiciones: [{ campo: \"temperatura\", operador: \"entre\", valor: 37 }] }\n"},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			ruta := filepath.Join(t.TempDir(), "reglas.yml")
+			if err := os.WriteFile(ruta, []byte(caso.contenido), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := CargarReglasPrioridad(ruta); err == nil {
+				t.Fatal("se esperaba un error")
+			}
+		})
+	}
+
+	// El mismo encabezado con una regla válida se acepta
+	ruta := filepath.Join(t.TempDir(), "reglas.yml")
+	if err := os.WriteFile(ruta, []byte(niveles+"reglas:\n  - { id: \"R\", nivel: 1, discriminadores: [\"inconsciente\"] }\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CargarReglasPrioridad(ruta); err != nil {
+		t.Fatalf("reglas válidas rechazadas: %v", err)
+	}
+}
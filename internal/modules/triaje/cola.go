@@ -0,0 +1,237 @@
+package triaje
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Tipos de evento publicados en la cola de espera de un servicio
+const (
+	EventoEstado       = "estado"
+	EventoTriado       = "triado"
+	EventoRepriorizado = "repriorizado"
+	EventoLlamado      = "llamado"
+)
+
+const (
+	// eventosRetenidos es la cantidad de eventos por servicio que se conservan
+	// para reenviarlos a clientes que se reconectan con Last-Event-ID
+	eventosRetenidos = 200
+	// capacidadSuscriptor es el buffer de eventos pendientes por conexión
+	capacidadSuscriptor = 32
+	// vigenciaEntrada es el tiempo que un paciente triado permanece en la cola sin
+	// ser llamado; pasado ese plazo se descarta junto con la marca de llamado
+	vigenciaEntrada = 12 * time.Hour
+)
+
+// EntradaCola representa a un paciente triado que espera ser llamado al consultorio
+type EntradaCola struct {
+	IdAtencion         int       `json:"idAtencion"`
+	NroHistoriaClinica *float64  `json:"nroHistoriaClinica"`
+	Nivel              int       `json:"nivel"`
+	NombreNivel        string    `json:"nombreNivel"`
+	Color              string    `json:"color"`
+	FechaLlegada       time.Time `json:"fechaLlegada"`
+}
+
+// EventoCola es el mensaje enviado a los consultorios suscritos a un servicio
+type EventoCola struct {
+	Id         uint64        `json:"id"`
+	Tipo       string        `json:"tipo"`
+	IdServicio int           `json:"idServicio"`
+	IdAtencion int           `json:"idAtencion,omitempty"`
+	Fecha      time.Time     `json:"fecha"`
+	Cola       []EntradaCola `json:"cola"`
+}
+
+type colaServicio struct {
+	entradas     map[int]EntradaCola
+	eventos      []EventoCola
+	descartados  uint64            // id del último evento que ya no se conserva
+	llamados     map[int]time.Time // fecha de llegada de los pacientes ya llamados
+	suscriptores map[chan EventoCola]struct{}
+}
+
+// ColaTriaje es un pub/sub en memoria de las colas de espera por servicio.
+// Refleja los triajes de las últimas horas realizados desde el arranque del proceso.
+type ColaTriaje struct {
+	mu sync.Mutex
+	// inicio es el primer id de evento de este proceso; los ids anteriores
+	// pertenecen a un arranque previo y no se pueden reanudar
+	inicio   uint64
+	ultimoId uint64
+	colas    map[int]*colaServicio
+}
+
+func NuevaColaTriaje() *ColaTriaje {
+	// Los ids parten del segundo de arranque para no repetir los de un proceso anterior.
+	// Se desplaza 20 bits: cabe en un entero exacto de JavaScript hasta el año 2255.
+	inicio := uint64(time.Now().Unix()) << 20
+	return &ColaTriaje{inicio: inicio, ultimoId: inicio, colas: make(map[int]*colaServicio)}
+}
+
+func (c *ColaTriaje) cola(idServicio int) *colaServicio {
+	cola, ok := c.colas[idServicio]
+	if !ok {
+		cola = &colaServicio{
+			entradas:     make(map[int]EntradaCola),
+			llamados:     make(map[int]time.Time),
+			suscriptores: make(map[chan EventoCola]struct{}),
+		}
+		c.colas[idServicio] = cola
+	}
+	cola.purgar(time.Now())
+	return cola
+}
+
+// purgar descarta a los pacientes que llegaron hace más de vigenciaEntrada,
+// estén esperando o ya hayan sido llamados
+func (s *colaServicio) purgar(ahora time.Time) {
+	limite := ahora.Add(-vigenciaEntrada)
+	for idAtencion, entrada := range s.entradas {
+		if entrada.FechaLlegada.Before(limite) {
+			delete(s.entradas, idAtencion)
+		}
+	}
+	for idAtencion, llegada := range s.llamados {
+		if llegada.Before(limite) {
+			delete(s.llamados, idAtencion)
+		}
+	}
+}
+
+// ordenada retorna la cola por prioridad (nivel menor primero) y luego por orden de llegada
+func (s *colaServicio) ordenada() []EntradaCola {
+	entradas := make([]EntradaCola, 0, len(s.entradas))
+	for _, entrada := range s.entradas {
+		entradas = append(entradas, entrada)
+	}
+
+	sort.Slice(entradas, func(i, j int) bool {
+		if entradas[i].Nivel != entradas[j].Nivel {
+			return entradas[i].Nivel < entradas[j].Nivel
+		}
+		if !entradas[i].FechaLlegada.Equal(entradas[j].FechaLlegada) {
+			return entradas[i].FechaLlegada.Before(entradas[j].FechaLlegada)
+		}
+		return entradas[i].IdAtencion < entradas[j].IdAtencion
+	})
+	return entradas
+}
+
+// Encolar agrega o reprioriza a un paciente y notifica a los suscriptores del servicio.
+// Los pacientes que ya fueron llamados no vuelven a la cola si se corrige su triaje,
+// y los que llegaron hace más de vigenciaEntrada (p. ej. sincronizados tarde) no entran.
+func (c *ColaTriaje) Encolar(idServicio int, entrada EntradaCola) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cola := c.cola(idServicio)
+	if _, ok := cola.llamados[entrada.IdAtencion]; ok {
+		return
+	}
+	if entrada.FechaLlegada.Before(time.Now().Add(-vigenciaEntrada)) {
+		return
+	}
+
+	tipo := EventoTriado
+	if anterior, ok := cola.entradas[entrada.IdAtencion]; ok {
+		tipo = EventoRepriorizado
+		entrada.FechaLlegada = anterior.FechaLlegada
+	}
+
+	cola.entradas[entrada.IdAtencion] = entrada
+	c.publicar(idServicio, cola, tipo, entrada.IdAtencion)
+}
+
+// Llamar retira al paciente de la cola cuando el consultorio lo llama.
+// Retorna false si el paciente no estaba en la cola del servicio.
+func (c *ColaTriaje) Llamar(idServicio int, idAtencion int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cola := c.cola(idServicio)
+	entrada, ok := cola.entradas[idAtencion]
+	if !ok {
+		return false
+	}
+
+	delete(cola.entradas, idAtencion)
+	cola.llamados[idAtencion] = entrada.FechaLlegada
+	c.publicar(idServicio, cola, EventoLlamado, idAtencion)
+	return true
+}
+
+// publicar debe llamarse con c.mu tomado
+func (c *ColaTriaje) publicar(idServicio int, cola *colaServicio, tipo string, idAtencion int) {
+	c.ultimoId++
+	evento := EventoCola{
+		Id:         c.ultimoId,
+		Tipo:       tipo,
+		IdServicio: idServicio,
+		IdAtencion: idAtencion,
+		Fecha:      time.Now(),
+		Cola:       cola.ordenada(),
+	}
+
+	cola.eventos = append(cola.eventos, evento)
+	if exceso := len(cola.eventos) - eventosRetenidos; exceso > 0 {
+		cola.descartados = cola.eventos[exceso-1].Id
+		cola.eventos = cola.eventos[exceso:]
+	}
+
+	for suscriptor := range cola.suscriptores {
+		select {
+		case suscriptor <- evento:
+		default:
+			// El cliente no consume a tiempo; se cierra para que reconecte con Last-Event-ID
+			delete(cola.suscriptores, suscriptor)
+			close(suscriptor)
+		}
+	}
+}
+
+// Suscribir registra un consultorio en la cola del servicio. Retorna los eventos a
+// enviar de inmediato: los posteriores a ultimoId si aún se conservan, o el estado
+// actual de la cola si el cliente es nuevo o perdió demasiados eventos.
+func (c *ColaTriaje) Suscribir(idServicio int, ultimoId uint64) ([]EventoCola, <-chan EventoCola, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cola := c.cola(idServicio)
+	canal := make(chan EventoCola, capacidadSuscriptor)
+	cola.suscriptores[canal] = struct{}{}
+
+	cancelar := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := cola.suscriptores[canal]; ok {
+			delete(cola.suscriptores, canal)
+			close(canal)
+		}
+	}
+
+	return c.pendientes(idServicio, cola, ultimoId), canal, cancelar
+}
+
+func (c *ColaTriaje) pendientes(idServicio int, cola *colaServicio, ultimoId uint64) []EventoCola {
+	// Un id fuera del rango de este proceso proviene de un arranque anterior: se envía el estado completo
+	if ultimoId >= c.inicio && ultimoId <= c.ultimoId && ultimoId >= cola.descartados {
+		var pendientes []EventoCola
+		for _, evento := range cola.eventos {
+			if evento.Id > ultimoId {
+				pendientes = append(pendientes, evento)
+			}
+		}
+		return pendientes
+	}
+
+	return []EventoCola{{
+		Id:         c.ultimoId,
+		Tipo:       EventoEstado,
+		IdServicio: idServicio,
+		Fecha:      time.Now(),
+		Cola:       cola.ordenada(),
+	}}
+}
@@ -0,0 +1,317 @@
+package triaje
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// intervaloLatido mantiene viva la conexión SSE a través de proxies con timeout de inactividad
+const intervaloLatido = 15 * time.Second
+
+type Handler struct {
+	servicio *TriajeServicio
+	cola     *ColaTriaje
+}
+
+func NuevoHandler(servicio *TriajeServicio, cola *ColaTriaje) *Handler {
+	return &Handler{servicio: servicio, cola: cola}
+}
+
+// Obtener retorna los signos vitales registrados para la atención
+func (h *Handler) Obtener(c *fiber.Ctx) error {
+	idAtencion, err := obtenerIdAtencion(c)
+	if err != nil {
+		return err
+	}
+
+	triaje, err := h.servicio.Obtener(c.UserContext(), idAtencion)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   triaje,
+	})
+}
+
+// Registrar guarda el triaje de la atención por primera vez
+func (h *Handler) Registrar(c *fiber.Ctx) error {
+	idAtencion, err := obtenerIdAtencion(c)
+	if err != nil {
+		return err
+	}
+
+	var solicitud SolicitudTriaje
+	if err := c.BodyParser(&solicitud); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "El cuerpo de la solicitud no es válido.")
+	}
+
+	triaje, err := h.servicio.Registrar(c.UserContext(), idAtencion, solicitud)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": true,
+		"data":   triaje,
+	})
+}
+
+// Actualizar modifica los signos vitales de un triaje existente
+func (h *Handler) Actualizar(c *fiber.Ctx) error {
+	idAtencion, err := obtenerIdAtencion(c)
+	if err != nil {
+		return err
+	}
+
+	var solicitud SolicitudTriaje
+	if err := c.BodyParser(&solicitud); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "El cuerpo de la solicitud no es válido.")
+	}
+
+	triaje, err := h.servicio.Actualizar(c.UserContext(), idAtencion, solicitud)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   triaje,
+	})
+}
+
+// Sincronizar recibe un lote de triajes capturados sin conexión y responde el estado de cada registro
+func (h *Handler) Sincronizar(c *fiber.Ctx) error {
+	var solicitud SolicitudSincronizacion
+	if err := c.BodyParser(&solicitud); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "El cuerpo de la solicitud no es válido.")
+	}
+
+	reporte, err := h.servicio.Sincronizar(c.UserContext(), solicitud)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   reporte,
+	})
+}
+
+// Revisiones lista las versiones guardadas del triaje de la atención
+func (h *Handler) Revisiones(c *fiber.Ctx) error {
+	idAtencion, err := obtenerIdAtencion(c)
+	if err != nil {
+		return err
+	}
+
+	revisiones, err := h.servicio.Revisiones(c.UserContext(), idAtencion)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   revisiones,
+	})
+}
+
+// CompararRevisiones muestra los campos que cambiaron entre las revisiones indicadas en desde y hasta
+func (h *Handler) CompararRevisiones(c *fiber.Ctx) error {
+	idAtencion, err := obtenerIdAtencion(c)
+	if err != nil {
+		return err
+	}
+
+	desde, hasta := c.QueryInt("desde"), c.QueryInt("hasta")
+	if desde <= 0 || hasta <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Debe indicar las revisiones a comparar en desde y hasta.")
+	}
+
+	comparacion, err := h.servicio.CompararRevisiones(c.UserContext(), idAtencion, desde, hasta)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   comparacion,
+	})
+}
+
+// Historial retorna los triajes previos del paciente en todas sus atenciones.
+// Acepta los parámetros pagina, tamanio, orden (asc|desc) y el rango desde/hasta (AAAA-MM-DD).
+func (h *Handler) Historial(c *fiber.Ctx) error {
+	idPaciente, err := c.ParamsInt("idPaciente")
+	if err != nil || idPaciente <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "El paciente no es válido.")
+	}
+
+	filtro := FiltroHistorial{
+		Pagina:        c.QueryInt("pagina", 1),
+		TamanioPagina: c.QueryInt("tamanio", tamanioPaginaPorDefecto),
+	}
+
+	switch c.Query("orden", "desc") {
+	case "asc":
+		filtro.Ascendente = true
+	case "desc":
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "El orden debe ser asc o desc.")
+	}
+
+	if filtro.Desde, err = obtenerFecha(c, "desde"); err != nil {
+		return err
+	}
+	if filtro.Hasta, err = obtenerFecha(c, "hasta"); err != nil {
+		return err
+	}
+	if filtro.Hasta != nil {
+		// La fecha final se incluye completa
+		siguiente := filtro.Hasta.AddDate(0, 0, 1)
+		filtro.Hasta = &siguiente
+	}
+
+	historial, err := h.servicio.Historial(c.UserContext(), idPaciente, filtro)
+	if err != nil {
+		return traducirError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   historial,
+	})
+}
+
+func obtenerFecha(c *fiber.Ctx, parametro string) (*time.Time, error) {
+	valor := c.Query(parametro)
+	if valor == "" {
+		return nil, nil
+	}
+
+	fecha, err := time.ParseInLocation("2006-01-02", valor, time.Local)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("La fecha %s debe tener el formato AAAA-MM-DD.", parametro))
+	}
+	return &fecha, nil
+}
+
+// StreamCola envía por Server-Sent Events la cola de espera del servicio cada vez que
+// un paciente es triado, repriorizado o llamado. Los clientes que reconectan con el
+// encabezado Last-Event-ID reciben los eventos que perdieron.
+func (h *Handler) StreamCola(c *fiber.Ctx) error {
+	idServicio, err := c.ParamsInt("idServicio")
+	if err != nil || idServicio <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "El servicio no es válido.")
+	}
+
+	var ultimoId uint64
+	if valor := c.Get("Last-Event-ID"); valor != "" {
+		ultimoId, _ = strconv.ParseUint(valor, 10, 64)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	pendientes, eventos, cancelar := h.cola.Suscribir(idServicio, ultimoId)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancelar()
+
+		fmt.Fprintf(w, "retry: %d\n\n", (5 * time.Second).Milliseconds())
+		for _, evento := range pendientes {
+			if err := escribirEvento(w, evento); err != nil {
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		latido := time.NewTicker(intervaloLatido)
+		defer latido.Stop()
+
+		for {
+			select {
+			case evento, ok := <-eventos:
+				if !ok {
+					return
+				}
+				if err := escribirEvento(w, evento); err != nil {
+					return
+				}
+			case <-latido.C:
+				fmt.Fprint(w, ": latido\n\n")
+			}
+
+			// Un error al vaciar el buffer indica que el cliente cerró la conexión
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// LlamarPaciente retira al paciente de la cola cuando el consultorio lo llama
+func (h *Handler) LlamarPaciente(c *fiber.Ctx) error {
+	idServicio, err := c.ParamsInt("idServicio")
+	if err != nil || idServicio <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "El servicio no es válido.")
+	}
+
+	idAtencion, err := obtenerIdAtencion(c)
+	if err != nil {
+		return err
+	}
+
+	if !h.cola.Llamar(idServicio, idAtencion) {
+		return fiber.NewError(fiber.StatusNotFound, "El paciente no se encuentra en la cola del servicio.")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": true,
+		"data":   fiber.Map{"idAtencion": idAtencion},
+	})
+}
+
+func escribirEvento(w *bufio.Writer, evento EventoCola) error {
+	datos, err := json.Marshal(evento)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", evento.Id, evento.Tipo, datos)
+	return err
+}
+
+func obtenerIdAtencion(c *fiber.Ctx) (int, error) {
+	idAtencion, err := c.ParamsInt("idAtencion")
+	if err != nil || idAtencion <= 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "El N° de cuenta no es válido.")
+	}
+	return idAtencion, nil
+}
+
+// traducirError convierte los errores del servicio en errores HTTP
+func traducirError(err error) error {
+	switch {
+	case errors.Is(err, ErrTriajeNoRegistrado), errors.Is(err, ErrRevisionNoEncontrada):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, ErrTriajeYaRegistrado):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, ErrDiscriminadorDesconocido), errors.Is(err, ErrFiltroHistorialInvalido),
+		errors.Is(err, ErrMotivoRequerido), errors.Is(err, ErrComparacionInvalida), errors.Is(err, ErrLoteInvalido):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return err
+}
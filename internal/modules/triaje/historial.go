@@ -0,0 +1,171 @@
+package triaje
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/internal/shared/services/anemia"
+)
+
+// Límites de paginación del historial
+const (
+	tamanioPaginaPorDefecto = 20
+	tamanioPaginaMaximo     = 100
+)
+
+var ErrFiltroHistorialInvalido = errors.New("el filtro del historial no es válido")
+
+// FiltroHistorial delimita y ordena el historial de triajes de un paciente.
+// Hasta es exclusivo: se incluyen los triajes anteriores a esa fecha.
+type FiltroHistorial struct {
+	Desde         *time.Time
+	Hasta         *time.Time
+	Pagina        int
+	TamanioPagina int
+	Ascendente    bool
+}
+
+// RegistroHistorial es un triaje previo del paciente con el contexto de su atención
+type RegistroHistorial struct {
+	IdAtencion     int           `json:"idAtencion"`
+	FechaRegistro  time.Time     `json:"fechaRegistro"`
+	EdadPaciente   *int          `json:"edadPaciente"`
+	NombreMedico   *string       `json:"nombreMedico"`
+	IdServicio     *int          `json:"idServicio"`
+	NombreServicio *string       `json:"nombreServicio"`
+	SignosVitales  SignosVitales `json:"signosVitales"`
+	IMC            *float64      `json:"imc"`
+	Nivel          *int          `json:"nivel"`
+}
+
+// HistorialTriaje es una página del historial longitudinal del paciente
+type HistorialTriaje struct {
+	IdPaciente        int                     `json:"idPaciente"`
+	Pagina            int                     `json:"pagina"`
+	TamanioPagina     int                     `json:"tamanioPagina"`
+	Total             int                     `json:"total"`
+	Registros         []RegistroHistorial     `json:"registros"`
+	UltimaHemoglobina *anemia.ResultadoAnemia `json:"ultimaHemoglobina"`
+}
+
+// normalizar aplica los valores por defecto y valida el rango de fechas
+func (f *FiltroHistorial) normalizar() error {
+	if f.Pagina <= 0 {
+		f.Pagina = 1
+	}
+	if f.TamanioPagina <= 0 {
+		f.TamanioPagina = tamanioPaginaPorDefecto
+	}
+	if f.TamanioPagina > tamanioPaginaMaximo {
+		f.TamanioPagina = tamanioPaginaMaximo
+	}
+	if f.Desde != nil && f.Hasta != nil && !f.Desde.Before(*f.Hasta) {
+		return fmt.Errorf("%w: la fecha inicial debe ser anterior a la final", ErrFiltroHistorialInvalido)
+	}
+	return nil
+}
+
+// Historial retorna los triajes del paciente en todas sus atenciones, ordenados por fecha,
+// junto con su último resultado de hemoglobina
+func (s *TriajeServicio) Historial(ctx context.Context, idPaciente int, filtro FiltroHistorial) (*HistorialTriaje, error) {
+	if err := filtro.normalizar(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.EjecutarQuery(ctx, QueryHistorialPaciente, false,
+		sql.Named("idPaciente", idPaciente),
+		sql.Named("desde", filtro.Desde),
+		sql.Named("hasta", filtro.Hasta),
+		sql.Named("ascendente", filtro.Ascendente),
+		sql.Named("saltar", (filtro.Pagina-1)*filtro.TamanioPagina),
+		sql.Named("tamanio", filtro.TamanioPagina),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener historial de triaje: %w", err)
+	}
+	defer rows.Close()
+
+	historial := &HistorialTriaje{
+		IdPaciente:    idPaciente,
+		Pagina:        filtro.Pagina,
+		TamanioPagina: filtro.TamanioPagina,
+		Registros:     []RegistroHistorial{},
+	}
+
+	for rows.Next() {
+		var (
+			registro                     RegistroHistorial
+			columnas                     columnasSignos
+			edad, idServicio, nivel      sql.NullInt64
+			nombreMedico, nombreServicio sql.NullString
+		)
+
+		destinos := []interface{}{&registro.IdAtencion, &registro.FechaRegistro, &edad, &nombreMedico, &idServicio, &nombreServicio}
+		destinos = append(destinos, columnas.destinos()...)
+		destinos = append(destinos, &nivel, &historial.Total)
+		if err := rows.Scan(destinos...); err != nil {
+			return nil, err
+		}
+
+		registro.EdadPaciente = enteroDesdeNulo(edad)
+		registro.IdServicio = enteroDesdeNulo(idServicio)
+		registro.Nivel = enteroDesdeNulo(nivel)
+		if nombreMedico.Valid {
+			registro.NombreMedico = &nombreMedico.String
+		}
+		if nombreServicio.Valid {
+			registro.NombreServicio = &nombreServicio.String
+		}
+		registro.SignosVitales = columnas.signos()
+		registro.IMC = registro.SignosVitales.CalcularIMC()
+
+		historial.Registros = append(historial.Registros, registro)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Fuera de la última página el total no viene en ninguna fila
+	if len(historial.Registros) == 0 && filtro.Pagina > 1 {
+		if historial.Total, err = s.contarHistorial(ctx, idPaciente, filtro); err != nil {
+			return nil, err
+		}
+	}
+
+	ultima, err := s.anemia.ObtenerUltimoPorPaciente(ctx, idPaciente)
+	if err != nil && !errors.Is(err, anemia.ErrTamizajeNoRegistrado) {
+		return nil, err
+	}
+	historial.UltimaHemoglobina = ultima
+
+	return historial, nil
+}
+
+func (s *TriajeServicio) contarHistorial(ctx context.Context, idPaciente int, filtro FiltroHistorial) (int, error) {
+	row := s.db.EjecutarQueryRow(ctx, QueryContarHistorialPaciente, false,
+		sql.Named("idPaciente", idPaciente),
+		sql.Named("desde", filtro.Desde),
+		sql.Named("hasta", filtro.Hasta),
+	)
+	if row == nil {
+		return 0, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	var total int
+	if err := row.Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func enteroDesdeNulo(valor sql.NullInt64) *int {
+	if !valor.Valid {
+		return nil
+	}
+
+	entero := int(valor.Int64)
+	return &entero
+}
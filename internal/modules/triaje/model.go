@@ -0,0 +1,62 @@
+package triaje
+
+import (
+	"math"
+	"time"
+
+	"backend/internal/shared/crecimiento"
+	"backend/internal/shared/services/anemia"
+	"backend/internal/shared/services/atenciones"
+)
+
+// SignosVitales representa los valores capturados en la estación de triaje
+type SignosVitales struct {
+	PresionSistolica       *int     `json:"presionSistolica"`
+	PresionDiastolica      *int     `json:"presionDiastolica"`
+	FrecuenciaCardiaca     *int     `json:"frecuenciaCardiaca"`
+	FrecuenciaRespiratoria *int     `json:"frecuenciaRespiratoria"`
+	Temperatura            *float64 `json:"temperatura"`
+	SaturacionOxigeno      *int     `json:"saturacionOxigeno"`
+	Peso                   *float64 `json:"peso"`
+	Talla                  *float64 `json:"talla"`
+	PerimetroCefalico      *float64 `json:"perimetroCefalico"`
+	PerimetroAbdominal     *float64 `json:"perimetroAbdominal"`
+}
+
+// Triaje representa el triaje registrado para una atención
+type Triaje struct {
+	IdAtencion      int                       `json:"idAtencion"`
+	Paciente        *atenciones.DatosPaciente `json:"paciente"`
+	Edad            *atenciones.EdadDetallada `json:"edad"`
+	SignosVitales   SignosVitales             `json:"signosVitales"`
+	IMC             *float64                  `json:"imc"`
+	MotivoConsulta  *string                   `json:"motivoConsulta"`
+	Discriminadores []string                  `json:"discriminadores"`
+	Clasificacion   *Clasificacion            `json:"clasificacion"`
+	Crecimiento     *crecimiento.Evaluacion   `json:"crecimiento"`
+	Anemia          *anemia.ResultadoAnemia   `json:"anemia"`
+	FechaRegistro   *time.Time                `json:"fechaRegistro"`
+	IdUsuario       *int                      `json:"idUsuario"`
+	NroRevision     int                       `json:"nroRevision"`
+}
+
+// SolicitudTriaje representa el cuerpo recibido al registrar o actualizar un triaje
+type SolicitudTriaje struct {
+	IdUsuario             int      `json:"idUsuario"`
+	MotivoConsulta        string   `json:"motivoConsulta"`
+	Discriminadores       []string `json:"discriminadores"`
+	ConfirmarAdvertencias bool     `json:"confirmarAdvertencias"`
+	MotivoCorreccion      string   `json:"motivoCorreccion"` // obligatorio al actualizar
+	SignosVitales
+}
+
+// CalcularIMC calcula el índice de masa corporal a partir del peso (kg) y la talla (cm)
+func (s SignosVitales) CalcularIMC() *float64 {
+	if s.Peso == nil || s.Talla == nil || *s.Peso <= 0 || *s.Talla <= 0 {
+		return nil
+	}
+
+	metros := *s.Talla / 100
+	imc := math.Round(*s.Peso/(metros*metros)*100) / 100
+	return &imc
+}
@@ -0,0 +1,82 @@
+package triaje
+
+import (
+	"backend/internal/config"
+	"backend/internal/config/database"
+	sharedDB "backend/internal/shared/database"
+	"backend/internal/shared/middlewares"
+	"backend/internal/shared/services/anemia"
+	"backend/internal/shared/services/atenciones"
+	"backend/internal/shared/services/auditoria"
+	"backend/internal/shared/services/elegibilidad"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Rutas usadas cuando config.yml no define la sección triaje
+const (
+	rutaReglasPorDefecto = "triaje_prioridad.yml"
+	rutaRangosPorDefecto = "triaje_rangos.yml"
+)
+
+type Modulo struct {
+	handler *Handler
+}
+
+// NuevoModulo construye el módulo de triaje con sus servicios compartidos
+func NuevoModulo(db *database.GestorDB, elegibilidadServicio *elegibilidad.ElegibilidadServicio) *Modulo {
+	rutaReglas := rutaReglasPorDefecto
+	rutaRangos := rutaRangosPorDefecto
+	altitud := 0
+	if cfg := config.Obtener(); cfg != nil {
+		if cfg.Triaje.ReglasPrioridad != "" {
+			rutaReglas = cfg.Triaje.ReglasPrioridad
+		}
+		if cfg.Triaje.RangosSignos != "" {
+			rutaRangos = cfg.Triaje.RangosSignos
+		}
+		altitud = cfg.Establecimiento.AltitudMsnm
+	}
+
+	servicioDB := sharedDB.NuevoServicio(db)
+	atencionesServicio := atenciones.NuevoServicio(servicioDB)
+	auditoriaServicio := auditoria.NuevoServicio(servicioDB)
+	cola := NuevaColaTriaje()
+	servicio := NuevoServicio(
+		servicioDB,
+		atencionesServicio,
+		auditoriaServicio,
+		elegibilidadServicio,
+		anemia.NuevoServicio(servicioDB, atencionesServicio, auditoriaServicio, elegibilidadServicio, altitud),
+		NuevoMotorPrioridad(rutaReglas),
+		NuevoValidadorSignos(rutaRangos),
+		cola,
+	)
+
+	return &Modulo{handler: NuevoHandler(servicio, cola)}
+}
+
+// RegistrarRutas registra los endpoints del módulo bajo /triaje
+func (m *Modulo) RegistrarRutas(router fiber.Router, requiere middlewares.Requiere, audita middlewares.Audita) {
+	leer := requiere("triaje:leer")
+	escribir := requiere("triaje:escribir")
+
+	grupo := router.Group("/triaje")
+	grupo.Get("/:idAtencion<int>", leer, m.handler.Obtener)
+	grupo.Post("/:idAtencion<int>", escribir, m.handler.Registrar)
+	grupo.Put("/:idAtencion<int>", escribir, m.handler.Actualizar)
+	grupo.Get("/:idAtencion<int>/revisiones", leer, m.handler.Revisiones)
+	grupo.Get("/:idAtencion<int>/revisiones/comparar", leer, m.handler.CompararRevisiones)
+	grupo.Post("/sincronizar", escribir, m.handler.Sincronizar)
+	grupo.Get("/paciente/:idPaciente<int>/historial", leer, m.handler.Historial)
+	grupo.Get("/cola/:idServicio<int>/stream", leer, m.handler.StreamCola)
+	grupo.Post("/cola/:idServicio<int>/llamar/:idAtencion<int>", requiere("triaje:llamar"), audita(middlewares.RutaAuditada{
+		Tabla:      TablaTriaje,
+		IdListItem: IdListItemTriaje,
+		IdRegistro: middlewares.IdDeParametro("idAtencion"),
+		Accion:     auditoria.AccionModificar,
+		Observaciones: func(c *fiber.Ctx) string {
+			return "Llamado del paciente desde la cola del servicio " + c.Params("idServicio")
+		},
+	}), m.handler.LlamarPaciente)
+}
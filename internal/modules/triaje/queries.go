@@ -0,0 +1,196 @@
+package triaje
+
+const (
+	QueryObtenerTriaje = `
+  SELECT
+    ce.TriajePresion,
+    ce.TriajePulso,
+    ce.TriajeFrecRespiratoria,
+    ce.TriajeTemperatura,
+    ce.TriajeSaturacionOxigeno,
+    ce.TriajePeso,
+    ce.TriajeTalla,
+    ce.TriajePerimCefalico,
+    ce.TriajePerimAbdominal,
+    ce.TriajeFecha,
+    ce.TriajeIdUsuario
+  FROM AtencionesCE ce
+  WHERE ce.IdAtencion = @idAtencion AND ce.TriajeFecha IS NOT NULL`
+
+	// QueryBloquearTriaje lee el triaje dentro de una transacción y lo bloquea hasta
+	// confirmarla, para que dos correcciones simultáneas partan de valores distintos
+	QueryBloquearTriaje = `
+  SELECT
+    ce.TriajePresion,
+    ce.TriajePulso,
+    ce.TriajeFrecRespiratoria,
+    ce.TriajeTemperatura,
+    ce.TriajeSaturacionOxigeno,
+    ce.TriajePeso,
+    ce.TriajeTalla,
+    ce.TriajePerimCefalico,
+    ce.TriajePerimAbdominal,
+    ce.TriajeFecha,
+    ce.TriajeIdUsuario
+  FROM AtencionesCE ce WITH (UPDLOCK, HOLDLOCK)
+  WHERE ce.IdAtencion = @idAtencion AND ce.TriajeFecha IS NOT NULL`
+
+	// QueryRegistrarTriaje solo escribe si la atención aún no tiene triaje. El bloqueo
+	// serializa dos registros simultáneos: el segundo no afecta filas.
+	QueryRegistrarTriaje = `
+  IF EXISTS (SELECT 1 FROM AtencionesCE WITH (UPDLOCK, HOLDLOCK) WHERE IdAtencion = @idAtencion)
+    UPDATE AtencionesCE SET
+      TriajeEdad = @edad,
+      TriajePresion = @presion,
+      TriajePulso = @pulso,
+      TriajeFrecRespiratoria = @frecRespiratoria,
+      TriajeTemperatura = @temperatura,
+      TriajeSaturacionOxigeno = @saturacion,
+      TriajePeso = @peso,
+      TriajeTalla = @talla,
+      TriajePerimCefalico = @perimCefalico,
+      TriajePerimAbdominal = @perimAbdominal,
+      TriajeFecha = GETDATE(),
+      TriajeIdUsuario = @idUsuario
+    WHERE IdAtencion = @idAtencion AND TriajeFecha IS NULL
+  ELSE
+    INSERT INTO AtencionesCE (
+      IdAtencion, NroHistoriaClinica, TriajeEdad, TriajePresion, TriajePulso,
+      TriajeFrecRespiratoria, TriajeTemperatura, TriajeSaturacionOxigeno,
+      TriajePeso, TriajeTalla, TriajePerimCefalico, TriajePerimAbdominal,
+      TriajeFecha, TriajeIdUsuario
+    ) VALUES (
+      @idAtencion, @nroHistoriaClinica, @edad, @presion, @pulso,
+      @frecRespiratoria, @temperatura, @saturacion,
+      @peso, @talla, @perimCefalico, @perimAbdominal,
+      GETDATE(), @idUsuario
+    )`
+
+	QueryActualizarTriaje = `
+  UPDATE AtencionesCE SET
+    TriajePresion = @presion,
+    TriajePulso = @pulso,
+    TriajeFrecRespiratoria = @frecRespiratoria,
+    TriajeTemperatura = @temperatura,
+    TriajeSaturacionOxigeno = @saturacion,
+    TriajePeso = @peso,
+    TriajeTalla = @talla,
+    TriajePerimCefalico = @perimCefalico,
+    TriajePerimAbdominal = @perimAbdominal
+  WHERE IdAtencion = @idAtencion AND TriajeFecha IS NOT NULL`
+)
+
+const (
+	QueryGuardarClasificacion = `
+  MERGE dbo.TriajeClasificacion AS destino
+  USING (SELECT @idAtencion AS IdAtencion) AS origen
+  ON destino.IdAtencion = origen.IdAtencion
+  WHEN MATCHED THEN UPDATE SET
+    Nivel = @nivel,
+    IdRegla = @idRegla,
+    DescripcionRegla = @descripcionRegla,
+    VersionReglas = @versionReglas,
+    MotivoConsulta = @motivoConsulta,
+    Discriminadores = @discriminadores,
+    FechaRegistro = GETDATE()
+  WHEN NOT MATCHED THEN
+    INSERT (IdAtencion, Nivel, IdRegla, DescripcionRegla, VersionReglas, MotivoConsulta, Discriminadores)
+    VALUES (@idAtencion, @nivel, @idRegla, @descripcionRegla, @versionReglas, @motivoConsulta, @discriminadores);`
+
+	QueryObtenerClasificacion = `
+  SELECT Nivel, IdRegla, DescripcionRegla, VersionReglas, MotivoConsulta, Discriminadores
+  FROM dbo.TriajeClasificacion
+  WHERE IdAtencion = @idAtencion`
+)
+
+const (
+	// QueryHistorialPaciente lista los triajes del paciente en todas sus citas.
+	// El total se calcula con una función de ventana para paginar en una sola consulta.
+	QueryHistorialPaciente = `
+  SELECT
+    ce.IdAtencion,
+    ce.TriajeFecha,
+    a.Edad AS edadPaciente,
+    e.ApellidoPaterno + ' ' + isnull(e.ApellidoMaterno, '') + ' ' + e.Nombres AS NombreMedico,
+    s.IdServicio,
+    s.Nombre AS nombreServicio,
+    ce.TriajePresion,
+    ce.TriajePulso,
+    ce.TriajeFrecRespiratoria,
+    ce.TriajeTemperatura,
+    ce.TriajeSaturacionOxigeno,
+    ce.TriajePeso,
+    ce.TriajeTalla,
+    ce.TriajePerimCefalico,
+    ce.TriajePerimAbdominal,
+    tc.Nivel,
+    COUNT(*) OVER() AS Total
+  FROM AtencionesCE ce
+  INNER JOIN Atenciones a ON ce.IdAtencion = a.IdAtencion
+  INNER JOIN Citas c ON a.IdAtencion = c.IdAtencion
+  LEFT JOIN ProgramacionMedica p ON c.IdProgramacion = p.IdProgramacion
+  LEFT JOIN Servicios s ON p.IdServicio = s.IdServicio
+  LEFT JOIN Medicos m ON p.IdMedico = m.IdMedico
+  LEFT JOIN Empleados e ON m.IdEmpleado = e.IdEmpleado
+  LEFT JOIN dbo.TriajeClasificacion tc ON ce.IdAtencion = tc.IdAtencion
+  WHERE c.IdPaciente = @idPaciente
+    AND ce.TriajeFecha IS NOT NULL
+    AND (@desde IS NULL OR ce.TriajeFecha >= @desde)
+    AND (@hasta IS NULL OR ce.TriajeFecha < @hasta)
+  ORDER BY
+    CASE WHEN @ascendente = 1 THEN ce.TriajeFecha END ASC,
+    CASE WHEN @ascendente = 0 THEN ce.TriajeFecha END DESC,
+    ce.IdAtencion
+  OFFSET @saltar ROWS FETCH NEXT @tamanio ROWS ONLY`
+
+	QueryContarHistorialPaciente = `
+  SELECT COUNT(*)
+  FROM AtencionesCE ce
+  INNER JOIN Citas c ON ce.IdAtencion = c.IdAtencion
+  WHERE c.IdPaciente = @idPaciente
+    AND ce.TriajeFecha IS NOT NULL
+    AND (@desde IS NULL OR ce.TriajeFecha >= @desde)
+    AND (@hasta IS NULL OR ce.TriajeFecha < @hasta)`
+)
+
+const (
+	// QueryGuardarRevision asigna el siguiente número de revisión de la atención.
+	// El bloqueo evita que dos ediciones simultáneas obtengan el mismo número.
+	QueryGuardarRevision = `
+  INSERT INTO dbo.TriajeRevision (IdAtencion, NroRevision, IdEmpleado, Motivo, Datos, FechaRegistro)
+  OUTPUT INSERTED.NroRevision
+  SELECT @idAtencion, ISNULL(MAX(NroRevision), 0) + 1, @idEmpleado, @motivo, @datos, ISNULL(@fecha, GETDATE())
+  FROM dbo.TriajeRevision WITH (UPDLOCK, HOLDLOCK)
+  WHERE IdAtencion = @idAtencion`
+
+	QueryListarRevisiones = `
+  SELECT NroRevision, IdEmpleado, Motivo, Datos, FechaRegistro
+  FROM dbo.TriajeRevision
+  WHERE IdAtencion = @idAtencion
+  ORDER BY NroRevision`
+
+	QueryUltimaRevision = `
+  SELECT ISNULL(MAX(NroRevision), 0)
+  FROM dbo.TriajeRevision
+  WHERE IdAtencion = @idAtencion`
+)
+
+const (
+	QueryObtenerSincronizacion = `
+  SELECT IdAtencion, NroRevision
+  FROM dbo.TriajeSincronizacion
+  WHERE Uuid = @uuid`
+
+	// QueryReclamarSincronizacion reserva el UUID antes de aplicar el registro. El bloqueo
+	// hace esperar a un envío simultáneo del mismo UUID, que luego no inserta filas.
+	QueryReclamarSincronizacion = `
+  INSERT INTO dbo.TriajeSincronizacion (Uuid, IdAtencion, NroRevision, IdEmpleado, FechaCaptura)
+  SELECT @uuid, @idAtencion, 0, @idEmpleado, @fechaCaptura
+  WHERE NOT EXISTS (
+    SELECT 1 FROM dbo.TriajeSincronizacion WITH (UPDLOCK, HOLDLOCK) WHERE Uuid = @uuid
+  )`
+
+	QueryCompletarSincronizacion = `
+  UPDATE dbo.TriajeSincronizacion SET NroRevision = @nroRevision
+  WHERE Uuid = @uuid`
+)
@@ -0,0 +1,299 @@
+package triaje
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/shared/services/auditoria"
+)
+
+// motivoRevisionInicial identifica la revisión creada a partir de un triaje registrado
+// antes de que existiera el historial de revisiones
+const motivoRevisionInicial = "Valores previos al historial de revisiones"
+
+var (
+	ErrMotivoRequerido      = errors.New("debe indicar el motivo de la corrección")
+	ErrRevisionNoEncontrada = errors.New("la revisión solicitada no existe")
+	ErrComparacionInvalida  = errors.New("debe indicar dos revisiones distintas para comparar")
+)
+
+// ContenidoRevision es la fotografía de los datos del triaje en una revisión
+type ContenidoRevision struct {
+	SignosVitales   SignosVitales `json:"signosVitales"`
+	MotivoConsulta  *string       `json:"motivoConsulta"`
+	Discriminadores []string      `json:"discriminadores"`
+	Nivel           *int          `json:"nivel"`
+}
+
+// RevisionTriaje es una versión guardada del triaje con su autor y motivo
+type RevisionTriaje struct {
+	NroRevision    int       `json:"nroRevision"`
+	IdEmpleado     int       `json:"idEmpleado"`
+	NombreEmpleado string    `json:"nombreEmpleado"`
+	Motivo         *string   `json:"motivo"`
+	FechaRegistro  time.Time `json:"fechaRegistro"`
+	ContenidoRevision
+}
+
+// CambioCampo describe el valor de un campo antes y después de una corrección
+type CambioCampo struct {
+	Campo    string      `json:"campo"`
+	Anterior interface{} `json:"anterior"`
+	Nuevo    interface{} `json:"nuevo"`
+}
+
+// ComparacionRevisiones es el resultado de comparar dos revisiones del triaje
+type ComparacionRevisiones struct {
+	IdAtencion int           `json:"idAtencion"`
+	Desde      int           `json:"desde"`
+	Hasta      int           `json:"hasta"`
+	Cambios    []CambioCampo `json:"cambios"`
+}
+
+// contenidoTriaje extrae los datos versionados de un triaje ya cargado
+func contenidoTriaje(triaje *Triaje) ContenidoRevision {
+	contenido := ContenidoRevision{
+		SignosVitales:   triaje.SignosVitales,
+		MotivoConsulta:  triaje.MotivoConsulta,
+		Discriminadores: triaje.Discriminadores,
+	}
+	if triaje.Clasificacion != nil {
+		nivel := triaje.Clasificacion.Nivel
+		contenido.Nivel = &nivel
+	}
+	return contenido
+}
+
+// contenidoSolicitud construye los datos versionados a partir de lo enviado por el cliente
+func contenidoSolicitud(solicitud SolicitudTriaje, clasificacion *Clasificacion) ContenidoRevision {
+	contenido := ContenidoRevision{
+		SignosVitales:   solicitud.SignosVitales,
+		Discriminadores: solicitud.Discriminadores,
+		Nivel:           &clasificacion.Nivel,
+	}
+	if motivo := strings.TrimSpace(solicitud.MotivoConsulta); motivo != "" {
+		contenido.MotivoConsulta = &motivo
+	}
+	return contenido
+}
+
+// CompararContenido retorna los campos que difieren entre dos revisiones, en el orden del formulario
+func CompararContenido(anterior, nuevo ContenidoRevision) []CambioCampo {
+	cambios := []CambioCampo{}
+
+	for _, campo := range ordenCampos {
+		valorAnterior := camposSignos[campo](anterior.SignosVitales)
+		valorNuevo := camposSignos[campo](nuevo.SignosVitales)
+		if !decimalesIguales(valorAnterior, valorNuevo) {
+			cambios = append(cambios, CambioCampo{Campo: campo, Anterior: valorAnterior, Nuevo: valorNuevo})
+		}
+	}
+
+	if textoNulo(anterior.MotivoConsulta) != textoNulo(nuevo.MotivoConsulta) {
+		cambios = append(cambios, CambioCampo{Campo: "motivoConsulta", Anterior: anterior.MotivoConsulta, Nuevo: nuevo.MotivoConsulta})
+	}
+
+	if unirDiscriminadores(anterior.Discriminadores) != unirDiscriminadores(nuevo.Discriminadores) {
+		cambios = append(cambios, CambioCampo{Campo: "discriminadores", Anterior: anterior.Discriminadores, Nuevo: nuevo.Discriminadores})
+	}
+
+	if (anterior.Nivel == nil) != (nuevo.Nivel == nil) || (anterior.Nivel != nil && *anterior.Nivel != *nuevo.Nivel) {
+		cambios = append(cambios, CambioCampo{Campo: "nivel", Anterior: anterior.Nivel, Nuevo: nuevo.Nivel})
+	}
+
+	return cambios
+}
+
+func decimalesIguales(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func textoNulo(valor *string) string {
+	if valor == nil {
+		return ""
+	}
+	return *valor
+}
+
+// guardarRevision agrega una revisión dentro de tx y retorna su número. Si fecha es nil se usa la hora del servidor.
+func guardarRevision(ctx context.Context, tx *sql.Tx, idAtencion int, idEmpleado int, motivo *string, fecha *time.Time, contenido ContenidoRevision) (int, error) {
+	datos, err := json.Marshal(contenido)
+	if err != nil {
+		return 0, fmt.Errorf("error al serializar revisión de triaje: %w", err)
+	}
+
+	var nroRevision int
+	err = tx.QueryRowContext(ctx, QueryGuardarRevision,
+		sql.Named("idAtencion", idAtencion),
+		sql.Named("idEmpleado", idEmpleado),
+		sql.Named("motivo", motivo),
+		sql.Named("datos", string(datos)),
+		sql.Named("fecha", fecha),
+	).Scan(&nroRevision)
+	if err != nil {
+		return 0, fmt.Errorf("error al guardar revisión de triaje: %w", err)
+	}
+
+	return nroRevision, nil
+}
+
+// ultimaRevision retorna el número de la revisión vigente, o 0 si el triaje no tiene revisiones
+func (s *TriajeServicio) ultimaRevision(ctx context.Context, idAtencion int) (int, error) {
+	row := s.db.EjecutarQueryRow(ctx, QueryUltimaRevision, false, sql.Named("idAtencion", idAtencion))
+	if row == nil {
+		return 0, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	var nroRevision int
+	if err := row.Scan(&nroRevision); err != nil {
+		return 0, err
+	}
+	return nroRevision, nil
+}
+
+// revisionVigente retorna el número de revisión que ven los clientes. Un triaje registrado
+// antes del historial cuenta como revisión 1, igual que en Revisiones.
+func (s *TriajeServicio) revisionVigente(ctx context.Context, idAtencion int) (int, error) {
+	nroRevision, err := s.ultimaRevision(ctx, idAtencion)
+	if err != nil {
+		return 0, err
+	}
+	if nroRevision == 0 {
+		return 1, nil
+	}
+	return nroRevision, nil
+}
+
+// asegurarRevisionInicial conserva como primera revisión los valores de un triaje registrado
+// antes del historial de revisiones, para que la primera corrección no los pierda
+func asegurarRevisionInicial(ctx context.Context, tx *sql.Tx, actual *Triaje) error {
+	var nroRevision int
+	if err := tx.QueryRowContext(ctx, QueryUltimaRevision, sql.Named("idAtencion", actual.IdAtencion)).Scan(&nroRevision); err != nil || nroRevision > 0 {
+		return err
+	}
+
+	idEmpleado := 0
+	if actual.IdUsuario != nil {
+		idEmpleado = *actual.IdUsuario
+	}
+	motivo := motivoRevisionInicial
+	_, err := guardarRevision(ctx, tx, actual.IdAtencion, idEmpleado, &motivo, actual.FechaRegistro, contenidoTriaje(actual))
+	return err
+}
+
+// Revisiones lista todas las versiones del triaje de la atención, de la más antigua a la más reciente
+func (s *TriajeServicio) Revisiones(ctx context.Context, idAtencion int) ([]RevisionTriaje, error) {
+	rows, err := s.db.EjecutarQuery(ctx, QueryListarRevisiones, false, sql.Named("idAtencion", idAtencion))
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener revisiones de triaje: %w", err)
+	}
+	defer rows.Close()
+
+	revisiones := []RevisionTriaje{}
+	for rows.Next() {
+		var (
+			revision RevisionTriaje
+			motivo   sql.NullString
+			datos    string
+		)
+		if err := rows.Scan(&revision.NroRevision, &revision.IdEmpleado, &motivo, &datos, &revision.FechaRegistro); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(datos), &revision.ContenidoRevision); err != nil {
+			return nil, fmt.Errorf("revisión %d de la atención %d dañada: %w", revision.NroRevision, idAtencion, err)
+		}
+		if motivo.Valid {
+			revision.Motivo = &motivo.String
+		}
+		revisiones = append(revisiones, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(revisiones) == 0 {
+		// Triaje anterior al historial: su única versión es la vigente
+		actual, err := s.obtenerSignos(ctx, idAtencion)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.cargarClasificacion(ctx, actual); err != nil {
+			return nil, err
+		}
+		revision := RevisionTriaje{NroRevision: 1, ContenidoRevision: contenidoTriaje(actual)}
+		if actual.IdUsuario != nil {
+			revision.IdEmpleado = *actual.IdUsuario
+		}
+		if actual.FechaRegistro != nil {
+			revision.FechaRegistro = *actual.FechaRegistro
+		}
+		motivo := motivoRevisionInicial
+		revision.Motivo = &motivo
+		revisiones = append(revisiones, revision)
+	}
+
+	nombres := make(map[int]string)
+	for i := range revisiones {
+		id := revisiones[i].IdEmpleado
+		if _, ok := nombres[id]; !ok {
+			nombre, err := s.auditoria.ObtenerNombreEmpleado(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			nombres[id] = nombre
+		}
+		revisiones[i].NombreEmpleado = nombres[id]
+	}
+
+	return revisiones, nil
+}
+
+// CompararRevisiones retorna los campos que cambiaron entre dos revisiones del triaje
+func (s *TriajeServicio) CompararRevisiones(ctx context.Context, idAtencion int, desde int, hasta int) (*ComparacionRevisiones, error) {
+	if desde == hasta {
+		return nil, ErrComparacionInvalida
+	}
+
+	revisiones, err := s.Revisiones(ctx, idAtencion)
+	if err != nil {
+		return nil, err
+	}
+
+	var anterior, nueva *RevisionTriaje
+	for i := range revisiones {
+		switch revisiones[i].NroRevision {
+		case desde:
+			anterior = &revisiones[i]
+		case hasta:
+			nueva = &revisiones[i]
+		}
+	}
+	if anterior == nil || nueva == nil {
+		return nil, ErrRevisionNoEncontrada
+	}
+
+	return &ComparacionRevisiones{
+		IdAtencion: idAtencion,
+		Desde:      desde,
+		Hasta:      hasta,
+		Cambios:    CompararContenido(anterior.ContenidoRevision, nueva.ContenidoRevision),
+	}, nil
+}
+
+// observacionesCorreccion serializa la corrección para el campo observaciones de la
+// auditoría; las consultas de auditoría la leen con auditoria.LeerCambios
+func observacionesCorreccion(nroRevision int, motivo string, cambios []CambioCampo) string {
+	diferencias := make([]auditoria.Cambio, 0, len(cambios))
+	for _, cambio := range cambios {
+		diferencias = append(diferencias, auditoria.Cambio{Campo: cambio.Campo, Anterior: cambio.Anterior, Nuevo: cambio.Nuevo})
+	}
+	return auditoria.ObservacionesCambios(fmt.Sprintf("Corrección de triaje, revisión %d: %s", nroRevision, motivo), diferencias)
+}
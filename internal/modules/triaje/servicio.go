@@ -0,0 +1,549 @@
+package triaje
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/shared/crecimiento"
+	"backend/internal/shared/database"
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/services/anemia"
+	"backend/internal/shared/services/atenciones"
+	"backend/internal/shared/services/auditoria"
+	"backend/internal/shared/services/elegibilidad"
+)
+
+// Tabla e item de menú de SIGH con los que se audita el triaje
+const (
+	TablaTriaje      = "AtencionesCE"
+	IdListItemTriaje = 1303
+)
+
+var (
+	ErrTriajeNoRegistrado = errors.New("la atención no tiene triaje registrado")
+	ErrTriajeYaRegistrado = errors.New("la atención ya tiene triaje registrado")
+)
+
+type TriajeServicio struct {
+	db           *database.ServicioDB
+	atenciones   *atenciones.AtencionesServicio
+	auditoria    *auditoria.AuditoriaServicio
+	elegibilidad *elegibilidad.ElegibilidadServicio
+	anemia       *anemia.AnemiaServicio
+	prioridad    *MotorPrioridad
+	validador    *ValidadorSignos
+	cola         *ColaTriaje
+}
+
+func NuevoServicio(
+	db *database.ServicioDB,
+	atencionesServicio *atenciones.AtencionesServicio,
+	auditoriaServicio *auditoria.AuditoriaServicio,
+	elegibilidadServicio *elegibilidad.ElegibilidadServicio,
+	anemiaServicio *anemia.AnemiaServicio,
+	motorPrioridad *MotorPrioridad,
+	validador *ValidadorSignos,
+	cola *ColaTriaje,
+) *TriajeServicio {
+	return &TriajeServicio{
+		db:           db,
+		atenciones:   atencionesServicio,
+		auditoria:    auditoriaServicio,
+		elegibilidad: elegibilidadServicio,
+		anemia:       anemiaServicio,
+		prioridad:    motorPrioridad,
+		validador:    validador,
+		cola:         cola,
+	}
+}
+
+// Obtener retorna el triaje de la atención junto con la cabecera del paciente
+func (s *TriajeServicio) Obtener(ctx context.Context, idAtencion int) (*Triaje, error) {
+	paciente, err := s.atenciones.ObtenerDatosPaciente(ctx, idAtencion)
+	if err != nil {
+		return nil, err
+	}
+
+	triaje, err := s.obtenerSignos(ctx, idAtencion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cargarClasificacion(ctx, triaje); err != nil {
+		return nil, err
+	}
+
+	if triaje.NroRevision, err = s.revisionVigente(ctx, idAtencion); err != nil {
+		return nil, err
+	}
+
+	triaje.Paciente = paciente
+	if err := evaluarCrecimiento(triaje); err != nil {
+		return nil, err
+	}
+
+	resultadoAnemia, err := s.anemia.Obtener(ctx, idAtencion)
+	if err != nil && !errors.Is(err, anemia.ErrTamizajeNoRegistrado) {
+		return nil, err
+	}
+	triaje.Anemia = resultadoAnemia
+
+	return triaje, nil
+}
+
+// cambioTriaje es un triaje ya validado y clasificado, listo para escribirse en una
+// transacción. motivo es nil en el registro inicial y obligatorio en una corrección.
+type cambioTriaje struct {
+	idAtencion    int
+	solicitud     SolicitudTriaje
+	paciente      *atenciones.DatosPaciente
+	clasificacion *Clasificacion
+	motivo        *string
+}
+
+// triajeGuardado es la revisión escrita por un cambioTriaje y la auditoría que le corresponde
+type triajeGuardado struct {
+	nroRevision   int
+	accion        string
+	observaciones string
+}
+
+// Registrar guarda el primer triaje de la atención y su clasificación de prioridad.
+// Requiere que el financiamiento de la atención permita el triaje.
+func (s *TriajeServicio) Registrar(ctx context.Context, idAtencion int, solicitud SolicitudTriaje) (*Triaje, error) {
+	solicitud.IdUsuario = autor(ctx, solicitud.IdUsuario)
+
+	cambio, err := s.preparar(ctx, idAtencion, solicitud, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.guardar(ctx, cambio)
+}
+
+// Actualizar corrige los signos vitales de un triaje ya registrado y recalcula su prioridad.
+// Cada corrección se conserva como una nueva revisión con su autor y motivo, y requiere
+// que el financiamiento de la atención siga permitiendo el triaje.
+func (s *TriajeServicio) Actualizar(ctx context.Context, idAtencion int, solicitud SolicitudTriaje) (*Triaje, error) {
+	solicitud.IdUsuario = autor(ctx, solicitud.IdUsuario)
+
+	motivo := strings.TrimSpace(solicitud.MotivoCorreccion)
+	if motivo == "" {
+		return nil, ErrMotivoRequerido
+	}
+
+	cambio, err := s.preparar(ctx, idAtencion, solicitud, &motivo)
+	if err != nil {
+		return nil, err
+	}
+	return s.guardar(ctx, cambio)
+}
+
+// preparar valida y clasifica la solicitud sin escribir en la base de datos. Tanto el
+// registro como cada corrección deben cumplir la política de financiamiento vigente.
+func (s *TriajeServicio) preparar(ctx context.Context, idAtencion int, solicitud SolicitudTriaje, motivo *string) (*cambioTriaje, error) {
+	if err := s.elegibilidad.Verificar(ctx, idAtencion, elegibilidad.AccionTriaje); err != nil {
+		return nil, err
+	}
+
+	paciente, err := s.atenciones.ObtenerDatosPaciente(ctx, idAtencion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validador.Validar(solicitud.SignosVitales, paciente.EdadPaciente, solicitud.ConfirmarAdvertencias); err != nil {
+		return nil, err
+	}
+
+	clasificacion, err := s.prioridad.Clasificar(solicitud.SignosVitales, paciente.EdadPaciente, solicitud.Discriminadores)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cambioTriaje{
+		idAtencion:    idAtencion,
+		solicitud:     solicitud,
+		paciente:      paciente,
+		clasificacion: clasificacion,
+		motivo:        motivo,
+	}, nil
+}
+
+// guardar escribe el cambio en una transacción y lo audita una vez confirmado
+func (s *TriajeServicio) guardar(ctx context.Context, cambio *cambioTriaje) (*Triaje, error) {
+	var guardado *triajeGuardado
+	err := s.db.EjecutarTransaccion(ctx, false, func(tx *sql.Tx) error {
+		var err error
+		guardado, err = s.escribir(ctx, tx, cambio)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditar(ctx, cambio.idAtencion, guardado)
+	return s.obtenerYEncolar(ctx, cambio.idAtencion)
+}
+
+// escribir guarda los signos vitales, la clasificación y la revisión dentro de tx.
+// Una corrección bloquea el triaje vigente para conservar sus valores como revisión previa.
+func (s *TriajeServicio) escribir(ctx context.Context, tx *sql.Tx, cambio *cambioTriaje) (*triajeGuardado, error) {
+	idAtencion, solicitud := cambio.idAtencion, cambio.solicitud
+	nuevo := contenidoSolicitud(solicitud, cambio.clasificacion)
+
+	if cambio.motivo == nil {
+		args := append(argumentosSignos(solicitud.SignosVitales),
+			sql.Named("idAtencion", idAtencion),
+			sql.Named("nroHistoriaClinica", cambio.paciente.NroHistoriaClinica),
+			sql.Named("edad", cambio.paciente.EdadPaciente),
+			sql.Named("idUsuario", solicitud.IdUsuario),
+		)
+		resultado, err := tx.ExecContext(ctx, QueryRegistrarTriaje, args...)
+		if err != nil {
+			return nil, fmt.Errorf("error al registrar triaje: %w", err)
+		}
+		if filas, err := resultado.RowsAffected(); err == nil && filas == 0 {
+			return nil, ErrTriajeYaRegistrado
+		}
+
+		if err := guardarClasificacion(ctx, tx, idAtencion, solicitud, cambio.clasificacion); err != nil {
+			return nil, err
+		}
+
+		nroRevision, err := guardarRevision(ctx, tx, idAtencion, solicitud.IdUsuario, nil, nil, nuevo)
+		if err != nil {
+			return nil, err
+		}
+		return &triajeGuardado{nroRevision: nroRevision, accion: auditoria.AccionAgregar, observaciones: "Registro de triaje"}, nil
+	}
+
+	actual, err := leerSignos(tx.QueryRowContext(ctx, QueryBloquearTriaje, sql.Named("idAtencion", idAtencion)), idAtencion)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.leerClasificacion(tx.QueryRowContext(ctx, QueryObtenerClasificacion, sql.Named("idAtencion", idAtencion)), actual); err != nil {
+		return nil, err
+	}
+	if err := asegurarRevisionInicial(ctx, tx, actual); err != nil {
+		return nil, err
+	}
+
+	args := append(argumentosSignos(solicitud.SignosVitales), sql.Named("idAtencion", idAtencion))
+	if _, err := tx.ExecContext(ctx, QueryActualizarTriaje, args...); err != nil {
+		return nil, fmt.Errorf("error al actualizar triaje: %w", err)
+	}
+
+	if err := guardarClasificacion(ctx, tx, idAtencion, solicitud, cambio.clasificacion); err != nil {
+		return nil, err
+	}
+
+	nroRevision, err := guardarRevision(ctx, tx, idAtencion, solicitud.IdUsuario, cambio.motivo, nil, nuevo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &triajeGuardado{
+		nroRevision:   nroRevision,
+		accion:        auditoria.AccionModificar,
+		observaciones: observacionesCorreccion(nroRevision, *cambio.motivo, CompararContenido(contenidoTriaje(actual), nuevo)),
+	}, nil
+}
+
+// auditar registra un triaje ya confirmado. Los datos quedaron guardados, así que un
+// fallo de la auditoría no se informa al cliente: queda en el log y en las métricas.
+func (s *TriajeServicio) auditar(ctx context.Context, idAtencion int, guardado *triajeGuardado) {
+	if err := s.auditoria.RegistrarAuditoria(
+		ctx, guardado.accion, idAtencion, TablaTriaje, IdListItemTriaje, guardado.observaciones,
+	); err != nil {
+		log.Printf("[Triaje] No se auditó la revisión %d del triaje %d: %v", guardado.nroRevision, idAtencion, err)
+	}
+}
+
+// autor retorna el empleado autenticado de la solicitud, que prevalece sobre el idUsuario
+// enviado en el cuerpo; este solo se usa en contextos sin identidad
+func autor(ctx context.Context, idUsuario int) int {
+	if empleado, ok := identidad.EmpleadoDesde(ctx); ok {
+		return empleado.IdEmpleado
+	}
+	return idUsuario
+}
+
+// obtenerYEncolar retorna el triaje guardado y notifica su prioridad a la cola del servicio
+func (s *TriajeServicio) obtenerYEncolar(ctx context.Context, idAtencion int) (*Triaje, error) {
+	triaje, err := s.Obtener(ctx, idAtencion)
+	if err != nil {
+		return nil, err
+	}
+
+	if triaje.Paciente.IdServicio != nil && triaje.Clasificacion != nil {
+		s.cola.Encolar(*triaje.Paciente.IdServicio, EntradaCola{
+			IdAtencion:         idAtencion,
+			NroHistoriaClinica: triaje.Paciente.NroHistoriaClinica,
+			Nivel:              triaje.Clasificacion.Nivel,
+			NombreNivel:        triaje.Clasificacion.Nombre,
+			Color:              triaje.Clasificacion.Color,
+			FechaLlegada:       fechaRegistro(triaje),
+		})
+	}
+
+	return triaje, nil
+}
+
+func fechaRegistro(triaje *Triaje) time.Time {
+	if triaje.FechaRegistro != nil {
+		return *triaje.FechaRegistro
+	}
+	return time.Now()
+}
+
+func (s *TriajeServicio) obtenerSignos(ctx context.Context, idAtencion int) (*Triaje, error) {
+	row := s.db.EjecutarQueryRow(ctx, QueryObtenerTriaje, false, sql.Named("idAtencion", idAtencion))
+	if row == nil {
+		return nil, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+	return leerSignos(row, idAtencion)
+}
+
+// leerSignos lee la fila de QueryObtenerTriaje o QueryBloquearTriaje
+func leerSignos(row *sql.Row, idAtencion int) (*Triaje, error) {
+	var (
+		columnas  columnasSignos
+		fecha     sql.NullTime
+		idUsuario sql.NullInt64
+	)
+	err := row.Scan(append(columnas.destinos(), &fecha, &idUsuario)...)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrTriajeNoRegistrado
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	triaje := &Triaje{IdAtencion: idAtencion, SignosVitales: columnas.signos()}
+	triaje.IMC = triaje.SignosVitales.CalcularIMC()
+
+	if fecha.Valid {
+		triaje.FechaRegistro = &fecha.Time
+	}
+	if idUsuario.Valid {
+		id := int(idUsuario.Int64)
+		triaje.IdUsuario = &id
+	}
+
+	return triaje, nil
+}
+
+func guardarClasificacion(ctx context.Context, tx *sql.Tx, idAtencion int, solicitud SolicitudTriaje, clasificacion *Clasificacion) error {
+	var idRegla, descripcionRegla *string
+	if clasificacion.Regla != nil {
+		idRegla = &clasificacion.Regla.Id
+		descripcionRegla = &clasificacion.Regla.Descripcion
+	}
+
+	var motivoConsulta *string
+	if motivo := strings.TrimSpace(solicitud.MotivoConsulta); motivo != "" {
+		motivoConsulta = &motivo
+	}
+
+	_, err := tx.ExecContext(ctx, QueryGuardarClasificacion,
+		sql.Named("idAtencion", idAtencion),
+		sql.Named("nivel", clasificacion.Nivel),
+		sql.Named("idRegla", idRegla),
+		sql.Named("descripcionRegla", descripcionRegla),
+		sql.Named("versionReglas", clasificacion.VersionReglas),
+		sql.Named("motivoConsulta", motivoConsulta),
+		sql.Named("discriminadores", unirDiscriminadores(solicitud.Discriminadores)),
+	)
+	if err != nil {
+		return fmt.Errorf("error al guardar clasificación de prioridad: %w", err)
+	}
+
+	return nil
+}
+
+// cargarClasificacion completa el triaje con la prioridad almacenada, si existe
+func (s *TriajeServicio) cargarClasificacion(ctx context.Context, triaje *Triaje) error {
+	row := s.db.EjecutarQueryRow(ctx, QueryObtenerClasificacion, false, sql.Named("idAtencion", triaje.IdAtencion))
+	if row == nil {
+		return fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+	return s.leerClasificacion(row, triaje)
+}
+
+// leerClasificacion completa el triaje con la fila de QueryObtenerClasificacion, si existe
+func (s *TriajeServicio) leerClasificacion(row *sql.Row, triaje *Triaje) error {
+	var (
+		nivel                           int
+		idRegla, descripcionRegla       sql.NullString
+		versionReglas                   string
+		motivoConsulta, discriminadores sql.NullString
+	)
+	err := row.Scan(&nivel, &idRegla, &descripcionRegla, &versionReglas, &motivoConsulta, &discriminadores)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	clasificacion := &Clasificacion{
+		NivelPrioridad: NivelPrioridad{Nivel: nivel},
+		VersionReglas:  versionReglas,
+		Explicacion:    "Ninguna regla se cumplió; se asigna la prioridad por defecto.",
+	}
+	if reglas, err := s.prioridad.Reglas(); err == nil {
+		if definido := reglas.nivel(nivel); definido != nil {
+			clasificacion.NivelPrioridad = *definido
+		}
+	}
+	if idRegla.Valid {
+		clasificacion.Regla = &ReglaAplicada{Id: idRegla.String, Descripcion: descripcionRegla.String, Nivel: nivel}
+		clasificacion.Explicacion = fmt.Sprintf("%s: %s", idRegla.String, descripcionRegla.String)
+	}
+
+	triaje.Clasificacion = clasificacion
+	if motivoConsulta.Valid {
+		triaje.MotivoConsulta = &motivoConsulta.String
+	}
+	triaje.Discriminadores = separarDiscriminadores(discriminadores.String)
+	return nil
+}
+
+// evaluarCrecimiento calcula la edad exacta y, en menores de 5 años, los z-scores de la OMS
+func evaluarCrecimiento(triaje *Triaje) error {
+	referencia := fechaRegistro(triaje)
+	paciente := triaje.Paciente
+	triaje.Edad = paciente.EdadCalendario(referencia)
+
+	dias := paciente.EdadEnDias(referencia)
+	if dias == nil || paciente.IdTipoSexo == nil {
+		return nil
+	}
+
+	var sexo crecimiento.Sexo
+	switch *paciente.IdTipoSexo {
+	case atenciones.SexoMasculino:
+		sexo = crecimiento.Masculino
+	case atenciones.SexoFemenino:
+		sexo = crecimiento.Femenino
+	default:
+		return nil
+	}
+
+	evaluacion, err := crecimiento.Evaluar(crecimiento.Medidas{
+		EdadDias:          *dias,
+		Sexo:              sexo,
+		Peso:              triaje.SignosVitales.Peso,
+		Talla:             triaje.SignosVitales.Talla,
+		PerimetroCefalico: triaje.SignosVitales.PerimetroCefalico,
+	})
+	if err != nil {
+		return fmt.Errorf("error al evaluar crecimiento: %w", err)
+	}
+
+	triaje.Crecimiento = evaluacion
+	return nil
+}
+
+// columnasSignos recibe las columnas de triaje de AtencionesCE en el orden de los queries
+type columnasSignos struct {
+	presion                                           sql.NullString
+	pulso, frecRespiratoria, saturacion               sql.NullFloat64
+	temperatura, peso, talla, perimCefalico, perimAbd sql.NullFloat64
+}
+
+func (c *columnasSignos) destinos() []interface{} {
+	return []interface{}{
+		&c.presion,
+		&c.pulso,
+		&c.frecRespiratoria,
+		&c.temperatura,
+		&c.saturacion,
+		&c.peso,
+		&c.talla,
+		&c.perimCefalico,
+		&c.perimAbd,
+	}
+}
+
+func (c *columnasSignos) signos() SignosVitales {
+	var signos SignosVitales
+	signos.PresionSistolica, signos.PresionDiastolica = separarPresion(c.presion)
+	signos.FrecuenciaCardiaca = enteroNulo(c.pulso)
+	signos.FrecuenciaRespiratoria = enteroNulo(c.frecRespiratoria)
+	signos.Temperatura = decimalNulo(c.temperatura)
+	signos.SaturacionOxigeno = enteroNulo(c.saturacion)
+	signos.Peso = decimalNulo(c.peso)
+	signos.Talla = decimalNulo(c.talla)
+	signos.PerimetroCefalico = decimalNulo(c.perimCefalico)
+	signos.PerimetroAbdominal = decimalNulo(c.perimAbd)
+	return signos
+}
+
+// argumentosSignos construye los parámetros comunes de los queries de triaje
+func argumentosSignos(signos SignosVitales) []interface{} {
+	return []interface{}{
+		sql.Named("presion", unirPresion(signos.PresionSistolica, signos.PresionDiastolica)),
+		sql.Named("pulso", signos.FrecuenciaCardiaca),
+		sql.Named("frecRespiratoria", signos.FrecuenciaRespiratoria),
+		sql.Named("temperatura", signos.Temperatura),
+		sql.Named("saturacion", signos.SaturacionOxigeno),
+		sql.Named("peso", signos.Peso),
+		sql.Named("talla", signos.Talla),
+		sql.Named("perimCefalico", signos.PerimetroCefalico),
+		sql.Named("perimAbdominal", signos.PerimetroAbdominal),
+	}
+}
+
+// unirPresion guarda la presión arterial en el formato de SIGH (sistólica/diastólica)
+func unirPresion(sistolica, diastolica *int) *string {
+	if sistolica == nil || diastolica == nil {
+		return nil
+	}
+
+	presion := fmt.Sprintf("%d/%d", *sistolica, *diastolica)
+	return &presion
+}
+
+func separarPresion(presion sql.NullString) (*int, *int) {
+	if !presion.Valid {
+		return nil, nil
+	}
+
+	partes := strings.SplitN(strings.TrimSpace(presion.String), "/", 2)
+	if len(partes) != 2 {
+		return nil, nil
+	}
+
+	sistolica, errS := strconv.Atoi(strings.TrimSpace(partes[0]))
+	diastolica, errD := strconv.Atoi(strings.TrimSpace(partes[1]))
+	if errS != nil || errD != nil {
+		return nil, nil
+	}
+
+	return &sistolica, &diastolica
+}
+
+func enteroNulo(valor sql.NullFloat64) *int {
+	if !valor.Valid {
+		return nil
+	}
+
+	entero := int(math.Round(valor.Float64))
+	return &entero
+}
+
+func decimalNulo(valor sql.NullFloat64) *float64 {
+	if !valor.Valid {
+		return nil
+	}
+
+	return &valor.Float64
+}
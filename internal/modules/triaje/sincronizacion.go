@@ -0,0 +1,318 @@
+package triaje
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/shared/errores"
+
+	"github.com/google/uuid"
+)
+
+// Estados posibles de cada registro de un lote sincronizado
+const (
+	EstadoAplicado     = "aplicado"
+	EstadoDuplicado    = "duplicado"
+	EstadoConflicto    = "conflicto"
+	EstadoRechazado    = "rechazado"
+	EstadoErrorInterno = "error"
+)
+
+// registrosPorLoteMaximo limita el tiempo que una sola solicitud ocupa la base de datos
+const registrosPorLoteMaximo = 500
+
+var ErrLoteInvalido = fmt.Errorf("el lote debe contener entre 1 y %d registros", registrosPorLoteMaximo)
+
+// RegistroSincronizacion es un triaje capturado sin conexión en una tablet.
+// RevisionBase es la revisión del servidor sobre la que se capturó: 0 para un triaje
+// nuevo, o el nroRevision descargado antes de salir a campaña para una corrección.
+type RegistroSincronizacion struct {
+	Uuid         string     `json:"uuid"`
+	IdAtencion   int        `json:"idAtencion"`
+	RevisionBase int        `json:"revisionBase"`
+	FechaCaptura *time.Time `json:"fechaCaptura"`
+	SolicitudTriaje
+}
+
+// SolicitudSincronizacion es el lote enviado por la tablet al recuperar conexión
+type SolicitudSincronizacion struct {
+	Registros []RegistroSincronizacion `json:"registros"`
+}
+
+// ResultadoSincronizacion informa qué ocurrió con un registro del lote
+type ResultadoSincronizacion struct {
+	Uuid             string      `json:"uuid"`
+	IdAtencion       int         `json:"idAtencion"`
+	Estado           string      `json:"estado"`
+	NroRevision      *int        `json:"nroRevision,omitempty"`
+	RevisionServidor *int        `json:"revisionServidor,omitempty"`
+	Tipo             string      `json:"tipo,omitempty"`
+	Mensaje          string      `json:"mensaje,omitempty"`
+	Detalles         interface{} `json:"detalles,omitempty"`
+}
+
+// ReporteSincronizacion resume el procesamiento del lote en el mismo orden recibido
+type ReporteSincronizacion struct {
+	Total      int                       `json:"total"`
+	Aplicados  int                       `json:"aplicados"`
+	Duplicados int                       `json:"duplicados"`
+	Conflictos int                       `json:"conflictos"`
+	Rechazados int                       `json:"rechazados"`
+	Errores    int                       `json:"errores"`
+	Resultados []ResultadoSincronizacion `json:"resultados"`
+}
+
+// Sincronizar aplica en orden los triajes capturados sin conexión. Cada registro pasa por
+// la misma validación, elegibilidad y auditoría que el registro individual; un registro
+// fallido no detiene el resto del lote. Reenviar un UUID ya aplicado no lo vuelve a aplicar.
+func (s *TriajeServicio) Sincronizar(ctx context.Context, solicitud SolicitudSincronizacion) (*ReporteSincronizacion, error) {
+	if len(solicitud.Registros) == 0 || len(solicitud.Registros) > registrosPorLoteMaximo {
+		return nil, ErrLoteInvalido
+	}
+
+	reporte := &ReporteSincronizacion{
+		Total:      len(solicitud.Registros),
+		Resultados: make([]ResultadoSincronizacion, 0, len(solicitud.Registros)),
+	}
+
+	for _, registro := range solicitud.Registros {
+		resultado := s.sincronizarRegistro(ctx, registro)
+
+		switch resultado.Estado {
+		case EstadoAplicado:
+			reporte.Aplicados++
+		case EstadoDuplicado:
+			reporte.Duplicados++
+		case EstadoConflicto:
+			reporte.Conflictos++
+		case EstadoRechazado:
+			reporte.Rechazados++
+		default:
+			reporte.Errores++
+		}
+		reporte.Resultados = append(reporte.Resultados, resultado)
+	}
+
+	return reporte, nil
+}
+
+func (s *TriajeServicio) sincronizarRegistro(ctx context.Context, registro RegistroSincronizacion) ResultadoSincronizacion {
+	resultado := ResultadoSincronizacion{Uuid: registro.Uuid, IdAtencion: registro.IdAtencion}
+	registro.IdUsuario = autor(ctx, registro.IdUsuario)
+
+	id, err := uuid.Parse(registro.Uuid)
+	if err != nil {
+		return rechazar(resultado, "El UUID del registro no es válido.")
+	}
+	resultado.Uuid = id.String()
+
+	if registro.IdAtencion <= 0 {
+		return rechazar(resultado, "El N° de cuenta no es válido.")
+	}
+	if registro.RevisionBase < 0 {
+		return rechazar(resultado, "La revisión base no es válida.")
+	}
+
+	// Un reenvío de un registro ya aplicado responde duplicado sin volver a validarlo
+	if idAtencion, nroRevision, err := s.obtenerSincronizacion(ctx, resultado.Uuid); err == nil {
+		return duplicado(resultado, idAtencion, nroRevision)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fallar(resultado, err)
+	}
+
+	var motivo *string
+	if registro.RevisionBase > 0 {
+		texto := strings.TrimSpace(registro.MotivoCorreccion)
+		if texto == "" {
+			return rechazar(resultado, ErrMotivoRequerido.Error())
+		}
+		motivo = &texto
+	}
+
+	cambio, err := s.preparar(ctx, registro.IdAtencion, registro.SolicitudTriaje, motivo)
+	if err != nil {
+		return s.resultadoError(ctx, resultado, registro.IdAtencion, err)
+	}
+
+	// El UUID se reserva, la revisión base se compara y el triaje se escribe en una sola
+	// transacción: si algo falla no queda nada escrito y el registro puede reenviarse
+	var guardado *triajeGuardado
+	err = s.db.EjecutarTransaccion(ctx, false, func(tx *sql.Tx) error {
+		if err := reclamarSincronizacion(ctx, tx, resultado.Uuid, registro); err != nil {
+			return err
+		}
+
+		revisionServidor, err := revisionServidorTx(ctx, tx, registro.IdAtencion)
+		if err != nil {
+			return err
+		}
+		if revisionServidor != registro.RevisionBase {
+			return &conflictoRevision{servidor: revisionServidor, base: registro.RevisionBase}
+		}
+
+		if guardado, err = s.escribir(ctx, tx, cambio); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, QueryCompletarSincronizacion,
+			sql.Named("uuid", resultado.Uuid),
+			sql.Named("nroRevision", guardado.nroRevision),
+		)
+		return err
+	})
+
+	var (
+		previo    *sincronizacionPrevia
+		conflicto *conflictoRevision
+	)
+	switch {
+	case errors.As(err, &previo):
+		return duplicado(resultado, previo.idAtencion, previo.nroRevision)
+	case errors.As(err, &conflicto):
+		resultado.Estado = EstadoConflicto
+		resultado.RevisionServidor = &conflicto.servidor
+		resultado.Mensaje = conflicto.Error()
+		return resultado
+	case err != nil:
+		return s.resultadoError(ctx, resultado, registro.IdAtencion, err)
+	}
+
+	// El registro ya quedó confirmado: los pasos siguientes no cambian el resultado
+	s.auditar(ctx, registro.IdAtencion, guardado)
+	if _, err := s.obtenerYEncolar(ctx, registro.IdAtencion); err != nil {
+		log.Printf("[Triaje] No se notificó a la cola el registro sincronizado %s: %v", resultado.Uuid, err)
+	}
+
+	resultado.Estado = EstadoAplicado
+	resultado.NroRevision = &guardado.nroRevision
+	return resultado
+}
+
+// sincronizacionPrevia aborta la transacción cuando el UUID ya fue aplicado
+type sincronizacionPrevia struct {
+	idAtencion  int
+	nroRevision int
+}
+
+func (p *sincronizacionPrevia) Error() string {
+	return "el registro ya fue sincronizado"
+}
+
+// conflictoRevision aborta la transacción cuando el triaje del servidor cambió desde
+// que la tablet descargó la revisión base
+type conflictoRevision struct {
+	servidor int
+	base     int
+}
+
+func (c *conflictoRevision) Error() string {
+	return fmt.Sprintf("El triaje fue modificado en el servidor (revisión %d); el registro se capturó sobre la revisión %d.", c.servidor, c.base)
+}
+
+// reclamarSincronizacion reserva el UUID dentro de tx. Si otro envío ya lo aplicó
+// retorna *sincronizacionPrevia con lo que se guardó entonces.
+func reclamarSincronizacion(ctx context.Context, tx *sql.Tx, id string, registro RegistroSincronizacion) error {
+	reclamo, err := tx.ExecContext(ctx, QueryReclamarSincronizacion,
+		sql.Named("uuid", id),
+		sql.Named("idAtencion", registro.IdAtencion),
+		sql.Named("idEmpleado", registro.IdUsuario),
+		sql.Named("fechaCaptura", registro.FechaCaptura),
+	)
+	if err != nil {
+		return fmt.Errorf("error al reservar la sincronización: %w", err)
+	}
+	if filas, err := reclamo.RowsAffected(); err != nil || filas > 0 {
+		return err
+	}
+
+	previo := &sincronizacionPrevia{}
+	if err := tx.QueryRowContext(ctx, QueryObtenerSincronizacion, sql.Named("uuid", id)).Scan(&previo.idAtencion, &previo.nroRevision); err != nil {
+		return fmt.Errorf("error al obtener la sincronización previa: %w", err)
+	}
+	return previo
+}
+
+// revisionServidorTx retorna, bloqueando el triaje dentro de tx, la revisión que ven los
+// clientes o 0 si la atención aún no tiene triaje
+func revisionServidorTx(ctx context.Context, tx *sql.Tx, idAtencion int) (int, error) {
+	if _, err := leerSignos(tx.QueryRowContext(ctx, QueryBloquearTriaje, sql.Named("idAtencion", idAtencion)), idAtencion); err != nil {
+		if errors.Is(err, ErrTriajeNoRegistrado) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var nroRevision int
+	if err := tx.QueryRowContext(ctx, QueryUltimaRevision, sql.Named("idAtencion", idAtencion)).Scan(&nroRevision); err != nil {
+		return 0, err
+	}
+	if nroRevision == 0 {
+		return 1, nil
+	}
+	return nroRevision, nil
+}
+
+func (s *TriajeServicio) obtenerSincronizacion(ctx context.Context, id string) (int, int, error) {
+	row := s.db.EjecutarQueryRow(ctx, QueryObtenerSincronizacion, false, sql.Named("uuid", id))
+	if row == nil {
+		return 0, 0, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	var idAtencion, nroRevision int
+	if err := row.Scan(&idAtencion, &nroRevision); err != nil {
+		return 0, 0, err
+	}
+	return idAtencion, nroRevision, nil
+}
+
+func duplicado(resultado ResultadoSincronizacion, idAtencion, nroRevision int) ResultadoSincronizacion {
+	resultado.Estado = EstadoDuplicado
+	resultado.IdAtencion = idAtencion
+	resultado.NroRevision = &nroRevision
+	return resultado
+}
+
+// resultadoError clasifica el error devuelto por Registrar o Actualizar
+func (s *TriajeServicio) resultadoError(ctx context.Context, resultado ResultadoSincronizacion, idAtencion int, err error) ResultadoSincronizacion {
+	var apiErr *errores.ErrorApi
+	switch {
+	case errors.As(err, &apiErr):
+		resultado.Estado = EstadoRechazado
+		resultado.Tipo = apiErr.Tipo
+		resultado.Mensaje = apiErr.Mensaje
+		resultado.Detalles = apiErr.Detalles
+		return resultado
+	case errors.Is(err, ErrDiscriminadorDesconocido), errors.Is(err, ErrMotivoRequerido):
+		return rechazar(resultado, err.Error())
+	case errors.Is(err, ErrTriajeYaRegistrado), errors.Is(err, ErrTriajeNoRegistrado):
+		// El triaje cambió de estado mientras se validaba el registro
+		resultado.Estado = EstadoConflicto
+		resultado.Mensaje = err.Error()
+		if revision, errRevision := s.revisionVigente(ctx, idAtencion); errRevision == nil {
+			resultado.RevisionServidor = &revision
+		}
+		return resultado
+	}
+	return fallar(resultado, err)
+}
+
+func rechazar(resultado ResultadoSincronizacion, mensaje string) ResultadoSincronizacion {
+	resultado.Estado = EstadoRechazado
+	resultado.Tipo = errores.TipoValidacion
+	resultado.Mensaje = mensaje
+	return resultado
+}
+
+// fallar reporta un error interno. Solo se usa antes de confirmar la transacción, cuando
+// nada quedó escrito, por lo que el registro puede reenviarse sin riesgo.
+func fallar(resultado ResultadoSincronizacion, err error) ResultadoSincronizacion {
+	log.Printf("[Triaje] Error al sincronizar el registro %s: %v", resultado.Uuid, err)
+	resultado.Estado = EstadoErrorInterno
+	resultado.Mensaje = "Error interno al procesar el registro; puede reenviarse."
+	return resultado
+}
@@ -0,0 +1,255 @@
+package triaje
+
+import (
+	"fmt"
+	"os"
+
+	"backend/internal/shared/errores"
+	"backend/internal/shared/recarga"
+
+	"github.com/gofiber/fiber/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Niveles de severidad de un hallazgo de validación
+const (
+	NivelLimite      = "limite"
+	NivelAdvertencia = "advertencia"
+)
+
+// ordenCampos fija el orden en que se reportan los hallazgos (el mismo del formulario)
+var ordenCampos = []string{
+	"presionSistolica",
+	"presionDiastolica",
+	"frecuenciaCardiaca",
+	"frecuenciaRespiratoria",
+	"temperatura",
+	"saturacionOxigeno",
+	"peso",
+	"talla",
+	"perimetroCefalico",
+	"perimetroAbdominal",
+}
+
+// RangoSigno define el rango imposible (limite) y el improbable (advertencia) de un campo
+type RangoSigno struct {
+	Limite      []float64 `yaml:"limite"`
+	Advertencia []float64 `yaml:"advertencia"`
+}
+
+// BandaEdad agrupa los rangos aplicables a un grupo de edad (en años)
+type BandaEdad struct {
+	Nombre  string                `yaml:"nombre"`
+	EdadMin *int                  `yaml:"edad_min"`
+	EdadMax *int                  `yaml:"edad_max"`
+	Rangos  map[string]RangoSigno `yaml:"rangos"`
+}
+
+// RangosSignos representa el archivo versionado de rangos de plausibilidad
+type RangosSignos struct {
+	Version string      `yaml:"version"`
+	Bandas  []BandaEdad `yaml:"bandas"`
+}
+
+// DetalleValidacion describe un campo fuera de rango para que el cliente lo asocie a su input
+type DetalleValidacion struct {
+	Campo   string   `json:"campo"`
+	Valor   *float64 `json:"valor"`
+	Minimo  *float64 `json:"minimo,omitempty"`
+	Maximo  *float64 `json:"maximo,omitempty"`
+	Nivel   string   `json:"nivel"`
+	Mensaje string   `json:"mensaje"`
+}
+
+// CargarRangosSignos lee y valida el archivo de rangos
+func CargarRangosSignos(ruta string) (*RangosSignos, error) {
+	contenido, err := os.ReadFile(ruta)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer rangos de signos vitales: %w", err)
+	}
+
+	var rangos RangosSignos
+	if err := yaml.Unmarshal(contenido, &rangos); err != nil {
+		return nil, fmt.Errorf("error al parsear rangos de signos vitales: %w", err)
+	}
+
+	if err := rangos.validar(); err != nil {
+		return nil, fmt.Errorf("rangos de signos vitales inválidos: %w", err)
+	}
+
+	return &rangos, nil
+}
+
+func (r *RangosSignos) validar() error {
+	if r.Version == "" {
+		return fmt.Errorf("falta la versión")
+	}
+
+	for _, banda := range r.Bandas {
+		for campo, rango := range banda.Rangos {
+			if _, ok := camposSignos[campo]; !ok {
+				return fmt.Errorf("banda %s: campo %q desconocido", banda.Nombre, campo)
+			}
+			if len(rango.Limite) != 2 || rango.Limite[0] > rango.Limite[1] {
+				return fmt.Errorf("banda %s: el límite de %s debe ser [mínimo, máximo]", banda.Nombre, campo)
+			}
+			if rango.Advertencia != nil {
+				if len(rango.Advertencia) != 2 || rango.Advertencia[0] > rango.Advertencia[1] {
+					return fmt.Errorf("banda %s: la advertencia de %s debe ser [mínimo, máximo]", banda.Nombre, campo)
+				}
+				if rango.Advertencia[0] < rango.Limite[0] || rango.Advertencia[1] > rango.Limite[1] {
+					return fmt.Errorf("banda %s: la advertencia de %s debe estar dentro del límite", banda.Nombre, campo)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// banda retorna la primera banda que corresponde a la edad, o la banda general si la edad es desconocida
+func (r *RangosSignos) banda(edad *int) *BandaEdad {
+	var general *BandaEdad
+	for i := range r.Bandas {
+		banda := &r.Bandas[i]
+		if banda.EdadMin == nil && banda.EdadMax == nil {
+			if general == nil {
+				general = banda
+			}
+			continue
+		}
+		if edad == nil {
+			continue
+		}
+		if banda.EdadMin != nil && *edad < *banda.EdadMin {
+			continue
+		}
+		if banda.EdadMax != nil && *edad > *banda.EdadMax {
+			continue
+		}
+		return banda
+	}
+	return general
+}
+
+// Validar separa los hallazgos en errores (se rechazan) y advertencias (requieren confirmación)
+func (r *RangosSignos) Validar(signos SignosVitales, edad *int) ([]DetalleValidacion, []DetalleValidacion) {
+	var errs, advertencias []DetalleValidacion
+
+	errs = append(errs, validarPresion(signos)...)
+
+	banda := r.banda(edad)
+	if banda == nil {
+		return errs, nil
+	}
+
+	for _, campo := range ordenCampos {
+		rango, ok := banda.Rangos[campo]
+		if !ok {
+			continue
+		}
+
+		valor := camposSignos[campo](signos)
+		if valor == nil {
+			continue
+		}
+
+		if *valor < rango.Limite[0] || *valor > rango.Limite[1] {
+			errs = append(errs, DetalleValidacion{
+				Campo:   campo,
+				Valor:   valor,
+				Minimo:  &rango.Limite[0],
+				Maximo:  &rango.Limite[1],
+				Nivel:   NivelLimite,
+				Mensaje: fmt.Sprintf("El valor %g no es fisiológicamente posible (%g a %g).", *valor, rango.Limite[0], rango.Limite[1]),
+			})
+			continue
+		}
+
+		if rango.Advertencia != nil && (*valor < rango.Advertencia[0] || *valor > rango.Advertencia[1]) {
+			advertencias = append(advertencias, DetalleValidacion{
+				Campo:   campo,
+				Valor:   valor,
+				Minimo:  &rango.Advertencia[0],
+				Maximo:  &rango.Advertencia[1],
+				Nivel:   NivelAdvertencia,
+				Mensaje: fmt.Sprintf("El valor %g es improbable (esperado %g a %g); confirme que es correcto.", *valor, rango.Advertencia[0], rango.Advertencia[1]),
+			})
+		}
+	}
+
+	return errs, advertencias
+}
+
+// validarPresion verifica que la presión arterial se registre completa y sea coherente
+func validarPresion(signos SignosVitales) []DetalleValidacion {
+	sistolica, diastolica := signos.PresionSistolica, signos.PresionDiastolica
+
+	switch {
+	case sistolica == nil && diastolica != nil:
+		return []DetalleValidacion{{
+			Campo:   "presionSistolica",
+			Nivel:   NivelLimite,
+			Mensaje: "Debe registrar la presión sistólica junto con la diastólica.",
+		}}
+	case sistolica != nil && diastolica == nil:
+		return []DetalleValidacion{{
+			Campo:   "presionDiastolica",
+			Nivel:   NivelLimite,
+			Mensaje: "Debe registrar la presión diastólica junto con la sistólica.",
+		}}
+	case sistolica != nil && *diastolica >= *sistolica:
+		return []DetalleValidacion{{
+			Campo:   "presionDiastolica",
+			Valor:   aDecimal(diastolica),
+			Nivel:   NivelLimite,
+			Mensaje: "La presión diastólica debe ser menor que la sistólica.",
+		}}
+	}
+	return nil
+}
+
+// ValidadorSignos aplica los rangos vigentes del archivo configurado
+type ValidadorSignos struct {
+	archivo *recarga.Archivo[RangosSignos]
+}
+
+func NuevoValidadorSignos(ruta string) *ValidadorSignos {
+	return &ValidadorSignos{
+		archivo: recarga.Nuevo("rangos de signos vitales", ruta, CargarRangosSignos),
+	}
+}
+
+// Validar retorna un error 422 si hay valores imposibles, o si hay valores improbables
+// y el cliente no confirmó explícitamente que son correctos
+func (v *ValidadorSignos) Validar(signos SignosVitales, edad *int, confirmado bool) error {
+	rangos, err := v.archivo.Obtener()
+	if err != nil {
+		return err
+	}
+
+	errs, advertencias := rangos.Validar(signos, edad)
+	if len(errs) > 0 {
+		return errores.Nuevo(
+			fiber.StatusUnprocessableEntity,
+			errores.TipoValidacion,
+			"Uno o más signos vitales están fuera de los límites fisiológicos.",
+		).ConDetalles(fiber.Map{
+			"versionRangos": rangos.Version,
+			"campos":        errs,
+		})
+	}
+
+	if len(advertencias) > 0 && !confirmado {
+		return errores.Nuevo(
+			fiber.StatusUnprocessableEntity,
+			errores.TipoConfirmacionRequerida,
+			"Uno o más signos vitales son improbables. Reenvíe con confirmarAdvertencias para guardarlos.",
+		).ConDetalles(fiber.Map{
+			"versionRangos": rangos.Version,
+			"campos":        advertencias,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,458 @@
+package crecimiento
+
+import (
+	"embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Tablas LMS de los Patrones de Crecimiento Infantil de la OMS (2006) para
+// menores de 5 años, exportadas a CSV desde las tablas ampliadas de la OMS
+// (ver tablas/LEEME.md). Las tablas por edad deben traer todos los días (0 a
+// 1856) o todos los meses (0 a 60) y las de peso para la longitud/talla pasos
+// de 0.1 o 0.5 cm; solo se interpola entre filas contiguas de esa grilla.
+//
+//go:embed tablas
+var archivosTablas embed.FS
+
+// ErrTablasNoDisponibles indica que faltan las tablas de la OMS o no están completas;
+// VerificarTablas lo retorna al arrancar para que la API no funcione sin ellas
+var ErrTablasNoDisponibles = errors.New("las tablas de crecimiento de la OMS no están disponibles")
+
+// Sexo del paciente según las tablas de la OMS
+type Sexo int
+
+const (
+	Masculino Sexo = iota + 1
+	Femenino
+)
+
+const (
+	// diasPorMes es la duración promedio del mes usada por la OMS
+	diasPorMes = 30.4375
+	// EdadMaximaMeses es la edad máxima cubierta por las tablas embebidas
+	EdadMaximaMeses = 60
+	// edadMaximaDias es el último día de las tablas ampliadas de la OMS
+	edadMaximaDias = 1856
+	// edadTallaMeses es la edad desde la que se mide de pie y se usa peso para la talla
+	edadTallaMeses = 24
+	// pasoMaximoCm es la mayor separación admitida entre filas de peso para la longitud/talla
+	pasoMaximoCm = 0.5
+)
+
+// Medidas representa los datos antropométricos capturados en triaje
+type Medidas struct {
+	EdadDias          int
+	Sexo              Sexo
+	Peso              *float64 // kg
+	Talla             *float64 // cm; longitud en menores de 2 años
+	PerimetroCefalico *float64 // cm
+}
+
+// Indicador representa el z-score de un indicador y su clasificación
+type Indicador struct {
+	ZScore        float64 `json:"zScore"`
+	Clasificacion string  `json:"clasificacion"`
+}
+
+// Evaluacion agrupa los indicadores calculados y el estado nutricional resultante
+type Evaluacion struct {
+	EdadMeses             int        `json:"edadMeses"`
+	EdadDias              int        `json:"edadDias"`
+	PesoEdad              *Indicador `json:"pesoEdad"`
+	TallaEdad             *Indicador `json:"tallaEdad"`
+	PesoTalla             *Indicador `json:"pesoTalla"`
+	IMCEdad               *Indicador `json:"imcEdad"`
+	PerimetroCefalicoEdad *Indicador `json:"perimetroCefalicoEdad"`
+	EstadoNutricional     []string   `json:"estadoNutricional"`
+}
+
+type filaLMS struct {
+	clave float64
+	l     float64
+	m     float64
+	s     float64
+}
+
+type tablaLMS []filaLMS
+
+type tablasSexo struct {
+	pesoEdad              tablaLMS
+	tallaEdad             tablaLMS
+	imcEdad               tablaLMS
+	perimetroCefalicoEdad tablaLMS
+	pesoLongitud          tablaLMS
+	pesoTalla             tablaLMS
+}
+
+// rangoTabla describe la cobertura que la OMS publica para cada indicador
+type rangoTabla struct {
+	nombre string
+	edad   bool    // indexada por edad; si no, por longitud/talla en cm
+	desde  float64 // cm, solo para las tablas por longitud/talla
+	hasta  float64
+}
+
+var rangosTablas = []rangoTabla{
+	{nombre: "peso_edad", edad: true},
+	{nombre: "talla_edad", edad: true},
+	{nombre: "imc_edad", edad: true},
+	{nombre: "perimetro_cefalico_edad", edad: true},
+	{nombre: "peso_longitud", desde: 45, hasta: 110},
+	{nombre: "peso_talla", desde: 65, hasta: 120},
+}
+
+var (
+	tablas      map[Sexo]*tablasSexo
+	tablasOnce  sync.Once
+	tablasError error
+)
+
+func cargarTablas() (map[Sexo]*tablasSexo, error) {
+	tablasOnce.Do(func() {
+		tablas, tablasError = leerTablas(archivosTablas)
+	})
+
+	return tablas, tablasError
+}
+
+// VerificarTablas carga las tablas embebidas y retorna ErrTablasNoDisponibles si falta
+// alguna o no cubre la grilla de la OMS; se llama al arrancar la API
+func VerificarTablas() error {
+	_, err := cargarTablas()
+	return err
+}
+
+// leerTablas carga las tablas de ambos sexos desde el directorio tablas de fuente
+func leerTablas(fuente fs.FS) (map[Sexo]*tablasSexo, error) {
+	resultado := make(map[Sexo]*tablasSexo)
+	sufijos := map[Sexo]string{Masculino: "masculino", Femenino: "femenino"}
+
+	for sexo, sufijo := range sufijos {
+		t := &tablasSexo{}
+		destinos := map[string]*tablaLMS{
+			"peso_edad":               &t.pesoEdad,
+			"talla_edad":              &t.tallaEdad,
+			"imc_edad":                &t.imcEdad,
+			"perimetro_cefalico_edad": &t.perimetroCefalicoEdad,
+			"peso_longitud":           &t.pesoLongitud,
+			"peso_talla":              &t.pesoTalla,
+		}
+		for _, rango := range rangosTablas {
+			tabla, err := leerTabla(fuente, fmt.Sprintf("tablas/%s_%s.csv", rango.nombre, sufijo), rango)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrTablasNoDisponibles, err)
+			}
+			*destinos[rango.nombre] = tabla
+		}
+		resultado[sexo] = t
+	}
+	return resultado, nil
+}
+
+// leerTabla carga una tabla LMS y verifica que cubra la grilla completa de la OMS.
+// Las tablas por edad se indexan en días aunque el archivo venga por meses.
+func leerTabla(fuente fs.FS, ruta string, rango rangoTabla) (tablaLMS, error) {
+	archivo, err := fuente.Open(ruta)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir tabla %s: %w", ruta, err)
+	}
+	defer archivo.Close()
+
+	registros, err := csv.NewReader(archivo).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error al leer tabla %s: %w", ruta, err)
+	}
+	if len(registros) < 2 {
+		return nil, fmt.Errorf("tabla %s vacía", ruta)
+	}
+
+	var tabla tablaLMS
+	for i, registro := range registros[1:] {
+		if len(registro) != 4 {
+			return nil, fmt.Errorf("tabla %s: fila %d con %d columnas", ruta, i+2, len(registro))
+		}
+
+		var valores [4]float64
+		for j, campo := range registro {
+			valores[j], err = strconv.ParseFloat(campo, 64)
+			if err != nil {
+				return nil, fmt.Errorf("tabla %s: fila %d: %w", ruta, i+2, err)
+			}
+		}
+		tabla = append(tabla, filaLMS{clave: valores[0], l: valores[1], m: valores[2], s: valores[3]})
+	}
+	sort.Slice(tabla, func(i, j int) bool { return tabla[i].clave < tabla[j].clave })
+
+	if !rango.edad {
+		return tabla, validarLongitud(ruta, tabla, rango)
+	}
+
+	switch registros[0][0] {
+	case "dia":
+		err = validarEdad(ruta, tabla, edadMaximaDias)
+	case "mes":
+		if err = validarEdad(ruta, tabla, EdadMaximaMeses); err == nil {
+			for i := range tabla {
+				tabla[i].clave *= diasPorMes
+			}
+		}
+	default:
+		err = fmt.Errorf("tabla %s: la primera columna debe ser dia o mes", ruta)
+	}
+	return tabla, err
+}
+
+// validarEdad exige una fila por cada día o mes, de 0 hasta el máximo
+func validarEdad(ruta string, tabla tablaLMS, maximo int) error {
+	if len(tabla) != maximo+1 {
+		return fmt.Errorf("tabla %s: se esperaban %d filas y tiene %d", ruta, maximo+1, len(tabla))
+	}
+	for i, fila := range tabla {
+		if fila.clave != float64(i) {
+			return fmt.Errorf("tabla %s: falta la edad %d", ruta, i)
+		}
+	}
+	return nil
+}
+
+// validarLongitud exige cubrir el rango de la OMS con un paso uniforme de hasta 0.5 cm
+func validarLongitud(ruta string, tabla tablaLMS, rango rangoTabla) error {
+	if len(tabla) < 2 || tabla[0].clave > rango.desde || tabla[len(tabla)-1].clave < rango.hasta {
+		return fmt.Errorf("tabla %s: debe cubrir de %.1f a %.1f cm", ruta, rango.desde, rango.hasta)
+	}
+
+	paso := tabla[1].clave - tabla[0].clave
+	if paso <= 0 || paso > pasoMaximoCm+1e-9 {
+		return fmt.Errorf("tabla %s: paso de %.2f cm, se requiere hasta %.1f cm", ruta, paso, pasoMaximoCm)
+	}
+	for i := 1; i < len(tabla); i++ {
+		if math.Abs(tabla[i].clave-tabla[i-1].clave-paso) > 1e-6 {
+			return fmt.Errorf("tabla %s: salto irregular después de %.1f cm", ruta, tabla[i-1].clave)
+		}
+	}
+	return nil
+}
+
+// interpolar retorna los parámetros LMS para la clave indicada o false si está fuera de rango
+func (t tablaLMS) interpolar(clave float64) (filaLMS, bool) {
+	if len(t) == 0 || clave < t[0].clave || clave > t[len(t)-1].clave {
+		return filaLMS{}, false
+	}
+
+	i := sort.Search(len(t), func(i int) bool { return t[i].clave >= clave })
+	if t[i].clave == clave {
+		return t[i], true
+	}
+
+	anterior, siguiente := t[i-1], t[i]
+	f := (clave - anterior.clave) / (siguiente.clave - anterior.clave)
+	return filaLMS{
+		clave: clave,
+		l:     anterior.l + f*(siguiente.l-anterior.l),
+		m:     anterior.m + f*(siguiente.m-anterior.m),
+		s:     anterior.s + f*(siguiente.s-anterior.s),
+	}, true
+}
+
+// zScore aplica la fórmula LMS. Para indicadores de peso la OMS restringe la
+// cola fuera de ±3 DE usando la distancia entre 2 y 3 DE.
+func (f filaLMS) zScore(valor float64, restringido bool) float64 {
+	z := f.zLMS(valor)
+	if !restringido || math.Abs(z) <= 3 {
+		return redondear(z)
+	}
+
+	if z > 3 {
+		de3 := f.valorEnZ(3)
+		de23 := de3 - f.valorEnZ(2)
+		return redondear(3 + (valor-de3)/de23)
+	}
+
+	de3 := f.valorEnZ(-3)
+	de23 := f.valorEnZ(-2) - de3
+	return redondear(-3 + (valor-de3)/de23)
+}
+
+func (f filaLMS) zLMS(valor float64) float64 {
+	if f.l == 0 {
+		return math.Log(valor/f.m) / f.s
+	}
+	return (math.Pow(valor/f.m, f.l) - 1) / (f.l * f.s)
+}
+
+func (f filaLMS) valorEnZ(z float64) float64 {
+	if f.l == 0 {
+		return f.m * math.Exp(f.s*z)
+	}
+	return f.m * math.Pow(1+f.l*f.s*z, 1/f.l)
+}
+
+func redondear(valor float64) float64 {
+	return math.Round(valor*100) / 100
+}
+
+// Evaluar calcula los z-scores disponibles para las medidas recibidas.
+// Retorna nil si el paciente está fuera del rango de edad cubierto por las tablas
+// y ErrTablasNoDisponibles si las tablas de la OMS no están completas.
+func Evaluar(medidas Medidas) (*Evaluacion, error) {
+	todas, err := cargarTablas()
+	if err != nil {
+		return nil, err
+	}
+	return evaluar(todas, medidas)
+}
+
+func evaluar(todas map[Sexo]*tablasSexo, medidas Medidas) (*Evaluacion, error) {
+	t, ok := todas[medidas.Sexo]
+	if !ok {
+		return nil, fmt.Errorf("sexo no soportado por las tablas de crecimiento: %d", medidas.Sexo)
+	}
+
+	if medidas.EdadDias < 0 || medidas.EdadDias > edadMaximaDias {
+		return nil, nil
+	}
+	edadMeses := float64(medidas.EdadDias) / diasPorMes
+	edadDias := float64(medidas.EdadDias)
+
+	evaluacion := &Evaluacion{
+		EdadMeses: int(edadMeses),
+		EdadDias:  medidas.EdadDias,
+	}
+
+	if medidas.Peso != nil {
+		if fila, ok := t.pesoEdad.interpolar(edadDias); ok {
+			z := fila.zScore(*medidas.Peso, true)
+			evaluacion.PesoEdad = &Indicador{ZScore: z, Clasificacion: clasificarPesoEdad(z)}
+		}
+	}
+
+	if medidas.Talla != nil {
+		if fila, ok := t.tallaEdad.interpolar(edadDias); ok {
+			z := fila.zScore(*medidas.Talla, false)
+			evaluacion.TallaEdad = &Indicador{ZScore: z, Clasificacion: clasificarTallaEdad(z)}
+		}
+	}
+
+	if medidas.Peso != nil && medidas.Talla != nil {
+		// Hasta los 2 años se mide la longitud acostado; desde entonces la talla de pie
+		tabla := t.pesoLongitud
+		if edadMeses >= edadTallaMeses {
+			tabla = t.pesoTalla
+		}
+		if fila, ok := tabla.interpolar(*medidas.Talla); ok {
+			z := fila.zScore(*medidas.Peso, true)
+			evaluacion.PesoTalla = &Indicador{ZScore: z, Clasificacion: clasificarPesoTalla(z)}
+		}
+
+		if *medidas.Talla > 0 {
+			metros := *medidas.Talla / 100
+			imc := *medidas.Peso / (metros * metros)
+			if fila, ok := t.imcEdad.interpolar(edadDias); ok {
+				z := fila.zScore(imc, true)
+				evaluacion.IMCEdad = &Indicador{ZScore: z, Clasificacion: clasificarPesoTalla(z)}
+			}
+		}
+	}
+
+	if medidas.PerimetroCefalico != nil {
+		if fila, ok := t.perimetroCefalicoEdad.interpolar(edadDias); ok {
+			z := fila.zScore(*medidas.PerimetroCefalico, false)
+			evaluacion.PerimetroCefalicoEdad = &Indicador{ZScore: z, Clasificacion: clasificarPerimetroCefalico(z)}
+		}
+	}
+
+	evaluacion.EstadoNutricional = estadoNutricional(evaluacion)
+	return evaluacion, nil
+}
+
+func clasificarPesoEdad(z float64) string {
+	switch {
+	case z < -2:
+		return "Desnutrición"
+	case z > 2:
+		return "Sobrepeso"
+	}
+	return "Normal"
+}
+
+func clasificarTallaEdad(z float64) string {
+	switch {
+	case z < -3:
+		return "Talla baja severa"
+	case z < -2:
+		return "Talla baja"
+	case z > 2:
+		return "Alto"
+	}
+	return "Normal"
+}
+
+// clasificarPesoTalla se usa tanto para peso para la talla como para IMC para la edad
+func clasificarPesoTalla(z float64) string {
+	switch {
+	case z < -3:
+		return "Desnutrición aguda severa"
+	case z < -2:
+		return "Desnutrición aguda"
+	case z > 3:
+		return "Obesidad"
+	case z > 2:
+		return "Sobrepeso"
+	}
+	return "Normal"
+}
+
+func clasificarPerimetroCefalico(z float64) string {
+	switch {
+	case z < -2:
+		return "Microcefalia"
+	case z > 2:
+		return "Macrocefalia"
+	}
+	return "Normal"
+}
+
+// estadoNutricional resume los diagnósticos nutricionales a partir de los indicadores.
+// El peso para la talla prevalece sobre el IMC para la edad cuando ambos existen.
+func estadoNutricional(e *Evaluacion) []string {
+	var estados []string
+
+	agudo := e.PesoTalla
+	if agudo == nil {
+		agudo = e.IMCEdad
+	}
+	if agudo != nil {
+		switch {
+		case agudo.ZScore < -3:
+			estados = append(estados, "Desnutrición aguda severa")
+		case agudo.ZScore < -2:
+			estados = append(estados, "Desnutrición aguda")
+		case agudo.ZScore > 3:
+			estados = append(estados, "Obesidad")
+		case agudo.ZScore > 2:
+			estados = append(estados, "Sobrepeso")
+		}
+	}
+
+	if e.TallaEdad != nil {
+		switch {
+		case e.TallaEdad.ZScore < -3:
+			estados = append(estados, "Desnutrición crónica severa")
+		case e.TallaEdad.ZScore < -2:
+			estados = append(estados, "Desnutrición crónica")
+		}
+	}
+
+	if len(estados) == 0 && (agudo != nil || e.TallaEdad != nil) {
+		estados = append(estados, "Normal")
+	}
+
+	return estados
+}
@@ -0,0 +1,191 @@
+package crecimiento
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// Filas publicadas en las tablas LMS de la OMS (2006): al nacer para los indicadores
+// por edad, a 45 cm para peso para la longitud y a 65 cm para peso para la talla
+var filasOMS = map[Sexo]map[string]filaLMS{
+	Masculino: {
+		"peso_edad":               {l: 0.3487, m: 3.3464, s: 0.14602},
+		"talla_edad":              {l: 1, m: 49.8842, s: 0.03795},
+		"imc_edad":                {l: -0.3053, m: 13.4069, s: 0.0956},
+		"perimetro_cefalico_edad": {l: 1, m: 34.4618, s: 0.03686},
+		"peso_longitud":           {l: -0.3521, m: 2.441, s: 0.09182},
+		"peso_talla":              {l: -0.3521, m: 7.4327, s: 0.08217},
+	},
+	Femenino: {
+		"peso_edad":  {l: 0.3809, m: 3.2322, s: 0.14171},
+		"talla_edad": {l: 1, m: 49.1477, s: 0.0379},
+	},
+}
+
+// tablasPrueba arma tablas que repiten la fila publicada en toda la grilla, para que
+// cualquier edad o talla dentro del rango se evalúe con ella
+func tablasPrueba(t *testing.T) map[Sexo]*tablasSexo {
+	t.Helper()
+	fuente := fstest.MapFS{}
+	sufijos := map[Sexo]string{Masculino: "masculino", Femenino: "femenino"}
+	for sexo, sufijo := range sufijos {
+		for _, rango := range rangosTablas {
+			fila, ok := filasOMS[sexo][rango.nombre]
+			if !ok {
+				fila = filasOMS[Masculino][rango.nombre]
+			}
+
+			var contenido strings.Builder
+			if rango.edad {
+				contenido.WriteString("mes,l,m,s\n")
+				for mes := 0; mes <= EdadMaximaMeses; mes++ {
+					fmt.Fprintf(&contenido, "%d,%g,%g,%g\n", mes, fila.l, fila.m, fila.s)
+				}
+			} else {
+				contenido.WriteString("cm,l,m,s\n")
+				for cm := rango.desde; cm <= rango.hasta; cm += pasoMaximoCm {
+					fmt.Fprintf(&contenido, "%.1f,%g,%g,%g\n", cm, fila.l, fila.m, fila.s)
+				}
+			}
+			fuente[fmt.Sprintf("tablas/%s_%s.csv", rango.nombre, sufijo)] = &fstest.MapFile{Data: []byte(contenido.String())}
+		}
+	}
+
+	tablas, err := leerTablas(fuente)
+	if err != nil {
+		t.Fatalf("leerTablas: %v", err)
+	}
+	return tablas
+}
+
+func valor(v float64) *float64 {
+	return &v
+}
+
+// casosOMS usan los valores de las tablas de desviaciones estándar de la OMS y valores
+// a uno y otro lado de los puntos de corte de -2 y -3 DE
+var casosOMS = []struct {
+	nombre        string
+	medidas       Medidas
+	indicador     func(*Evaluacion) *Indicador
+	zScore        float64
+	clasificacion string
+}{
+	{"peso mediana al nacer", Medidas{Sexo: Masculino, Peso: valor(3.3464)}, pesoEdad, 0, "Normal"},
+	{"peso sobre -2 DE", Medidas{Sexo: Masculino, Peso: valor(2.5)}, pesoEdad, -1.9, "Normal"},
+	{"peso bajo -2 DE", Medidas{Sexo: Masculino, Peso: valor(2.45)}, pesoEdad, -2.02, "Desnutrición"},
+	{"peso sobre -3 DE", Medidas{Sexo: Masculino, Peso: valor(2.1)}, pesoEdad, -2.95, "Desnutrición"},
+	{"peso bajo -3 DE con cola restringida", Medidas{Sexo: Masculino, Peso: valor(1.8)}, pesoEdad, -3.74, "Desnutrición"},
+	{"peso sobre +3 DE con cola restringida", Medidas{Sexo: Masculino, Peso: valor(5.2)}, pesoEdad, 3.28, "Sobrepeso"},
+	{"peso niña sobre -2 DE", Medidas{Sexo: Femenino, Peso: valor(2.4)}, pesoEdad, -1.99, "Normal"},
+	{"peso niña bajo -2 DE", Medidas{Sexo: Femenino, Peso: valor(2.35)}, pesoEdad, -2.12, "Desnutrición"},
+	{"longitud en -2 DE", Medidas{Sexo: Masculino, Talla: valor(46.1)}, tallaEdad, -2, "Normal"},
+	{"longitud bajo -2 DE", Medidas{Sexo: Masculino, Talla: valor(46)}, tallaEdad, -2.05, "Talla baja"},
+	{"longitud sobre -3 DE", Medidas{Sexo: Masculino, Talla: valor(44.3)}, tallaEdad, -2.95, "Talla baja"},
+	{"longitud bajo -3 DE", Medidas{Sexo: Masculino, Talla: valor(44.1)}, tallaEdad, -3.06, "Talla baja severa"},
+	{"longitud niña bajo -3 DE", Medidas{Sexo: Femenino, Talla: valor(43.5)}, tallaEdad, -3.03, "Talla baja severa"},
+	{"peso para la longitud sobre -2 DE", Medidas{Sexo: Masculino, Peso: valor(2.05), Talla: valor(45)}, pesoTalla, -1.96, "Normal"},
+	{"peso para la longitud bajo -2 DE", Medidas{Sexo: Masculino, Peso: valor(2), Talla: valor(45)}, pesoTalla, -2.25, "Desnutrición aguda"},
+	{"peso para la longitud sobre -3 DE", Medidas{Sexo: Masculino, Peso: valor(1.9), Talla: valor(45)}, pesoTalla, -2.85, "Desnutrición aguda"},
+	{"peso para la longitud bajo -3 DE", Medidas{Sexo: Masculino, Peso: valor(1.85), Talla: valor(45)}, pesoTalla, -3.16, "Desnutrición aguda severa"},
+	{"peso para la talla a los 2 años", Medidas{EdadDias: 731, Sexo: Masculino, Peso: valor(6.35), Talla: valor(65)}, pesoTalla, -1.97, "Normal"},
+	{"peso para la talla bajo -2 DE", Medidas{EdadDias: 731, Sexo: Masculino, Peso: valor(6.3), Talla: valor(65)}, pesoTalla, -2.07, "Desnutrición aguda"},
+	{"peso para la talla bajo -3 DE", Medidas{EdadDias: 731, Sexo: Masculino, Peso: valor(5.85), Talla: valor(65)}, pesoTalla, -3.04, "Desnutrición aguda severa"},
+	{"peso para la talla sobre +3 DE", Medidas{EdadDias: 731, Sexo: Masculino, Peso: valor(9.7), Talla: valor(65)}, pesoTalla, 3.1, "Obesidad"},
+	{"perímetro cefálico bajo -2 DE", Medidas{Sexo: Masculino, PerimetroCefalico: valor(31.8)}, perimetroCefalico, -2.1, "Microcefalia"},
+	{"perímetro cefálico sobre +3 DE", Medidas{Sexo: Masculino, PerimetroCefalico: valor(38.4)}, perimetroCefalico, 3.1, "Macrocefalia"},
+}
+
+func pesoEdad(e *Evaluacion) *Indicador          { return e.PesoEdad }
+func tallaEdad(e *Evaluacion) *Indicador         { return e.TallaEdad }
+func pesoTalla(e *Evaluacion) *Indicador         { return e.PesoTalla }
+func perimetroCefalico(e *Evaluacion) *Indicador { return e.PerimetroCefalicoEdad }
+
+func verificarCasosOMS(t *testing.T, tablas map[Sexo]*tablasSexo) {
+	for _, caso := range casosOMS {
+		t.Run(caso.nombre, func(t *testing.T) {
+			evaluacion, err := evaluar(tablas, caso.medidas)
+			if err != nil {
+				t.Fatalf("evaluar: %v", err)
+			}
+			indicador := caso.indicador(evaluacion)
+			if indicador == nil {
+				t.Fatal("no se calculó el indicador")
+			}
+			if math.Abs(indicador.ZScore-caso.zScore) > 0.005 {
+				t.Fatalf("z-score %.2f, se esperaba %.2f", indicador.ZScore, caso.zScore)
+			}
+			if indicador.Clasificacion != caso.clasificacion {
+				t.Fatalf("clasificación %q, se esperaba %q", indicador.Clasificacion, caso.clasificacion)
+			}
+		})
+	}
+}
+
+func TestEvaluarFilasOMS(t *testing.T) {
+	verificarCasosOMS(t, tablasPrueba(t))
+}
+
+// TestEvaluarTablasEmbebidas comprueba los mismos casos con las tablas que se compilan
+// en la API
+func TestEvaluarTablasEmbebidas(t *testing.T) {
+	tablas, err := cargarTablas()
+	if errors.Is(err, ErrTablasNoDisponibles) {
+		t.Skipf("tablas de la OMS no embebidas: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("cargarTablas: %v", err)
+	}
+	verificarCasosOMS(t, tablas)
+}
+
+func TestEstadoNutricional(t *testing.T) {
+	tablas := tablasPrueba(t)
+
+	casos := []struct {
+		nombre   string
+		medidas  Medidas
+		esperado []string
+	}{
+		{"normal", Medidas{Sexo: Masculino, Peso: valor(2.45), Talla: valor(49.9)}, []string{"Normal"}},
+		{"aguda severa y crónica", Medidas{Sexo: Masculino, Peso: valor(1.85), Talla: valor(45)}, []string{"Desnutrición aguda severa", "Desnutrición crónica"}},
+		{"sin talla no hay diagnóstico", Medidas{Sexo: Masculino, Peso: valor(1.8)}, nil},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			evaluacion, err := evaluar(tablas, caso.medidas)
+			if err != nil {
+				t.Fatalf("evaluar: %v", err)
+			}
+			if strings.Join(evaluacion.EstadoNutricional, "|") != strings.Join(caso.esperado, "|") {
+				t.Fatalf("estado %v, se esperaba %v", evaluacion.EstadoNutricional, caso.esperado)
+			}
+		})
+	}
+}
+
+func TestLeerTablasIncompletas(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		contenido string
+	}{
+		{"falta la tabla", ""},
+		{"meses incompletos", "mes,l,m,s\n0,0.3487,3.3464,0.14602\n1,0.2297,4.4709,0.13395\n"},
+		{"primera columna desconocida", "semana,l,m,s\n0,0.3487,3.3464,0.14602\n"},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			fuente := fstest.MapFS{}
+			if caso.contenido != "" {
+				fuente["tablas/peso_edad_masculino.csv"] = &fstest.MapFile{Data: []byte(caso.contenido)}
+			}
+			if _, err := leerTablas(fuente); !errors.Is(err, ErrTablasNoDisponibles) {
+				t.Fatalf("error %v, se esperaba ErrTablasNoDisponibles", err)
+			}
+		})
+	}
+}
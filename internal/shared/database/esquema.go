@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrEsquemaIncompleto indica que faltan aplicar migraciones en la base de datos
+var ErrEsquemaIncompleto = errors.New("faltan tablas de la API en la base de datos")
+
+// Tabla es una tabla propia de la API y el script de migraciones/ que la crea
+type Tabla struct {
+	Nombre    string
+	Migracion string
+}
+
+// TablasApi son las tablas que crean los scripts de migraciones/, en orden de versión
+var TablasApi = []Tabla{
+	{Nombre: "TriajeClasificacion", Migracion: "V001__triaje_clasificacion.sql"},
+	{Nombre: "TamizajeAnemia", Migracion: "V002__tamizaje_anemia.sql"},
+	{Nombre: "TriajeRevision", Migracion: "V003__triaje_revision.sql"},
+	{Nombre: "TriajeSincronizacion", Migracion: "V004__triaje_sincronizacion.sql"},
+	{Nombre: "CredencialesEmpleado", Migracion: "V005__credenciales_empleado.sql"},
+	{Nombre: "SesionesRefresco", Migracion: "V006__sesiones_refresco.sql"},
+	{Nombre: "ClavesApi", Migracion: "V007__claves_api.sql"},
+	{Nombre: "EmpleadosServicio", Migracion: "V008__empleados_servicio.sql"},
+}
+
+const queryExisteTabla = `
+  SELECT CASE WHEN OBJECT_ID(@tabla, 'U') IS NULL THEN 0 ELSE 1 END`
+
+// VerificarTablas comprueba que existan las tablas indicadas. La API no ejecuta DDL:
+// las tablas se crean con los scripts de migraciones/ y un usuario con permisos DDL.
+// Si faltan tablas retorna ErrEsquemaIncompleto con las migraciones pendientes; si la
+// base de datos no responde retorna el error de conexión.
+func (s *ServicioDB) VerificarTablas(ctx context.Context, tablas []Tabla, usarSecundaria bool) error {
+	db, err := s.ObtenerConexion(usarSecundaria)
+	if err != nil {
+		return fmt.Errorf("error al obtener conexión: %w", err)
+	}
+
+	faltantes := []string{}
+	for _, tabla := range tablas {
+		var existe bool
+		if err := db.QueryRowContext(ctx, queryExisteTabla, sql.Named("tabla", "dbo."+tabla.Nombre)).Scan(&existe); err != nil {
+			return fmt.Errorf("error al verificar la tabla %s: %w", tabla.Nombre, err)
+		}
+		if !existe {
+			faltantes = append(faltantes, fmt.Sprintf("%s (%s)", tabla.Nombre, tabla.Migracion))
+		}
+	}
+
+	if len(faltantes) > 0 {
+		return fmt.Errorf("%w: %s", ErrEsquemaIncompleto, strings.Join(faltantes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// EjecutarTransaccion ejecuta fn dentro de una transacción. Confirma si fn retorna nil
+// y revierte en cualquier otro caso; el error de fn se retorna sin envolver para que
+// el llamador pueda compararlo con sus errores conocidos.
+func (s *ServicioDB) EjecutarTransaccion(ctx context.Context, usarSecundaria bool, fn func(tx *sql.Tx) error) error {
+	db, err := s.ObtenerConexion(usarSecundaria)
+	if err != nil {
+		return fmt.Errorf("error al obtener conexión: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar transacción: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package errores
+
+// Tipos de error devueltos en el campo "tipo" de la respuesta de ErroresGlobales
+const (
+	TipoValidacion            = "VALIDATION_ERROR"
+	TipoConfirmacionRequerida = "CONFIRMATION_REQUIRED"
+	TipoNoElegible            = "NOT_ELIGIBLE"
+	TipoNoAutenticado         = "UNAUTHORIZED"
+	TipoProhibido             = "FORBIDDEN"
+	TipoCuentaBloqueada       = "ACCOUNT_LOCKED"
+	TipoDemasiadosIntentos    = "TOO_MANY_ATTEMPTS"
+)
+
+// ErrorApi es un error con código HTTP, tipo y detalles que ErroresGlobales
+// serializa tal cual en la respuesta estandarizada
+type ErrorApi struct {
+	Status   int
+	Tipo     string
+	Mensaje  string
+	Detalles interface{}
+}
+
+func (e *ErrorApi) Error() string {
+	return e.Mensaje
+}
+
+// Nuevo crea un ErrorApi sin detalles
+func Nuevo(status int, tipo string, mensaje string) *ErrorApi {
+	return &ErrorApi{Status: status, Tipo: tipo, Mensaje: mensaje}
+}
+
+// ConDetalles adjunta información adicional para el cliente (p. ej. errores por campo)
+func (e *ErrorApi) ConDetalles(detalles interface{}) *ErrorApi {
+	e.Detalles = detalles
+	return e
+}
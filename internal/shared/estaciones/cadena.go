@@ -0,0 +1,275 @@
+package estaciones
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"backend/internal/config"
+)
+
+// Valores por defecto cuando config.yml no define la sección estacion
+const (
+	EncabezadoPorDefecto   = "X-Nombre-PC"
+	longitudPorDefecto     = 50
+	cacheDnsPorDefecto     = time.Hour
+	plazoDnsPorDefecto     = 300 * time.Millisecond
+	maximoEntradasCacheDns = 10000
+)
+
+// ResolutorDNS obtiene los nombres de una dirección IP; *net.Resolver lo implementa y en
+// pruebas puede reemplazarse por uno fijo
+type ResolutorDNS interface {
+	LookupAddr(ctx context.Context, direccion string) ([]string, error)
+}
+
+// Solicitud son los datos de la solicitud HTTP que identifican a la estación
+type Solicitud struct {
+	// Encabezado es el valor del encabezado con el nombre de la PC que envían los clientes
+	Encabezado string
+	// ReenviadoPara es el valor de X-Forwarded-For
+	ReenviadoPara string
+	// IpRemota es la dirección de la conexión
+	IpRemota string
+}
+
+type nombreCache struct {
+	nombre string
+	expira time.Time
+}
+
+// Cadena resuelve el nombrePC de la auditoría: el nombre que envía el cliente en el
+// encabezado confiable; si falta, el nombre DNS inverso de la IP real del cliente (tomada
+// de X-Forwarded-For solo detrás de los proxies configurados); y si tampoco lo hay, la IP.
+// El resultado se normaliza a la longitud que admite AuditoriaAgregarV.
+type Cadena struct {
+	encabezado string
+	clientes   []netip.Prefix
+	proxies    []netip.Prefix
+	longitud   int
+	dns        ResolutorDNS
+	cacheDns   time.Duration
+	plazoDns   time.Duration
+
+	mu      sync.Mutex
+	nombres map[netip.Addr]nombreCache
+}
+
+// NuevaCadena crea la cadena según la sección estacion de config.yml. dns puede ser nil
+// para usar el resolutor del sistema; la búsqueda inversa se omite si no está habilitada.
+func NuevaCadena(cfg config.EstacionConfig, dns ResolutorDNS) *Cadena {
+	cadena := &Cadena{
+		encabezado: cfg.Encabezado,
+		longitud:   cfg.LongitudMaxima,
+		cacheDns:   time.Duration(cfg.CacheDnsMinutos) * time.Minute,
+		plazoDns:   time.Duration(cfg.PlazoDnsMilisegundos) * time.Millisecond,
+		nombres:    make(map[netip.Addr]nombreCache),
+	}
+	if cadena.encabezado == "" {
+		cadena.encabezado = EncabezadoPorDefecto
+	}
+	if cadena.longitud <= 0 {
+		cadena.longitud = longitudPorDefecto
+	}
+	if cadena.cacheDns <= 0 {
+		cadena.cacheDns = cacheDnsPorDefecto
+	}
+	if cadena.plazoDns <= 0 {
+		cadena.plazoDns = plazoDnsPorDefecto
+	}
+
+	if cfg.DnsInverso {
+		cadena.dns = dns
+		if cadena.dns == nil {
+			cadena.dns = net.DefaultResolver
+		}
+	}
+
+	cadena.proxies = parsearRangos("proxy confiable", cfg.ProxiesConfiables)
+	cadena.clientes = parsearRangos("red de clientes", cfg.RedesClientes)
+	if len(cadena.clientes) == 0 {
+		log.Printf("[Estacion] No hay redes_clientes configuradas: se ignora el encabezado %s y se usa el nombre DNS o la IP", cadena.encabezado)
+	}
+	return cadena
+}
+
+// Encabezado es el nombre del encabezado con el nombre de la PC
+func (c *Cadena) Encabezado() string {
+	return c.encabezado
+}
+
+// Resolver retorna el nombrePC de la solicitud. El encabezado solo se acepta de las
+// redes de clientes configuradas, pues cualquier otro origen podría escribir un nombre
+// arbitrario en la auditoría.
+func (c *Cadena) Resolver(ctx context.Context, solicitud Solicitud) string {
+	ip, ok := c.IpCliente(solicitud)
+
+	if ok && contiene(c.clientes, ip) {
+		if nombre := c.normalizar(solicitud.Encabezado); nombre != "" {
+			return nombre
+		}
+	}
+
+	if !ok {
+		return c.normalizar(solicitud.IpRemota)
+	}
+
+	if nombre := c.normalizar(c.nombreDns(ctx, ip)); nombre != "" {
+		return nombre
+	}
+	return c.normalizar(ip.String())
+}
+
+// IpCliente retorna la IP real del cliente. X-Forwarded-For solo se considera si la
+// conexión viene de un proxy confiable; se recorre de derecha a izquierda y se toma la
+// primera dirección que no es de un proxy confiable, pues las anteriores las escribe el
+// propio cliente.
+func (c *Cadena) IpCliente(solicitud Solicitud) (netip.Addr, bool) {
+	remota, err := netip.ParseAddr(strings.TrimSpace(solicitud.IpRemota))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	remota = remota.Unmap()
+
+	if !c.confiable(remota) || solicitud.ReenviadoPara == "" {
+		return remota, true
+	}
+
+	saltos := strings.Split(solicitud.ReenviadoPara, ",")
+	for i := len(saltos) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(strings.TrimSpace(saltos[i]))
+		if err != nil {
+			// Una entrada ilegible impide confiar en las anteriores
+			return remota, true
+		}
+		ip = ip.Unmap()
+		if !c.confiable(ip) {
+			return ip, true
+		}
+		remota = ip
+	}
+	return remota, true
+}
+
+func (c *Cadena) confiable(ip netip.Addr) bool {
+	return contiene(c.proxies, ip)
+}
+
+func contiene(redes []netip.Prefix, ip netip.Addr) bool {
+	for _, red := range redes {
+		if red.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// nombreDns consulta el nombre inverso de la IP con un plazo corto; los resultados,
+// también los fallidos, se guardan en caché para no repetir la consulta en cada solicitud
+func (c *Cadena) nombreDns(ctx context.Context, ip netip.Addr) string {
+	if c.dns == nil {
+		return ""
+	}
+
+	ahora := time.Now()
+	c.mu.Lock()
+	cache, ok := c.nombres[ip]
+	c.mu.Unlock()
+	if ok && ahora.Before(cache.expira) {
+		return cache.nombre
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.plazoDns)
+	defer cancel()
+
+	nombre := ""
+	if nombres, err := c.dns.LookupAddr(ctx, ip.String()); err == nil && len(nombres) > 0 {
+		nombre = nombreEquipo(nombres[0])
+	}
+
+	c.mu.Lock()
+	if len(c.nombres) >= maximoEntradasCacheDns {
+		for clave, entrada := range c.nombres {
+			if ahora.After(entrada.expira) {
+				delete(c.nombres, clave)
+			}
+		}
+		if len(c.nombres) >= maximoEntradasCacheDns {
+			c.nombres = make(map[netip.Addr]nombreCache)
+		}
+	}
+	c.nombres[ip] = nombreCache{nombre: nombre, expira: ahora.Add(c.cacheDns)}
+	c.mu.Unlock()
+
+	return nombre
+}
+
+// nombreEquipo conserva solo el nombre del equipo de un FQDN (PC-TRIAJE01.hospital.local)
+func nombreEquipo(fqdn string) string {
+	nombre, _, _ := strings.Cut(strings.TrimSuffix(fqdn, "."), ".")
+	return nombre
+}
+
+// normalizar reemplaza los caracteres de control y espacios internos y recorta el nombre
+// a la longitud de la columna
+func (c *Cadena) normalizar(nombre string) string {
+	nombre = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			return ' '
+		}
+		return r
+	}, nombre)
+	nombre = strings.Join(strings.Fields(nombre), "_")
+
+	if runas := []rune(nombre); len(runas) > c.longitud {
+		nombre = string(runas[:c.longitud])
+	}
+	return nombre
+}
+
+// parsearRangos descarta con un aviso los rangos inválidos de la configuración
+func parsearRangos(descripcion string, rangos []string) []netip.Prefix {
+	prefijos := []netip.Prefix{}
+	for _, rango := range rangos {
+		prefijo, err := ParsearRango(rango)
+		if err != nil {
+			log.Printf("[Estacion] Se ignora la %s: %v", descripcion, err)
+			continue
+		}
+		prefijos = append(prefijos, prefijo)
+	}
+	return prefijos
+}
+
+// ParsearRango acepta un rango CIDR o una dirección individual. Las direcciones y rangos
+// IPv4 mapeados en IPv6 se convierten a IPv4, como las IPs de cliente con que se comparan;
+// se rechazan los rangos mapeados que abarcan más que el espacio IPv4.
+func ParsearRango(rango string) (netip.Prefix, error) {
+	rango = strings.TrimSpace(rango)
+	if strings.Contains(rango, "/") {
+		prefijo, err := netip.ParsePrefix(rango)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("el rango de IP %q no es válido", rango)
+		}
+		if prefijo.Addr().Is4In6() {
+			if prefijo.Bits() < 96 {
+				return netip.Prefix{}, fmt.Errorf("el rango de IP %q excede las direcciones IPv4 mapeadas", rango)
+			}
+			prefijo = netip.PrefixFrom(prefijo.Addr().Unmap(), prefijo.Bits()-96)
+		}
+		return prefijo.Masked(), nil
+	}
+
+	direccion, err := netip.ParseAddr(rango)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("la dirección IP %q no es válida", rango)
+	}
+	direccion = direccion.Unmap()
+	return netip.PrefixFrom(direccion, direccion.BitLen()), nil
+}
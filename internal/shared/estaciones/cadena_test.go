@@ -0,0 +1,177 @@
+package estaciones
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"backend/internal/config"
+)
+
+// resolutorFijo responde con los nombres configurados y cuenta las consultas
+type resolutorFijo struct {
+	mu        sync.Mutex
+	nombres   map[string][]string
+	consultas map[string]int
+}
+
+func nuevoResolutorFijo(nombres map[string][]string) *resolutorFijo {
+	return &resolutorFijo{nombres: nombres, consultas: make(map[string]int)}
+}
+
+func (r *resolutorFijo) LookupAddr(_ context.Context, direccion string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consultas[direccion]++
+	if nombres, ok := r.nombres[direccion]; ok {
+		return nombres, nil
+	}
+	return nil, errors.New("sin registro PTR")
+}
+
+func (r *resolutorFijo) cantidad(direccion string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.consultas[direccion]
+}
+
+func TestIpCliente(t *testing.T) {
+	cadena := NuevaCadena(config.EstacionConfig{
+		ProxiesConfiables: []string{"10.0.0.1", "10.1.0.0/16"},
+	}, nil)
+
+	casos := []struct {
+		nombre    string
+		solicitud Solicitud
+		esperada  string
+		ok        bool
+	}{
+		{"sin proxy", Solicitud{IpRemota: "192.168.1.20"}, "192.168.1.20", true},
+		{"XFF de una conexión no confiable", Solicitud{IpRemota: "192.168.1.20", ReenviadoPara: "8.8.8.8"}, "192.168.1.20", true},
+		{"XFF detrás de un proxy confiable", Solicitud{IpRemota: "10.0.0.1", ReenviadoPara: "192.168.1.20"}, "192.168.1.20", true},
+		{"se ignora lo que escribe el cliente", Solicitud{IpRemota: "10.0.0.1", ReenviadoPara: "1.2.3.4, 192.168.1.20"}, "192.168.1.20", true},
+		{"cadena de proxies confiables", Solicitud{IpRemota: "10.0.0.1", ReenviadoPara: "1.2.3.4, 192.168.1.20, 10.1.5.5"}, "192.168.1.20", true},
+		{"todos los saltos son proxies", Solicitud{IpRemota: "10.0.0.1", ReenviadoPara: "10.1.0.2, 10.1.0.3"}, "10.1.0.2", true},
+		{"entrada ilegible", Solicitud{IpRemota: "10.0.0.1", ReenviadoPara: "192.168.1.20, basura"}, "10.0.0.1", true},
+		{"IPv4 mapeada en IPv6", Solicitud{IpRemota: "::ffff:10.0.0.1", ReenviadoPara: "::ffff:192.168.1.20"}, "192.168.1.20", true},
+		{"IP remota ilegible", Solicitud{IpRemota: "desconocida"}, "", false},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			ip, ok := cadena.IpCliente(caso.solicitud)
+			if ok != caso.ok {
+				t.Fatalf("ok = %v, se esperaba %v", ok, caso.ok)
+			}
+			if ok && ip.String() != caso.esperada {
+				t.Fatalf("IP %s, se esperaba %s", ip, caso.esperada)
+			}
+		})
+	}
+}
+
+func TestResolver(t *testing.T) {
+	dns := nuevoResolutorFijo(map[string][]string{
+		"192.168.1.20": {"PC-TRIAJE01.hospital.local."},
+	})
+	cadena := NuevaCadena(config.EstacionConfig{
+		RedesClientes:     []string{"192.168.1.0/24"},
+		ProxiesConfiables: []string{"10.0.0.1"},
+		DnsInverso:        true,
+		LongitudMaxima:    12,
+	}, dns)
+
+	casos := []struct {
+		nombre    string
+		solicitud Solicitud
+		esperado  string
+	}{
+		{"encabezado de la red de clientes", Solicitud{Encabezado: "PC-ADMISION", IpRemota: "192.168.1.30"}, "PC-ADMISION"},
+		{"encabezado detrás del proxy", Solicitud{Encabezado: "PC-ADMISION", IpRemota: "10.0.0.1", ReenviadoPara: "192.168.1.30"}, "PC-ADMISION"},
+		{"encabezado fuera de la red de clientes", Solicitud{Encabezado: "PC-FALSA", IpRemota: "172.16.0.9"}, "172.16.0.9"},
+		{"nombre DNS sin dominio", Solicitud{IpRemota: "192.168.1.20"}, "PC-TRIAJE01"},
+		{"nombre DNS de la IP reenviada", Solicitud{IpRemota: "10.0.0.1", ReenviadoPara: "192.168.1.20"}, "PC-TRIAJE01"},
+		{"espacios y controles", Solicitud{Encabezado: " PC \tDE\nCAJA ", IpRemota: "192.168.1.30"}, "PC_DE_CAJA"},
+		{"recorte a la longitud", Solicitud{Encabezado: "PC-CONSULTORIO-EXTERNO", IpRemota: "192.168.1.30"}, "PC-CONSULTOR"},
+		{"IP remota ilegible", Solicitud{IpRemota: "desconocida"}, "desconocida"},
+		{"IP remota ilegible con encabezado", Solicitud{Encabezado: "PC-FALSA", IpRemota: "desconocida"}, "desconocida"},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			if nombre := cadena.Resolver(context.Background(), caso.solicitud); nombre != caso.esperado {
+				t.Fatalf("nombrePC %q, se esperaba %q", nombre, caso.esperado)
+			}
+		})
+	}
+}
+
+func TestResolverGuardaDnsEnCache(t *testing.T) {
+	dns := nuevoResolutorFijo(map[string][]string{
+		"192.168.1.20": {"PC-TRIAJE01.hospital.local."},
+	})
+	cadena := NuevaCadena(config.EstacionConfig{DnsInverso: true}, dns)
+
+	for range 3 {
+		cadena.Resolver(context.Background(), Solicitud{IpRemota: "192.168.1.20"})
+		cadena.Resolver(context.Background(), Solicitud{IpRemota: "192.168.1.21"})
+	}
+
+	// Las consultas fallidas también se guardan para no repetirlas en cada solicitud
+	for _, ip := range []string{"192.168.1.20", "192.168.1.21"} {
+		if consultas := dns.cantidad(ip); consultas != 1 {
+			t.Fatalf("%d consultas DNS para %s, se esperaba 1", consultas, ip)
+		}
+	}
+}
+
+func TestResolverSinDnsInverso(t *testing.T) {
+	dns := nuevoResolutorFijo(map[string][]string{
+		"192.168.1.20": {"PC-TRIAJE01.hospital.local."},
+	})
+	cadena := NuevaCadena(config.EstacionConfig{}, dns)
+
+	if nombre := cadena.Resolver(context.Background(), Solicitud{IpRemota: "192.168.1.20"}); nombre != "192.168.1.20" {
+		t.Fatalf("nombrePC %q, se esperaba la IP", nombre)
+	}
+	if consultas := dns.cantidad("192.168.1.20"); consultas != 0 {
+		t.Fatalf("%d consultas DNS con la búsqueda inversa deshabilitada", consultas)
+	}
+}
+
+func TestResolverSinRedesClientesIgnoraElEncabezado(t *testing.T) {
+	cadena := NuevaCadena(config.EstacionConfig{}, nil)
+
+	if nombre := cadena.Resolver(context.Background(), Solicitud{Encabezado: "PC-FALSA", IpRemota: "192.168.1.20"}); nombre != "192.168.1.20" {
+		t.Fatalf("nombrePC %q, se esperaba la IP", nombre)
+	}
+}
+
+func TestParsearRango(t *testing.T) {
+	casos := []struct {
+		rango    string
+		esperado string
+		valido   bool
+	}{
+		{"192.168.1.0/24", "192.168.1.0/24", true},
+		{" 192.168.1.77/24 ", "192.168.1.0/24", true},
+		{"10.0.0.1", "10.0.0.1/32", true},
+		{"::ffff:10.0.0.1", "10.0.0.1/32", true},
+		{"2001:db8::1", "2001:db8::1/128", true},
+		{"::ffff:10.0.0.0/104", "10.0.0.0/8", true},
+		{"::ffff:192.168.1.77/120", "192.168.1.0/24", true},
+		{"::ffff:0:0/64", "", false},
+		{"192.168.1.0/33", "", false},
+		{"servidor", "", false},
+	}
+	for _, caso := range casos {
+		t.Run(caso.rango, func(t *testing.T) {
+			prefijo, err := ParsearRango(caso.rango)
+			if (err == nil) != caso.valido {
+				t.Fatalf("error %v, válido esperado %v", err, caso.valido)
+			}
+			if caso.valido && prefijo.String() != caso.esperado {
+				t.Fatalf("prefijo %s, se esperaba %s", prefijo, caso.esperado)
+			}
+		})
+	}
+}
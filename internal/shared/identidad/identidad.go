@@ -0,0 +1,79 @@
+package identidad
+
+import "context"
+
+// Empleado identifica al usuario de SIGH autenticado en la solicitud
+type Empleado struct {
+	IdEmpleado int    `json:"idEmpleado"`
+	Usuario    string `json:"usuario"`
+	Nombre     string `json:"nombre"`
+	// Actor es el supervisor que actúa a nombre de este empleado durante una suplencia;
+	// la identidad efectiva (permisos, registros) es la del empleado
+	Actor *Empleado `json:"actor,omitempty"`
+}
+
+type claveEmpleado struct{}
+
+// ConEmpleado retorna un contexto que transporta al empleado autenticado
+func ConEmpleado(ctx context.Context, empleado *Empleado) context.Context {
+	return context.WithValue(ctx, claveEmpleado{}, empleado)
+}
+
+// EmpleadoDesde obtiene el empleado autenticado del contexto de la solicitud
+func EmpleadoDesde(ctx context.Context) (*Empleado, bool) {
+	empleado, ok := ctx.Value(claveEmpleado{}).(*Empleado)
+	return empleado, ok && empleado != nil
+}
+
+// EstacionSistema es la estación con que se auditan los procesos del sistema
+const EstacionSistema = "API"
+
+type claveEstacion struct{}
+
+// ConEstacion retorna un contexto que transporta el nombre de la estación de trabajo
+// (PC) desde la que se hizo la solicitud
+func ConEstacion(ctx context.Context, nombrePC string) context.Context {
+	return context.WithValue(ctx, claveEstacion{}, nombrePC)
+}
+
+// EstacionDesde obtiene la estación de trabajo de la solicitud
+func EstacionDesde(ctx context.Context) (string, bool) {
+	nombrePC, ok := ctx.Value(claveEstacion{}).(string)
+	return nombrePC, ok && nombrePC != ""
+}
+
+// ComoSistema prepara el contexto de un proceso sin solicitud HTTP (tarea programada,
+// reintento) para que audite a nombre del empleado indicado desde la estación "API".
+// Es la única forma prevista de fijar la identidad fuera de los middlewares.
+func ComoSistema(ctx context.Context, idEmpleado int) context.Context {
+	ctx = ConEmpleado(ctx, &Empleado{IdEmpleado: idEmpleado, Usuario: EstacionSistema, Nombre: EstacionSistema})
+	return ConEstacion(ctx, EstacionSistema)
+}
+
+type claveIpCliente struct{}
+
+// ConIpCliente retorna un contexto que transporta la IP real del cliente, resuelta detrás
+// de los proxies confiables
+func ConIpCliente(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, claveIpCliente{}, ip)
+}
+
+// IpClienteDesde obtiene la IP real del cliente de la solicitud
+func IpClienteDesde(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(claveIpCliente{}).(string)
+	return ip, ok && ip != ""
+}
+
+type claveAlcance struct{}
+
+// ConAlcance limita los permisos de la solicitud a los indicados, sin importar los roles
+// del empleado; lo usan las solicitudes autenticadas con clave de API
+func ConAlcance(ctx context.Context, permisos []string) context.Context {
+	return context.WithValue(ctx, claveAlcance{}, permisos)
+}
+
+// AlcanceDesde obtiene los permisos a los que está limitada la solicitud
+func AlcanceDesde(ctx context.Context) ([]string, bool) {
+	permisos, ok := ctx.Value(claveAlcance{}).([]string)
+	return permisos, ok
+}
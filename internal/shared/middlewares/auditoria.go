@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"backend/internal/shared/services/auditoria"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RutaAuditada declara cómo auditar una ruta de escritura
+type RutaAuditada struct {
+	Tabla      string
+	IdListItem int
+	// IdRegistro obtiene el id del registro afectado una vez ejecutado el handler;
+	// ver IdDeParametro e IdDeRespuesta
+	IdRegistro func(c *fiber.Ctx) (int, error)
+	// Observaciones es opcional; por defecto se registra el método y la ruta
+	Observaciones func(c *fiber.Ctx) string
+	// Accion reemplaza la deducida del método, p. ej. en un POST que modifica un registro
+	// existente
+	Accion string
+}
+
+// Audita construye el middleware que audita una ruta de escritura, p. ej.
+// grupo.Post("/:id<int>", requiere("x:escribir"), audita(middlewares.RutaAuditada{...}), handler)
+type Audita func(ruta RutaAuditada) fiber.Handler
+
+// Auditoria retorna el constructor de middlewares de auditoría. Tras una respuesta 2xx de
+// un POST, PUT/PATCH o DELETE registra AccionAgregar, AccionModificar o AccionEliminar a
+// nombre del empleado del contexto. Un fallo de la auditoría se registra en el log y en
+// las métricas de auditoría, pero no revierte ni falla la escritura ya realizada.
+// Las rutas cuyo servicio ya audita con más detalle no deben declararla.
+func Auditoria(servicio *auditoria.AuditoriaServicio) Audita {
+	return func(ruta RutaAuditada) fiber.Handler {
+		// Una declaración incompleta es un error de programación
+		if ruta.Tabla == "" || ruta.IdListItem <= 0 || ruta.IdRegistro == nil {
+			panic(fmt.Sprintf("auditoría de la tabla %q: faltan la tabla, el idListItem o el extractor de IdRegistro", ruta.Tabla))
+		}
+
+		return func(c *fiber.Ctx) error {
+			if err := c.Next(); err != nil {
+				return err
+			}
+
+			estado := c.Response().StatusCode()
+			accion := accionAuditoria(c.Method())
+			if ruta.Accion != "" && accion != "" {
+				accion = ruta.Accion
+			}
+			if accion == "" || estado < fiber.StatusOK || estado >= fiber.StatusMultipleChoices {
+				return nil
+			}
+
+			idRegistro, err := ruta.IdRegistro(c)
+			if err != nil {
+				auditoria.ContarFallo()
+				log.Printf("[Auditoria] No se auditó %s %s: %v", c.Method(), c.OriginalURL(), err)
+				return nil
+			}
+
+			observaciones := fmt.Sprintf("%s %s", c.Method(), c.Path())
+			if ruta.Observaciones != nil {
+				observaciones = ruta.Observaciones(c)
+			}
+
+			if err := servicio.RegistrarAuditoria(c.UserContext(), accion, idRegistro, ruta.Tabla, ruta.IdListItem, observaciones); err != nil {
+				log.Printf("[Auditoria] No se auditó %s %s (%s %d): %v", c.Method(), c.OriginalURL(), ruta.Tabla, idRegistro, err)
+			}
+			return nil
+		}
+	}
+}
+
+func accionAuditoria(metodo string) string {
+	switch metodo {
+	case fiber.MethodPost:
+		return auditoria.AccionAgregar
+	case fiber.MethodPut, fiber.MethodPatch:
+		return auditoria.AccionModificar
+	case fiber.MethodDelete:
+		return auditoria.AccionEliminar
+	}
+	return ""
+}
+
+// IdDeParametro toma IdRegistro del parámetro entero de la ruta
+func IdDeParametro(nombre string) func(c *fiber.Ctx) (int, error) {
+	return func(c *fiber.Ctx) (int, error) {
+		id, err := c.ParamsInt(nombre)
+		if err != nil || id <= 0 {
+			return 0, fmt.Errorf("el parámetro %s no es un id válido", nombre)
+		}
+		return id, nil
+	}
+}
+
+// IdDeRespuesta toma IdRegistro del campo de "data" en la respuesta JSON del handler,
+// para las rutas que crean el registro y retornan su id
+func IdDeRespuesta(campo string) func(c *fiber.Ctx) (int, error) {
+	return func(c *fiber.Ctx) (int, error) {
+		var respuesta struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(c.Response().Body(), &respuesta); err != nil {
+			return 0, fmt.Errorf("la respuesta no es JSON: %w", err)
+		}
+
+		var id int
+		valor, ok := respuesta.Data[campo]
+		if !ok || json.Unmarshal(valor, &id) != nil || id <= 0 {
+			return 0, fmt.Errorf("la respuesta no incluye data.%s", campo)
+		}
+		return id, nil
+	}
+}
@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"errors"
+	"strings"
+
+	"backend/internal/shared/errores"
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/services/clavesapi"
+	"backend/internal/shared/sesiones"
+	"backend/internal/shared/tokens"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Autenticacion exige un token de acceso válido en el encabezado Authorization: Bearer,
+// una clave de API en X-Api-Key o, en su defecto, una cookie de sesión firmada (modo
+// cookie). La sesión no debe estar revocada y el empleado queda en el contexto de la
+// solicitud (c.UserContext()); con clave de API es la cuenta de servicio configurada.
+// En modo cookie los métodos que modifican datos exigen el token CSRF.
+func Autenticacion(gestor *sesiones.GestorSesiones, opciones OpcionesCookie, claves *clavesapi.ClavesApiServicio) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token := TokenBearer(c.Get(fiber.HeaderAuthorization)); token != "" {
+			claims, err := gestor.VerificarAcceso(c.UserContext(), token)
+			if errors.Is(err, tokens.ErrTokenInvalido) || errors.Is(err, sesiones.ErrSesionRevocada) {
+				return noAutenticado(c, "La sesión no es válida o expiró. Inicie sesión nuevamente.")
+			}
+			if err != nil {
+				return err
+			}
+
+			c.SetUserContext(identidad.ConEmpleado(c.UserContext(), claims.Empleado()))
+			return c.Next()
+		}
+
+		if clave := c.Get(EncabezadoClaveApi); clave != "" {
+			return autenticarClave(c, claves, clave)
+		}
+
+		if valor := c.Cookies(CookieSesion); valor != "" {
+			return autenticarCookie(c, gestor, opciones, valor)
+		}
+
+		return noAutenticado(c, "Debe iniciar sesión para acceder a este recurso.")
+	}
+}
+
+func autenticarClave(c *fiber.Ctx, claves *clavesapi.ClavesApiServicio, valor string) error {
+	empleado, clave, err := claves.Verificar(c.UserContext(), valor, IpCliente(c))
+	if errors.Is(err, clavesapi.ErrClaveInvalida) {
+		return noAutenticado(c, "La clave de API no es válida, expiró o fue revocada.")
+	}
+	if errors.Is(err, clavesapi.ErrCuentaServicioNoConfigurada) {
+		return noAutenticado(c, "Las claves de API no están habilitadas en este servidor.")
+	}
+	if errors.Is(err, clavesapi.ErrIpNoPermitida) {
+		return errores.Nuevo(fiber.StatusForbidden, errores.TipoProhibido, "La clave de API no admite solicitudes desde esta dirección.")
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx := identidad.ConEmpleado(c.UserContext(), empleado)
+	c.SetUserContext(identidad.ConAlcance(ctx, clave.Permisos))
+	return c.Next()
+}
+
+func autenticarCookie(c *fiber.Ctx, gestor *sesiones.GestorSesiones, opciones OpcionesCookie, valor string) error {
+	sesion, renovada, err := gestor.VerificarCookie(c.UserContext(), valor)
+	if errors.Is(err, sesiones.ErrCookieInvalida) || errors.Is(err, sesiones.ErrSesionRevocada) {
+		opciones.Limpiar(c)
+		return noAutenticado(c, "La sesión no es válida o expiró. Inicie sesión nuevamente.")
+	}
+	if err != nil {
+		return err
+	}
+
+	if !metodoSeguro(c.Method()) && (!CsrfDobleEnvio(c) || !gestor.VerificarCsrf(sesion, c.Get(EncabezadoCsrf))) {
+		return errores.Nuevo(fiber.StatusForbidden, errores.TipoProhibido, "El token CSRF no es válido. Recargue la página e intente nuevamente.")
+	}
+
+	if renovada != "" {
+		opciones.EstablecerSesion(c, renovada, gestor.TokenCsrf(sesion), sesion.ExpiraEn())
+	}
+
+	c.SetUserContext(identidad.ConEmpleado(c.UserContext(), sesion.Empleado()))
+	return c.Next()
+}
+
+// EncabezadoClaveApi lo envían las integraciones (analizadores, kioscos, reportes)
+const EncabezadoClaveApi = "X-Api-Key"
+
+// TokenBearer extrae el token del encabezado Authorization; el esquema no distingue mayúsculas
+func TokenBearer(encabezado string) string {
+	esquema, token, ok := strings.Cut(strings.TrimSpace(encabezado), " ")
+	if !ok || !strings.EqualFold(esquema, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func noAutenticado(c *fiber.Ctx, mensaje string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+	return errores.Nuevo(fiber.StatusUnauthorized, errores.TipoNoAutenticado, mensaje)
+}
@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"backend/internal/shared/errores"
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/services/permisos"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Requiere construye el middleware que exige los permisos indicados en una ruta, p. ej.
+// grupo.Post("/:idAtencion<int>", requiere("triaje:escribir"), handler.Registrar)
+type Requiere func(permisos ...string) fiber.Handler
+
+// Autorizacion retorna el constructor de middlewares de permisos. Debe usarse en rutas
+// registradas después de Autenticacion; el empleado necesita todos los permisos listados.
+func Autorizacion(servicio *permisos.PermisosServicio) Requiere {
+	return func(requeridos ...string) fiber.Handler {
+		for _, permiso := range requeridos {
+			// Un permiso mal escrito en una ruta es un error de programación
+			if err := permisos.ValidarPermiso(permiso); err != nil {
+				panic(err)
+			}
+		}
+
+		return func(c *fiber.Ctx) error {
+			empleado, ok := identidad.EmpleadoDesde(c.UserContext())
+			if !ok {
+				return noAutenticado(c, "Debe iniciar sesión para acceder a este recurso.")
+			}
+
+			conjunto, err := servicio.Efectivos(c.UserContext(), empleado)
+			if err != nil {
+				return err
+			}
+
+			faltantes := []string{}
+			for _, permiso := range requeridos {
+				if !conjunto.Tiene(permiso) {
+					faltantes = append(faltantes, permiso)
+				}
+			}
+			if len(faltantes) > 0 {
+				return errores.Nuevo(fiber.StatusForbidden, errores.TipoProhibido, "No tiene permiso para realizar esta acción.").
+					ConDetalles(fiber.Map{"permisosFaltantes": faltantes})
+			}
+
+			return c.Next()
+		}
+	}
+}
+
+// EnSuplencia exige que la solicitud se haya autenticado con un token de suplencia. La
+// usan las rutas que solo tienen sentido dentro de una suplencia, como finalizarla.
+func EnSuplencia(c *fiber.Ctx) error {
+	empleado, ok := identidad.EmpleadoDesde(c.UserContext())
+	if !ok {
+		return noAutenticado(c, "Debe iniciar sesión para acceder a este recurso.")
+	}
+	if empleado.Actor == nil {
+		return errores.Nuevo(fiber.StatusForbidden, errores.TipoProhibido, "Esta acción solo está disponible durante una suplencia.")
+	}
+	return c.Next()
+}
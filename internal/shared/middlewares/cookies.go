@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"strings"
+	"time"
+
+	"backend/internal/config"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Nombres de las cookies y del encabezado del modo cookie
+const (
+	CookieSesion   = "sihce_sesion"
+	CookieCsrf     = "sihce_csrf"
+	EncabezadoCsrf = "X-CSRF-Token"
+)
+
+// rutaCookies limita el envío de las cookies a la API
+const rutaCookies = "/api"
+
+// OpcionesCookie son los atributos de las cookies del modo cookie
+type OpcionesCookie struct {
+	Segura   bool
+	SameSite string
+}
+
+// NuevasOpcionesCookie lee los atributos de SecurityConfig. SameSite=None exige Secure
+// en los navegadores, por lo que en ese caso la cookie siempre se marca como segura.
+func NuevasOpcionesCookie(cfg config.SecurityConfig) OpcionesCookie {
+	opciones := OpcionesCookie{Segura: cfg.CookieSegura, SameSite: fiber.CookieSameSiteLaxMode}
+	switch strings.ToLower(cfg.CookieSameSite) {
+	case fiber.CookieSameSiteStrictMode:
+		opciones.SameSite = fiber.CookieSameSiteStrictMode
+	case fiber.CookieSameSiteNoneMode:
+		opciones.SameSite = fiber.CookieSameSiteNoneMode
+		opciones.Segura = true
+	}
+	return opciones
+}
+
+// EstablecerSesion envía la cookie de sesión (HttpOnly) y la cookie CSRF, que el cliente
+// debe leer y reenviar en el encabezado X-CSRF-Token en los métodos que modifican datos
+func (o OpcionesCookie) EstablecerSesion(c *fiber.Ctx, valor string, csrf string, expira time.Time) {
+	c.Cookie(o.cookie(CookieSesion, valor, expira, true))
+	c.Cookie(o.cookie(CookieCsrf, csrf, expira, false))
+}
+
+// Limpiar elimina las cookies del modo cookie en el navegador
+func (o OpcionesCookie) Limpiar(c *fiber.Ctx) {
+	c.Cookie(o.cookie(CookieSesion, "", time.Unix(0, 0), true))
+	c.Cookie(o.cookie(CookieCsrf, "", time.Unix(0, 0), false))
+}
+
+func (o OpcionesCookie) cookie(nombre string, valor string, expira time.Time, httpOnly bool) *fiber.Cookie {
+	return &fiber.Cookie{
+		Name:     nombre,
+		Value:    valor,
+		Path:     rutaCookies,
+		Expires:  expira,
+		Secure:   o.Segura,
+		HTTPOnly: httpOnly,
+		SameSite: o.SameSite,
+	}
+}
+
+// CsrfDobleEnvio verifica que el encabezado X-CSRF-Token coincida con la cookie CSRF
+func CsrfDobleEnvio(c *fiber.Ctx) bool {
+	encabezado := c.Get(EncabezadoCsrf)
+	return encabezado != "" && encabezado == c.Cookies(CookieCsrf)
+}
+
+// metodoSeguro indica los métodos que no modifican datos y no requieren token CSRF
+func metodoSeguro(metodo string) bool {
+	switch metodo {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"backend/internal/shared/estaciones"
+	"backend/internal/shared/identidad"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Estacion deja en el contexto de la solicitud la estación de trabajo del cliente, que
+// RegistrarAuditoria usa como nombrePC, resuelta por la cadena configurada: encabezado
+// del cliente, nombre DNS inverso de la IP real o la IP. También deja la IP real del
+// cliente para el control de intentos y los rangos de las claves de API.
+func Estacion(cadena *estaciones.Cadena) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		solicitud := estaciones.Solicitud{
+			Encabezado:    c.Get(cadena.Encabezado()),
+			ReenviadoPara: c.Get(fiber.HeaderXForwardedFor),
+			IpRemota:      c.IP(),
+		}
+		nombrePC := cadena.Resolver(c.UserContext(), solicitud)
+
+		ctx := identidad.ConEstacion(c.UserContext(), nombrePC)
+		if ip, ok := cadena.IpCliente(solicitud); ok {
+			ctx = identidad.ConIpCliente(ctx, ip.String())
+		}
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+// IpCliente retorna la IP real del cliente que resolvió Estacion, o la de la conexión si
+// la solicitud no pasó por ese middleware
+func IpCliente(c *fiber.Ctx) string {
+	if ip, ok := identidad.IpClienteDesde(c.UserContext()); ok {
+		return ip
+	}
+	return c.IP()
+}
@@ -0,0 +1,73 @@
+package recarga
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Archivo mantiene en memoria el contenido de un archivo de configuración (reglas
+// clínicas, políticas, etc.) y lo vuelve a cargar cuando cambia su fecha de
+// modificación, de modo que los ajustes se aplican sin reiniciar la API. Si la
+// recarga falla se conserva la última versión válida.
+type Archivo[T any] struct {
+	nombre     string
+	ruta       string
+	cargar     func(ruta string) (*T, error)
+	mu         sync.RWMutex
+	valor      *T
+	modificado time.Time
+}
+
+// Nuevo carga el archivo por primera vez; si falla, el error se reintenta en cada Obtener
+func Nuevo[T any](nombre string, ruta string, cargar func(string) (*T, error)) *Archivo[T] {
+	archivo := &Archivo[T]{nombre: nombre, ruta: ruta, cargar: cargar}
+	if _, err := archivo.Obtener(); err != nil {
+		log.Printf("[Recarga] No se pudo cargar el archivo de %s: %v", nombre, err)
+	}
+	return archivo
+}
+
+// Obtener retorna el contenido vigente, recargándolo si el archivo fue modificado
+func (a *Archivo[T]) Obtener() (*T, error) {
+	info, err := os.Stat(a.ruta)
+
+	a.mu.RLock()
+	valor, modificado := a.valor, a.modificado
+	a.mu.RUnlock()
+
+	if err != nil {
+		if valor != nil {
+			return valor, nil
+		}
+		return nil, fmt.Errorf("error al leer %s: %w", a.nombre, err)
+	}
+
+	if valor != nil && info.ModTime().Equal(modificado) {
+		return valor, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.valor != nil && info.ModTime().Equal(a.modificado) {
+		return a.valor, nil
+	}
+
+	nuevo, err := a.cargar(a.ruta)
+	if err != nil {
+		if a.valor != nil {
+			log.Printf("[Recarga] Se conserva la versión anterior de %s: %v", a.nombre, err)
+			a.modificado = info.ModTime()
+			return a.valor, nil
+		}
+		return nil, err
+	}
+
+	log.Printf("[Recarga] Archivo de %s cargado desde %s", a.nombre, a.ruta)
+	a.valor = nuevo
+	a.modificado = info.ModTime()
+	return a.valor, nil
+}
@@ -0,0 +1,107 @@
+package anemia
+
+import (
+	"math"
+
+	"backend/internal/shared/services/atenciones"
+)
+
+// Clasificaciones de anemia según la NTS N° 134-MINSA/2017
+const (
+	SinAnemia       = "Sin anemia"
+	AnemiaLeve      = "Leve"
+	AnemiaModerada  = "Moderada"
+	AnemiaSevera    = "Severa"
+	NoAplica        = "No aplica"
+	edadMinimaMeses = 6
+)
+
+// ajustesAltitud es la tabla de ajuste de hemoglobina por altitud del MINSA:
+// a partir de cada altitud (msnm) se resta el factor (g/dL) a la hemoglobina observada
+var ajustesAltitud = []struct {
+	desde  int
+	factor float64
+}{
+	{1000, 0.1}, {1100, 0.2}, {1200, 0.2}, {1300, 0.3}, {1400, 0.3},
+	{1500, 0.4}, {1600, 0.4}, {1700, 0.5}, {1800, 0.6}, {1900, 0.7},
+	{2000, 0.7}, {2100, 0.8}, {2200, 0.9}, {2300, 1.0}, {2400, 1.1},
+	{2500, 1.2}, {2600, 1.3}, {2700, 1.5}, {2800, 1.6}, {2900, 1.7},
+	{3000, 1.8}, {3100, 1.9}, {3200, 2.0}, {3300, 2.1}, {3400, 2.2},
+	{3500, 2.3}, {3600, 2.5}, {3700, 2.6}, {3800, 2.7}, {3900, 2.8},
+	{4000, 2.9}, {4100, 3.1}, {4200, 3.2}, {4300, 3.3}, {4400, 3.4},
+	{4500, 3.6}, {4600, 3.7}, {4700, 3.9}, {4800, 4.0}, {4900, 4.1},
+	{5000, 4.3},
+}
+
+// umbralesAnemia define los puntos de corte (g/dL) de cada grupo poblacional:
+// normal >= normal, leve >= leve, moderada >= moderada, severa por debajo
+type umbralesAnemia struct {
+	grupo    string
+	normal   float64
+	leve     float64
+	moderada float64
+}
+
+var (
+	umbralNinos6a59Meses = umbralesAnemia{"Niños de 6 a 59 meses", 11.0, 10.0, 7.0}
+	umbralNinos5a11Anios = umbralesAnemia{"Niños de 5 a 11 años", 11.5, 11.0, 8.0}
+	umbralAdolescentes   = umbralesAnemia{"Adolescentes de 12 a 14 años", 12.0, 11.0, 8.0}
+	umbralMujeres        = umbralesAnemia{"Mujeres no gestantes de 15 años a más", 12.0, 11.0, 8.0}
+	umbralVarones        = umbralesAnemia{"Varones de 15 años a más", 13.0, 11.0, 8.0}
+	umbralGestantes      = umbralesAnemia{"Gestantes", 11.0, 10.0, 7.0}
+)
+
+// FactorAjusteAltitud retorna los g/dL que se restan a la hemoglobina según la altitud
+func FactorAjusteAltitud(altitudMsnm int) float64 {
+	factor := 0.0
+	for _, ajuste := range ajustesAltitud {
+		if altitudMsnm < ajuste.desde {
+			break
+		}
+		factor = ajuste.factor
+	}
+	return factor
+}
+
+// AjustarHemoglobina aplica el factor de altitud redondeando a un decimal
+func AjustarHemoglobina(hemoglobina float64, altitudMsnm int) float64 {
+	return math.Round((hemoglobina-FactorAjusteAltitud(altitudMsnm))*10) / 10
+}
+
+// grupoPoblacional selecciona los umbrales según edad en meses, sexo y gestación.
+// Retorna nil en menores de 6 meses, para quienes no se establece punto de corte.
+func grupoPoblacional(edadMeses int, idTipoSexo *int, gestante bool) *umbralesAnemia {
+	switch {
+	case gestante:
+		return &umbralGestantes
+	case edadMeses < edadMinimaMeses:
+		return nil
+	case edadMeses < 60:
+		return &umbralNinos6a59Meses
+	case edadMeses < 12*12:
+		return &umbralNinos5a11Anios
+	case edadMeses < 15*12:
+		return &umbralAdolescentes
+	case idTipoSexo != nil && *idTipoSexo == atenciones.SexoMasculino:
+		return &umbralVarones
+	}
+	return &umbralMujeres
+}
+
+// Clasificar retorna la severidad de la anemia y el grupo poblacional aplicado
+func Clasificar(hemoglobinaAjustada float64, edadMeses int, idTipoSexo *int, gestante bool) (string, string) {
+	umbral := grupoPoblacional(edadMeses, idTipoSexo, gestante)
+	if umbral == nil {
+		return NoAplica, "Menores de 6 meses"
+	}
+
+	switch {
+	case hemoglobinaAjustada >= umbral.normal:
+		return SinAnemia, umbral.grupo
+	case hemoglobinaAjustada >= umbral.leve:
+		return AnemiaLeve, umbral.grupo
+	case hemoglobinaAjustada >= umbral.moderada:
+		return AnemiaModerada, umbral.grupo
+	}
+	return AnemiaSevera, umbral.grupo
+}
@@ -0,0 +1,40 @@
+package anemia
+
+const (
+	QueryGuardarTamizaje = `
+  MERGE dbo.TamizajeAnemia AS destino
+  USING (SELECT @idAtencion AS IdAtencion) AS origen
+  ON destino.IdAtencion = origen.IdAtencion
+  WHEN MATCHED THEN UPDATE SET
+    Hemoglobina = @hemoglobina,
+    AltitudMsnm = @altitud,
+    FactorAjuste = @factorAjuste,
+    HemoglobinaAjustada = @hemoglobinaAjustada,
+    Clasificacion = @clasificacion,
+    Gestante = @gestante,
+    IdUsuario = @idUsuario,
+    FechaRegistro = GETDATE()
+  WHEN NOT MATCHED THEN
+    INSERT (IdAtencion, IdPaciente, Hemoglobina, AltitudMsnm, FactorAjuste, HemoglobinaAjustada, Clasificacion, Gestante, IdUsuario)
+    VALUES (@idAtencion, @idPaciente, @hemoglobina, @altitud, @factorAjuste, @hemoglobinaAjustada, @clasificacion, @gestante, @idUsuario);`
+
+	QueryObtenerTamizaje = `
+  SELECT
+    IdPaciente,
+    Hemoglobina,
+    AltitudMsnm,
+    FactorAjuste,
+    HemoglobinaAjustada,
+    Clasificacion,
+    Gestante,
+    IdUsuario,
+    FechaRegistro
+  FROM dbo.TamizajeAnemia
+  WHERE IdAtencion = @idAtencion`
+
+	QueryObtenerUltimoTamizajePaciente = `
+  SELECT TOP 1 IdAtencion
+  FROM dbo.TamizajeAnemia
+  WHERE IdPaciente = @idPaciente
+  ORDER BY FechaRegistro DESC, IdAtencion DESC`
+)
@@ -0,0 +1,238 @@
+package anemia
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/shared/database"
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/services/atenciones"
+	"backend/internal/shared/services/auditoria"
+	"backend/internal/shared/services/elegibilidad"
+)
+
+// Tabla e item de menú con los que se audita el tamizaje de anemia
+const (
+	TablaTamizaje      = "TamizajeAnemia"
+	IdListItemTamizaje = 1304
+)
+
+// Rango aceptado para la hemoglobina observada (g/dL)
+const (
+	hemoglobinaMinima = 3.0
+	hemoglobinaMaxima = 25.0
+)
+
+var (
+	ErrSinPruebaHemoglobina = errors.New("la atención no tiene una prueba de hemoglobina despachada")
+	ErrHemoglobinaInvalida  = errors.New("el valor de hemoglobina está fuera del rango aceptado")
+	ErrGestanteInvalida     = errors.New("solo se puede indicar gestación en pacientes de sexo femenino")
+	ErrTamizajeNoRegistrado = errors.New("la atención no tiene resultado de hemoglobina registrado")
+)
+
+// ResultadoAnemia representa la hemoglobina registrada y su clasificación
+type ResultadoAnemia struct {
+	IdAtencion          int       `json:"idAtencion"`
+	IdPaciente          int       `json:"idPaciente"`
+	Hemoglobina         float64   `json:"hemoglobina"`
+	AltitudMsnm         int       `json:"altitudMsnm"`
+	FactorAjuste        float64   `json:"factorAjuste"`
+	HemoglobinaAjustada float64   `json:"hemoglobinaAjustada"`
+	Clasificacion       string    `json:"clasificacion"`
+	Grupo               string    `json:"grupo"`
+	Gestante            bool      `json:"gestante"`
+	IdUsuario           int       `json:"idUsuario"`
+	FechaRegistro       time.Time `json:"fechaRegistro"`
+}
+
+// SolicitudHemoglobina representa el resultado enviado por el laboratorio o triaje
+type SolicitudHemoglobina struct {
+	IdUsuario   int     `json:"idUsuario"`
+	Hemoglobina float64 `json:"hemoglobina"`
+	Gestante    bool    `json:"gestante"`
+}
+
+type AnemiaServicio struct {
+	db           *database.ServicioDB
+	atenciones   *atenciones.AtencionesServicio
+	auditoria    *auditoria.AuditoriaServicio
+	elegibilidad *elegibilidad.ElegibilidadServicio
+	altitudMsnm  int
+}
+
+func NuevoServicio(
+	db *database.ServicioDB,
+	atencionesServicio *atenciones.AtencionesServicio,
+	auditoriaServicio *auditoria.AuditoriaServicio,
+	elegibilidadServicio *elegibilidad.ElegibilidadServicio,
+	altitudMsnm int,
+) *AnemiaServicio {
+	return &AnemiaServicio{
+		db:           db,
+		atenciones:   atencionesServicio,
+		auditoria:    auditoriaServicio,
+		elegibilidad: elegibilidadServicio,
+		altitudMsnm:  altitudMsnm,
+	}
+}
+
+// Registrar guarda la hemoglobina de la atención ajustada por altitud.
+// Solo se acepta si la atención tiene despachada la prueba de hemoglobina y su
+// financiamiento permite acciones de laboratorio.
+func (s *AnemiaServicio) Registrar(ctx context.Context, idAtencion int, solicitud SolicitudHemoglobina) (*ResultadoAnemia, error) {
+	// El empleado autenticado prevalece sobre el idUsuario enviado en el cuerpo
+	if empleado, ok := identidad.EmpleadoDesde(ctx); ok {
+		solicitud.IdUsuario = empleado.IdEmpleado
+	}
+
+	if solicitud.Hemoglobina < hemoglobinaMinima || solicitud.Hemoglobina > hemoglobinaMaxima {
+		return nil, ErrHemoglobinaInvalida
+	}
+
+	if err := s.elegibilidad.Verificar(ctx, idAtencion, elegibilidad.AccionLaboratorio); err != nil {
+		return nil, err
+	}
+
+	info, err := s.atenciones.ObtenerInfoFacturacionAtencion(ctx, idAtencion)
+	if err != nil {
+		return nil, err
+	}
+	if !info.TieneHemoglobina {
+		return nil, ErrSinPruebaHemoglobina
+	}
+
+	paciente, err := s.atenciones.ObtenerDatosPaciente(ctx, idAtencion)
+	if err != nil {
+		return nil, err
+	}
+	if solicitud.Gestante && (paciente.IdTipoSexo == nil || *paciente.IdTipoSexo != atenciones.SexoFemenino) {
+		return nil, ErrGestanteInvalida
+	}
+
+	anterior, err := s.Obtener(ctx, idAtencion)
+	if err != nil && !errors.Is(err, ErrTamizajeNoRegistrado) {
+		return nil, err
+	}
+
+	resultado := s.calcular(paciente, info.IdPaciente, solicitud, time.Now())
+	resultado.IdAtencion = idAtencion
+
+	_, err = s.db.EjecutarExec(ctx, QueryGuardarTamizaje, false,
+		sql.Named("idAtencion", idAtencion),
+		sql.Named("idPaciente", resultado.IdPaciente),
+		sql.Named("hemoglobina", resultado.Hemoglobina),
+		sql.Named("altitud", resultado.AltitudMsnm),
+		sql.Named("factorAjuste", resultado.FactorAjuste),
+		sql.Named("hemoglobinaAjustada", resultado.HemoglobinaAjustada),
+		sql.Named("clasificacion", resultado.Clasificacion),
+		sql.Named("gestante", resultado.Gestante),
+		sql.Named("idUsuario", resultado.IdUsuario),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error al registrar hemoglobina: %w", err)
+	}
+
+	accion := auditoria.AccionAgregar
+	observaciones := fmt.Sprintf("Hemoglobina %.1f g/dL, ajustada %.1f g/dL: %s", resultado.Hemoglobina, resultado.HemoglobinaAjustada, resultado.Clasificacion)
+	if anterior != nil {
+		accion = auditoria.AccionModificar
+		observaciones = auditoria.ObservacionesModificacion(observaciones, anterior, resultado, "fechaRegistro")
+	}
+	// El tamizaje ya quedó guardado: un fallo de la auditoría queda en el log y en las
+	// métricas, pues un reintento del cliente se auditaría como una modificación
+	if err := s.auditoria.RegistrarAuditoria(
+		ctx, accion, idAtencion, TablaTamizaje, IdListItemTamizaje, observaciones,
+	); err != nil {
+		log.Printf("[Anemia] No se auditó el tamizaje de la atención %d: %v", idAtencion, err)
+	}
+
+	return resultado, nil
+}
+
+// Obtener retorna el resultado de hemoglobina registrado para la atención
+func (s *AnemiaServicio) Obtener(ctx context.Context, idAtencion int) (*ResultadoAnemia, error) {
+	row := s.db.EjecutarQueryRow(ctx, QueryObtenerTamizaje, false, sql.Named("idAtencion", idAtencion))
+	if row == nil {
+		return nil, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	resultado := ResultadoAnemia{IdAtencion: idAtencion}
+	err := row.Scan(
+		&resultado.IdPaciente,
+		&resultado.Hemoglobina,
+		&resultado.AltitudMsnm,
+		&resultado.FactorAjuste,
+		&resultado.HemoglobinaAjustada,
+		&resultado.Clasificacion,
+		&resultado.Gestante,
+		&resultado.IdUsuario,
+		&resultado.FechaRegistro,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrTamizajeNoRegistrado
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// El grupo poblacional no se almacena; se recalcula con los datos actuales del paciente
+	if paciente, err := s.atenciones.ObtenerDatosPaciente(ctx, idAtencion); err == nil {
+		edadMeses := edadEnMeses(paciente, resultado.FechaRegistro)
+		_, resultado.Grupo = Clasificar(resultado.HemoglobinaAjustada, edadMeses, paciente.IdTipoSexo, resultado.Gestante)
+	}
+
+	return &resultado, nil
+}
+
+// ObtenerUltimoPorPaciente retorna el resultado de hemoglobina más reciente del paciente
+// en cualquiera de sus atenciones
+func (s *AnemiaServicio) ObtenerUltimoPorPaciente(ctx context.Context, idPaciente int) (*ResultadoAnemia, error) {
+	row := s.db.EjecutarQueryRow(ctx, QueryObtenerUltimoTamizajePaciente, false, sql.Named("idPaciente", idPaciente))
+	if row == nil {
+		return nil, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	var idAtencion int
+	err := row.Scan(&idAtencion)
+	if err == sql.ErrNoRows {
+		return nil, ErrTamizajeNoRegistrado
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Obtener(ctx, idAtencion)
+}
+
+func (s *AnemiaServicio) calcular(paciente *atenciones.DatosPaciente, idPaciente int, solicitud SolicitudHemoglobina, fecha time.Time) *ResultadoAnemia {
+	resultado := &ResultadoAnemia{
+		IdPaciente:          idPaciente,
+		Hemoglobina:         solicitud.Hemoglobina,
+		AltitudMsnm:         s.altitudMsnm,
+		FactorAjuste:        FactorAjusteAltitud(s.altitudMsnm),
+		HemoglobinaAjustada: AjustarHemoglobina(solicitud.Hemoglobina, s.altitudMsnm),
+		Gestante:            solicitud.Gestante,
+		IdUsuario:           solicitud.IdUsuario,
+		FechaRegistro:       fecha,
+	}
+
+	edadMeses := edadEnMeses(paciente, fecha)
+	resultado.Clasificacion, resultado.Grupo = Clasificar(resultado.HemoglobinaAjustada, edadMeses, paciente.IdTipoSexo, solicitud.Gestante)
+	return resultado
+}
+
+// edadEnMeses usa la fecha de nacimiento y, si no existe, la edad en años registrada en la atención
+func edadEnMeses(paciente *atenciones.DatosPaciente, fecha time.Time) int {
+	if meses := paciente.EdadEnMeses(fecha); meses != nil {
+		return *meses
+	}
+	if paciente.EdadPaciente != nil {
+		return *paciente.EdadPaciente * 12
+	}
+	return 0
+}
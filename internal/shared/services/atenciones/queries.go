@@ -27,7 +27,9 @@ const (
     pa.NroHistoriaClinica,
     e.ApellidoPaterno + ' ' + isnull(e.ApellidoMaterno, '') + ' ' + e.Nombres AS NombreMedico,
     s.IdServicio,
-    s.Nombre AS nombreServicio
+    s.Nombre AS nombreServicio,
+    pa.FechaNacimiento,
+    pa.IdTipoSexo
   FROM Atenciones a
   INNER JOIN Citas c ON a.IdAtencion = c.IdAtencion
   INNER JOIN Pacientes pa ON c.IdPaciente = pa.IdPaciente
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"backend/internal/shared/database"
 )
@@ -18,11 +19,26 @@ type InfoFacturacionAtencion struct {
 }
 
 type DatosPaciente struct {
-	EdadPaciente       *int
-	NroHistoriaClinica *float64
-	NombreMedico       *string
-	IdServicio         *int
-	NombreServicio     *string
+	EdadPaciente       *int       `json:"edadPaciente"`
+	NroHistoriaClinica *float64   `json:"nroHistoriaClinica"`
+	NombreMedico       *string    `json:"nombreMedico"`
+	IdServicio         *int       `json:"idServicio"`
+	NombreServicio     *string    `json:"nombreServicio"`
+	FechaNacimiento    *time.Time `json:"fechaNacimiento"`
+	IdTipoSexo         *int       `json:"idTipoSexo"`
+}
+
+// Valores de IdTipoSexo en SIGH
+const (
+	SexoMasculino = 1
+	SexoFemenino  = 2
+)
+
+// EdadDetallada representa la edad calendario del paciente en años, meses y días
+type EdadDetallada struct {
+	Anios int `json:"anios"`
+	Meses int `json:"meses"`
+	Dias  int `json:"dias"`
 }
 
 type AtencionesServicio struct {
@@ -72,6 +88,8 @@ func (s *AtencionesServicio) ObtenerDatosPaciente(ctx context.Context, idAtencio
 		&datos.NombreMedico,
 		&datos.IdServicio,
 		&datos.NombreServicio,
+		&datos.FechaNacimiento,
+		&datos.IdTipoSexo,
 	)
 
 	if err == sql.ErrNoRows {
@@ -83,3 +101,62 @@ func (s *AtencionesServicio) ObtenerDatosPaciente(ctx context.Context, idAtencio
 
 	return &datos, nil
 }
+
+// EdadEnDias retorna los días cumplidos a la fecha de referencia.
+// Retorna nil si el paciente no tiene fecha de nacimiento registrada.
+func (d *DatosPaciente) EdadEnDias(referencia time.Time) *int {
+	if d.FechaNacimiento == nil {
+		return nil
+	}
+
+	nacimiento := truncarFecha(*d.FechaNacimiento)
+	dias := int(truncarFecha(referencia).Sub(nacimiento).Hours() / 24)
+	if dias < 0 {
+		return nil
+	}
+	return &dias
+}
+
+// EdadEnMeses retorna los meses calendario cumplidos a la fecha de referencia
+func (d *DatosPaciente) EdadEnMeses(referencia time.Time) *int {
+	edad := d.EdadCalendario(referencia)
+	if edad == nil {
+		return nil
+	}
+
+	meses := edad.Anios*12 + edad.Meses
+	return &meses
+}
+
+// EdadCalendario retorna la edad en años, meses y días a la fecha de referencia
+func (d *DatosPaciente) EdadCalendario(referencia time.Time) *EdadDetallada {
+	if d.FechaNacimiento == nil {
+		return nil
+	}
+
+	nacimiento := truncarFecha(*d.FechaNacimiento)
+	referencia = truncarFecha(referencia)
+	if referencia.Before(nacimiento) {
+		return nil
+	}
+
+	anios := referencia.Year() - nacimiento.Year()
+	meses := int(referencia.Month()) - int(nacimiento.Month())
+	dias := referencia.Day() - nacimiento.Day()
+
+	if dias < 0 {
+		meses--
+		// Días del mes anterior a la fecha de referencia
+		dias += time.Date(referencia.Year(), referencia.Month(), 0, 0, 0, 0, 0, time.UTC).Day()
+	}
+	if meses < 0 {
+		anios--
+		meses += 12
+	}
+
+	return &EdadDetallada{Anios: anios, Meses: meses, Dias: dias}
+}
+
+func truncarFecha(fecha time.Time) time.Time {
+	return time.Date(fecha.Year(), fecha.Month(), fecha.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,453 @@
+package auditoria
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Valores por defecto cuando config.yml no define las observaciones de auditoría
+const (
+	// LongitudObservacionesPorDefecto es el tamaño de la columna observaciones de Auditoria
+	LongitudObservacionesPorDefecto = 500
+	// Enmascarado reemplaza el valor de los campos sensibles; solo indica que cambiaron
+	Enmascarado = "***"
+)
+
+const (
+	// prefijoCambios identifica las observaciones que LeerCambios sabe interpretar
+	prefijoCambios = "cambios:"
+	// reservaPrefijos deja lugar a los prefijos de suplencia y envío diferido que se
+	// anteponen a las observaciones al registrar
+	reservaPrefijos = 80
+	// longitudValorRecortado es lo que se conserva de cada valor largo al recortar
+	longitudValorRecortado = 40
+	// longitudResumenRecortado es lo que se conserva del resumen al recortar
+	longitudResumenRecortado = 120
+	// marcaRecorte termina los textos recortados
+	marcaRecorte = "…"
+)
+
+// camposSensiblesPorDefecto se enmascaran siempre, además de los de config.yml
+var camposSensiblesPorDefecto = []string{"clave", "contrasena", "password", "secreto", "token"}
+
+// ConfigCambios define las observaciones con diferencias; los valores en cero toman los
+// por defecto
+type ConfigCambios struct {
+	// Longitud es el tamaño de la columna observaciones
+	Longitud int
+	// Sensibles son los campos cuyos valores se enmascaran, por nombre JSON ("dni") o por
+	// ruta ("paciente.dni"); se suman a los por defecto. Un objeto sensible enmascara todo
+	// su contenido, y dentro de las listas se enmascaran los campos de cada elemento.
+	Sensibles []string
+}
+
+// configCambios es la configuración que usa ObservacionesCambios; nil usa la por defecto
+var configCambios atomic.Pointer[ConfigCambios]
+
+// ConfigurarCambios establece la longitud de la columna y los campos sensibles
+func ConfigurarCambios(cfg ConfigCambios) {
+	if cfg.Longitud <= 0 {
+		cfg.Longitud = LongitudObservacionesPorDefecto
+	}
+	cfg.Sensibles = normalizarCampos(append(append([]string{}, camposSensiblesPorDefecto...), cfg.Sensibles...))
+	configCambios.Store(&cfg)
+}
+
+func obtenerConfigCambios() *ConfigCambios {
+	if cfg := configCambios.Load(); cfg != nil {
+		return cfg
+	}
+	return &ConfigCambios{Longitud: LongitudObservacionesPorDefecto, Sensibles: camposSensiblesPorDefecto}
+}
+
+// Cambio es un campo que difiere entre dos versiones de un registro
+type Cambio struct {
+	Campo    string      `json:"campo"`
+	Anterior interface{} `json:"anterior"`
+	Nuevo    interface{} `json:"nuevo"`
+}
+
+// Diferencias compara dos versiones de un registro de dominio (struct o puntero a struct)
+// campo a campo. Los campos se nombran como en su JSON y las estructuras anidadas se
+// aplanan con "." (signosVitales.temperatura); las listas se comparan completas. Los
+// cambios siguen el orden de los campos de nuevo. anterior puede ser nil, p. ej. al
+// agregar. Los campos de ignorar (p. ej. fechas de registro) no se comparan, y los
+// sensibles se enmascaran también dentro de los objetos y listas.
+func Diferencias(anterior, nuevo interface{}, ignorar ...string) ([]Cambio, error) {
+	valoresAnterior, ordenAnterior, err := aplanar(anterior)
+	if err != nil {
+		return nil, fmt.Errorf("error al comparar la versión anterior: %w", err)
+	}
+	valoresNuevo, ordenNuevo, err := aplanar(nuevo)
+	if err != nil {
+		return nil, fmt.Errorf("error al comparar la versión nueva: %w", err)
+	}
+
+	ignorar = normalizarCampos(ignorar)
+	sensibles := obtenerConfigCambios().Sensibles
+
+	cambios := []Cambio{}
+	vistos := make(map[string]bool, len(ordenNuevo))
+	for _, campo := range append(ordenNuevo, ordenAnterior...) {
+		if vistos[campo] {
+			continue
+		}
+		vistos[campo] = true
+
+		if coincide(ignorar, campo) {
+			continue
+		}
+		valorAnterior, valorNuevo := valoresAnterior[campo], valoresNuevo[campo]
+		if valoresIguales(valorAnterior, valorNuevo) {
+			continue
+		}
+
+		valorAnterior, valorNuevo = enmascararSensibles(sensibles, campo, valorAnterior), enmascararSensibles(sensibles, campo, valorNuevo)
+		cambios = append(cambios, Cambio{Campo: campo, Anterior: valorAnterior, Nuevo: valorNuevo})
+	}
+	return cambios, nil
+}
+
+// ObservacionesModificacion compara las versiones y serializa el resumen y los cambios
+// para el registro de AccionModificar. Si no se pueden comparar, retorna solo el resumen.
+func ObservacionesModificacion(resumen string, anterior, nuevo interface{}, ignorar ...string) string {
+	cambios, err := Diferencias(anterior, nuevo, ignorar...)
+	if err != nil {
+		return resumen
+	}
+	return ObservacionesCambios(resumen, cambios)
+}
+
+// registroCompacto es el formato de las observaciones con diferencias:
+// cambios:{"r":"resumen","c":[["campo",anterior,nuevo],...],"o":2,"t":true}
+type registroCompacto struct {
+	Resumen  string           `json:"r,omitempty"`
+	Cambios  [][3]interface{} `json:"c"`
+	Omitidos int              `json:"o,omitempty"`
+	Truncado bool             `json:"t,omitempty"`
+}
+
+// ObservacionesCambios serializa el resumen y los cambios en forma compacta para la
+// columna observaciones. Si no caben, primero recorta el resumen y los valores largos y
+// luego omite los últimos cambios; el resultado sigue siendo legible por LeerCambios,
+// que informa cuántos cambios se omitieron.
+func ObservacionesCambios(resumen string, cambios []Cambio) string {
+	limite := max(obtenerConfigCambios().Longitud-reservaPrefijos, longitudValorRecortado*2)
+
+	registro := registroCompacto{Resumen: resumen, Cambios: make([][3]interface{}, 0, len(cambios))}
+	for _, cambio := range cambios {
+		registro.Cambios = append(registro.Cambios, [3]interface{}{cambio.Campo, cambio.Anterior, cambio.Nuevo})
+	}
+
+	texto := serializarCambios(registro)
+	if utf8.RuneCountInString(texto) <= limite {
+		return texto
+	}
+
+	registro.Truncado = true
+	registro.Resumen = recortarTexto(registro.Resumen, longitudResumenRecortado)
+	for i, cambio := range registro.Cambios {
+		registro.Cambios[i] = [3]interface{}{cambio[0], recortarValor(cambio[1]), recortarValor(cambio[2])}
+	}
+	texto = serializarCambios(registro)
+
+	for utf8.RuneCountInString(texto) > limite && len(registro.Cambios) > 0 {
+		registro.Cambios = registro.Cambios[:len(registro.Cambios)-1]
+		registro.Omitidos++
+		texto = serializarCambios(registro)
+	}
+
+	for exceso := utf8.RuneCountInString(texto) - limite; exceso > 0 && registro.Resumen != ""; exceso = utf8.RuneCountInString(texto) - limite {
+		longitud := utf8.RuneCountInString(registro.Resumen) - exceso - utf8.RuneCountInString(marcaRecorte)
+		if longitud <= 0 {
+			registro.Resumen = ""
+		} else {
+			registro.Resumen = recortarTexto(registro.Resumen, longitud)
+		}
+		texto = serializarCambios(registro)
+	}
+	return texto
+}
+
+func serializarCambios(registro registroCompacto) string {
+	var contenido bytes.Buffer
+	codificador := json.NewEncoder(&contenido)
+	codificador.SetEscapeHTML(false)
+	if err := codificador.Encode(registro); err != nil {
+		return registro.Resumen
+	}
+	return prefijoCambios + strings.TrimSpace(contenido.String())
+}
+
+// RegistroCambios son unas observaciones generadas por ObservacionesCambios, leídas para
+// mostrarlas en las consultas de auditoría
+type RegistroCambios struct {
+	// Prefijo es el texto antepuesto al registrar, p. ej. la suplencia o el envío diferido
+	Prefijo string   `json:"prefijo,omitempty"`
+	Resumen string   `json:"resumen"`
+	Cambios []Cambio `json:"cambios"`
+	// Omitidos es la cantidad de cambios que no cupieron en la columna
+	Omitidos int `json:"omitidos"`
+	// Truncado indica que se omitieron cambios o se recortaron valores (terminan en "…")
+	Truncado bool `json:"truncado"`
+}
+
+// LeerCambios interpreta las observaciones generadas por ObservacionesCambios. Retorna
+// false si son texto libre o si no se pueden leer, p. ej. porque otro sistema las recortó.
+func LeerCambios(observaciones string) (*RegistroCambios, bool) {
+	inicio := strings.Index(observaciones, prefijoCambios+"{")
+	if inicio < 0 {
+		return nil, false
+	}
+
+	var compacto struct {
+		Resumen  string            `json:"r"`
+		Cambios  []json.RawMessage `json:"c"`
+		Omitidos int               `json:"o"`
+		Truncado bool              `json:"t"`
+	}
+	if err := decodificar([]byte(observaciones[inicio+len(prefijoCambios):]), &compacto); err != nil {
+		return nil, false
+	}
+
+	registro := &RegistroCambios{
+		Prefijo:  strings.TrimSpace(observaciones[:inicio]),
+		Resumen:  compacto.Resumen,
+		Cambios:  make([]Cambio, 0, len(compacto.Cambios)),
+		Omitidos: compacto.Omitidos,
+		Truncado: compacto.Truncado || compacto.Omitidos > 0,
+	}
+	for _, crudo := range compacto.Cambios {
+		var cambio []interface{}
+		if err := decodificar(crudo, &cambio); err != nil || len(cambio) != 3 {
+			return nil, false
+		}
+		campo, ok := cambio[0].(string)
+		if !ok {
+			return nil, false
+		}
+		registro.Cambios = append(registro.Cambios, Cambio{Campo: campo, Anterior: cambio[1], Nuevo: cambio[2]})
+	}
+	return registro, true
+}
+
+// decodificar conserva los números tal como se registraron
+func decodificar(datos []byte, destino interface{}) error {
+	decodificador := json.NewDecoder(bytes.NewReader(datos))
+	decodificador.UseNumber()
+	return decodificador.Decode(destino)
+}
+
+// aplanar serializa el registro a JSON y retorna sus valores por ruta, en el orden de los
+// campos
+func aplanar(registro interface{}) (map[string]interface{}, []string, error) {
+	datos, err := json.Marshal(registro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decodificador := json.NewDecoder(bytes.NewReader(datos))
+	decodificador.UseNumber()
+	token, err := decodificador.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plano := &registroPlano{valores: map[string]interface{}{}}
+	if err := plano.leer(decodificador, token, ""); err != nil {
+		return nil, nil, err
+	}
+	return plano.valores, plano.orden, nil
+}
+
+type registroPlano struct {
+	valores map[string]interface{}
+	orden   []string
+}
+
+// leer recorre los objetos anidados; cualquier otro valor se guarda completo en su ruta
+func (p *registroPlano) leer(decodificador *json.Decoder, token json.Token, ruta string) error {
+	if delimitador, ok := token.(json.Delim); ok && delimitador == '{' {
+		for decodificador.More() {
+			clave, err := decodificador.Token()
+			if err != nil {
+				return err
+			}
+			valor, err := decodificador.Token()
+			if err != nil {
+				return err
+			}
+			campo := clave.(string)
+			if ruta != "" {
+				campo = ruta + "." + campo
+			}
+			if err := p.leer(decodificador, valor, campo); err != nil {
+				return err
+			}
+		}
+		_, err := decodificador.Token()
+		return err
+	}
+
+	valor, err := valorJSON(decodificador, token)
+	if err != nil || ruta == "" {
+		return err
+	}
+	p.valores[ruta] = valor
+	p.orden = append(p.orden, ruta)
+	return nil
+}
+
+// valorJSON lee el valor completo que empieza con token
+func valorJSON(decodificador *json.Decoder, token json.Token) (interface{}, error) {
+	delimitador, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delimitador {
+	case '[':
+		lista := []interface{}{}
+		for decodificador.More() {
+			elemento, err := decodificador.Token()
+			if err != nil {
+				return nil, err
+			}
+			valor, err := valorJSON(decodificador, elemento)
+			if err != nil {
+				return nil, err
+			}
+			lista = append(lista, valor)
+		}
+		_, err := decodificador.Token()
+		return lista, err
+	case '{':
+		objeto := map[string]interface{}{}
+		for decodificador.More() {
+			clave, err := decodificador.Token()
+			if err != nil {
+				return nil, err
+			}
+			elemento, err := decodificador.Token()
+			if err != nil {
+				return nil, err
+			}
+			valor, err := valorJSON(decodificador, elemento)
+			if err != nil {
+				return nil, err
+			}
+			objeto[clave.(string)] = valor
+		}
+		_, err := decodificador.Token()
+		return objeto, err
+	}
+	return nil, fmt.Errorf("delimitador JSON inesperado %v", delimitador)
+}
+
+// valoresIguales compara por su JSON; un campo ausente equivale a null
+func valoresIguales(a, b interface{}) bool {
+	datosA, errA := json.Marshal(a)
+	datosB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(datosA, datosB)
+}
+
+// enmascarar oculta el valor pero conserva si el campo quedó vacío
+func enmascarar(valor interface{}) interface{} {
+	if valor == nil {
+		return nil
+	}
+	return Enmascarado
+}
+
+// enmascararSensibles enmascara el valor si su ruta es sensible y, si no, los campos
+// sensibles de sus objetos y listas anidados; los elementos de una lista comparten la ruta
+// de la lista. Retorna una copia para no alterar el registro comparado.
+func enmascararSensibles(sensibles []string, ruta string, valor interface{}) interface{} {
+	if sensible(sensibles, ruta) {
+		return enmascarar(valor)
+	}
+
+	switch v := valor.(type) {
+	case map[string]interface{}:
+		objeto := make(map[string]interface{}, len(v))
+		for clave, elemento := range v {
+			objeto[clave] = enmascararSensibles(sensibles, ruta+"."+clave, elemento)
+		}
+		return objeto
+	case []interface{}:
+		lista := make([]interface{}, len(v))
+		for i, elemento := range v {
+			lista[i] = enmascararSensibles(sensibles, ruta, elemento)
+		}
+		return lista
+	}
+	return valor
+}
+
+// recortarValor acorta los textos largos; las listas y objetos largos se reemplazan por
+// el inicio de su JSON
+func recortarValor(valor interface{}) interface{} {
+	switch v := valor.(type) {
+	case nil, bool, json.Number:
+		return valor
+	case string:
+		return recortarTexto(v, longitudValorRecortado)
+	}
+
+	datos, err := json.Marshal(valor)
+	if err != nil || utf8.RuneCount(datos) <= longitudValorRecortado {
+		return valor
+	}
+	return recortarTexto(string(datos), longitudValorRecortado)
+}
+
+func recortarTexto(texto string, longitud int) string {
+	runas := []rune(texto)
+	if len(runas) <= longitud {
+		return texto
+	}
+	return string(runas[:longitud]) + marcaRecorte
+}
+
+// coincide indica si la ruta o su último nombre están en campos
+func coincide(campos []string, ruta string) bool {
+	ruta = strings.ToLower(ruta)
+	hoja := ruta[strings.LastIndex(ruta, ".")+1:]
+	for _, campo := range campos {
+		if campo == ruta || campo == hoja {
+			return true
+		}
+	}
+	return false
+}
+
+// sensible indica si la ruta, o alguna de sus rutas superiores, está en campos por ruta
+// completa o por nombre: con "paciente" configurado, "paciente.dni" también es sensible
+func sensible(campos []string, ruta string) bool {
+	ruta = strings.ToLower(ruta)
+	for _, campo := range campos {
+		if campo == ruta || strings.HasPrefix(ruta, campo+".") {
+			return true
+		}
+		for _, nombre := range strings.Split(ruta, ".") {
+			if campo == nombre {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func normalizarCampos(campos []string) []string {
+	normalizados := make([]string, 0, len(campos))
+	for _, campo := range campos {
+		if campo = strings.ToLower(strings.TrimSpace(campo)); campo != "" {
+			normalizados = append(normalizados, campo)
+		}
+	}
+	return normalizados
+}
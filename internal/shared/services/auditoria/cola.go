@@ -0,0 +1,333 @@
+package auditoria
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/shared/database"
+)
+
+// Valores por defecto cuando config.yml no define la sección auditoria
+const (
+	capacidadPorDefecto    = 1000
+	trabajadoresPorDefecto = 2
+	lotePorDefecto         = 50
+	SpoolPorDefecto        = "auditoria_spool.jsonl"
+)
+
+const (
+	// esperaLote es lo máximo que un registro espera a que se complete su lote
+	esperaLote = time.Second
+	// intervaloReproduccion es cada cuánto se intenta reenviar el spool
+	intervaloReproduccion = 15 * time.Second
+	// plazoEscritura limita cada envío de un lote a la base de datos
+	plazoEscritura = 10 * time.Second
+	// retrasoAnotado es el retraso a partir del cual observaciones indica la hora real de
+	// la acción, pues AuditoriaAgregarV registra la hora de su ejecución
+	retrasoAnotado = time.Minute
+)
+
+// colaActiva es la cola que usa RegistrarAuditoria; nil registra de forma síncrona
+var colaActiva atomic.Pointer[ColaAuditoria]
+
+// Entrada es un registro de auditoría pendiente de enviar a AuditoriaAgregarV
+type Entrada struct {
+	IdEmpleado    int       `json:"idEmpleado"`
+	Accion        string    `json:"accion"`
+	IdRegistro    int       `json:"idRegistro"`
+	Tabla         string    `json:"tabla"`
+	IdListItem    int       `json:"idListItem"`
+	NombrePC      string    `json:"nombrePC"`
+	Observaciones string    `json:"observaciones"`
+	Fecha         time.Time `json:"fecha"`
+}
+
+// ConfigCola define la cola de auditoría; los valores en cero toman los por defecto
+type ConfigCola struct {
+	Capacidad    int
+	Trabajadores int
+	Lote         int
+	Spool        string
+}
+
+// ColaAuditoria saca la auditoría del camino de las solicitudes: los registros se
+// encolan en memoria y varios trabajadores los envían por lotes a AuditoriaAgregarV.
+// Si la base de datos principal no responde, los lotes se guardan en el spool y se
+// reenvían en orden al recuperarse; mientras el spool tenga pendientes, los registros
+// nuevos se anexan detrás para conservar el orden.
+type ColaAuditoria struct {
+	db    *database.ServicioDB
+	lote  int
+	spool *spool
+
+	mu       sync.RWMutex
+	entradas chan Entrada
+	cerrada  bool
+
+	soloSpool    atomic.Bool
+	trabajadores sync.WaitGroup
+	detener      chan struct{}
+	reproductor  sync.WaitGroup
+}
+
+// IniciarCola crea la cola, inicia sus trabajadores y la activa para RegistrarAuditoria
+func IniciarCola(db *database.ServicioDB, cfg ConfigCola) (*ColaAuditoria, error) {
+	if cfg.Capacidad <= 0 {
+		cfg.Capacidad = capacidadPorDefecto
+	}
+	if cfg.Trabajadores <= 0 {
+		cfg.Trabajadores = trabajadoresPorDefecto
+	}
+	if cfg.Lote <= 0 {
+		cfg.Lote = lotePorDefecto
+	}
+	if cfg.Spool == "" {
+		cfg.Spool = SpoolPorDefecto
+	}
+
+	archivo, err := abrirSpool(cfg.Spool)
+	if err != nil {
+		return nil, err
+	}
+
+	cola := &ColaAuditoria{
+		db:       db,
+		lote:     cfg.Lote,
+		spool:    archivo,
+		entradas: make(chan Entrada, cfg.Capacidad),
+		detener:  make(chan struct{}),
+	}
+
+	cola.trabajadores.Add(cfg.Trabajadores)
+	for range cfg.Trabajadores {
+		go cola.trabajar()
+	}
+	cola.reproductor.Add(1)
+	go cola.reproducirSpool()
+
+	colaActiva.Store(cola)
+	return cola, nil
+}
+
+// Encolar agrega el registro sin esperar a la base de datos. Si la cola está llena o ya
+// se cerró, el registro va directamente al spool.
+func (c *ColaAuditoria) Encolar(entrada Entrada) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.cerrada {
+		select {
+		case c.entradas <- entrada:
+			return nil
+		default:
+		}
+	}
+
+	if err := c.spool.agregar([]Entrada{entrada}); err != nil {
+		ContarFallo()
+		return err
+	}
+	return nil
+}
+
+// Cerrar deja de aceptar registros y envía los encolados. Si ctx vence antes, lo que
+// queda se guarda en el spool para enviarlo en el siguiente arranque.
+func (c *ColaAuditoria) Cerrar(ctx context.Context) error {
+	c.mu.Lock()
+	if c.cerrada {
+		c.mu.Unlock()
+		return nil
+	}
+	c.cerrada = true
+	close(c.entradas)
+	c.mu.Unlock()
+
+	close(c.detener)
+	c.reproductor.Wait()
+
+	terminado := make(chan struct{})
+	go func() {
+		c.trabajadores.Wait()
+		close(terminado)
+	}()
+
+	select {
+	case <-terminado:
+	case <-ctx.Done():
+		log.Println("[Auditoria] Plazo de apagado vencido; los registros en cola se guardan en el spool")
+		c.soloSpool.Store(true)
+		<-terminado
+	}
+
+	if pendientes := c.spool.cantidad(); pendientes > 0 {
+		log.Printf("[Auditoria] Quedan %d registros en el spool %s para el siguiente arranque", pendientes, c.spool.ruta)
+	}
+	return nil
+}
+
+// trabajar arma lotes de hasta c.lote registros, o los que haya tras esperaLote
+func (c *ColaAuditoria) trabajar() {
+	defer c.trabajadores.Done()
+
+	temporizador := time.NewTicker(esperaLote)
+	defer temporizador.Stop()
+
+	lote := make([]Entrada, 0, c.lote)
+	for {
+		select {
+		case entrada, ok := <-c.entradas:
+			if !ok {
+				c.enviar(lote)
+				return
+			}
+			lote = append(lote, entrada)
+			if len(lote) < c.lote {
+				continue
+			}
+		case <-temporizador.C:
+			if len(lote) == 0 {
+				continue
+			}
+		}
+
+		c.enviar(lote)
+		lote = lote[:0]
+	}
+}
+
+// enviar escribe el lote en la base de datos, o en el spool si tiene pendientes o la
+// base de datos principal no responde
+func (c *ColaAuditoria) enviar(lote []Entrada) {
+	if len(lote) == 0 {
+		return
+	}
+
+	if !c.soloSpool.Load() && c.spool.cantidad() == 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), plazoEscritura)
+		err := c.escribirLote(ctx, lote)
+		cancel()
+		if err == nil {
+			return
+		}
+		log.Printf("[Auditoria] Base de datos principal no disponible; %d registros al spool: %v", len(lote), err)
+	}
+
+	if err := c.spool.agregar(lote); err != nil {
+		fallidas.Add(int64(len(lote)))
+		ultimoFallo.Store(time.Now().Unix())
+		log.Printf("[Auditoria] Se perdieron %d registros de auditoría: %v", len(lote), err)
+	}
+}
+
+// escribirLote envía el lote en una sola transacción. Solo retorna error si la base de
+// datos principal no responde; si responde, el lote se reintenta registro por registro
+// y los que el SP rechaza se descartan y cuentan como fallos.
+func (c *ColaAuditoria) escribirLote(ctx context.Context, lote []Entrada) error {
+	err := c.escribir(ctx, lote)
+	if err == nil {
+		registradas.Add(int64(len(lote)))
+		return nil
+	}
+	if !c.disponible(ctx) {
+		return err
+	}
+
+	for _, entrada := range lote {
+		if err := c.escribir(ctx, []Entrada{entrada}); err != nil {
+			ContarFallo()
+			log.Printf("[Auditoria] Se descarta el registro de %s %d: %v", entrada.Tabla, entrada.IdRegistro, err)
+			continue
+		}
+		registradas.Add(1)
+	}
+	return nil
+}
+
+func (c *ColaAuditoria) escribir(ctx context.Context, lote []Entrada) error {
+	var sentencia strings.Builder
+	sentencia.WriteString("SET XACT_ABORT ON; BEGIN TRANSACTION;")
+
+	args := make([]interface{}, 0, len(lote)*7)
+	for i, entrada := range lote {
+		fmt.Fprintf(&sentencia,
+			" EXEC AuditoriaAgregarV @IdEmpleado%[1]d, @Accion%[1]d, @IdRegistro%[1]d, @Tabla%[1]d, @idListItem%[1]d, @nombrePC%[1]d, @observaciones%[1]d;", i)
+		args = append(args,
+			sql.Named(fmt.Sprintf("IdEmpleado%d", i), entrada.IdEmpleado),
+			sql.Named(fmt.Sprintf("Accion%d", i), entrada.Accion),
+			sql.Named(fmt.Sprintf("IdRegistro%d", i), entrada.IdRegistro),
+			sql.Named(fmt.Sprintf("Tabla%d", i), entrada.Tabla),
+			sql.Named(fmt.Sprintf("idListItem%d", i), entrada.IdListItem),
+			sql.Named(fmt.Sprintf("nombrePC%d", i), entrada.NombrePC),
+			sql.Named(fmt.Sprintf("observaciones%d", i), observacionesDiferidas(entrada)),
+		)
+	}
+	sentencia.WriteString(" COMMIT TRANSACTION;")
+
+	_, err := c.db.EjecutarExec(ctx, sentencia.String(), false, args...)
+	return err
+}
+
+// observacionesDiferidas antepone la hora real de la acción a los registros enviados
+// con retraso (p. ej. desde el spool)
+func observacionesDiferidas(entrada Entrada) string {
+	if entrada.Fecha.IsZero() || time.Since(entrada.Fecha) < retrasoAnotado {
+		return entrada.Observaciones
+	}
+	return fmt.Sprintf("[Diferido %s] %s", entrada.Fecha.Format("2006-01-02 15:04:05"), entrada.Observaciones)
+}
+
+// disponible verifica que la base de datos principal responda
+func (c *ColaAuditoria) disponible(ctx context.Context) bool {
+	db, err := c.db.ObtenerConexion(false)
+	return err == nil && db.PingContext(ctx) == nil
+}
+
+// reproducirSpool reenvía periódicamente el spool cuando la base de datos responde
+func (c *ColaAuditoria) reproducirSpool() {
+	defer c.reproductor.Done()
+
+	temporizador := time.NewTicker(intervaloReproduccion)
+	defer temporizador.Stop()
+
+	for {
+		select {
+		case <-c.detener:
+			return
+		case <-temporizador.C:
+		}
+
+		pendientes := c.spool.cantidad()
+		if pendientes == 0 {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), plazoEscritura)
+		disponible := c.disponible(ctx)
+		cancel()
+		if !disponible {
+			continue
+		}
+
+		err := c.spool.reproducir(c.lote, func(lote []Entrada) error {
+			select {
+			case <-c.detener:
+				return fmt.Errorf("la cola de auditoría se está cerrando")
+			default:
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), plazoEscritura)
+			defer cancel()
+			return c.escribirLote(ctx, lote)
+		})
+		if err != nil {
+			log.Printf("[Auditoria] Se interrumpió el reenvío del spool: %v", err)
+			continue
+		}
+		log.Printf("[Auditoria] Se reenviaron %d registros del spool", pendientes)
+	}
+}
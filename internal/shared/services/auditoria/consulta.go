@@ -0,0 +1,159 @@
+package auditoria
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Límites de la paginación de la consulta de auditoría
+const (
+	TamanioPaginaPorDefecto = 50
+	tamanioPaginaMaximo     = 200
+)
+
+var ErrFiltroAuditoriaInvalido = errors.New("el filtro de auditoría no es válido")
+
+// FiltroAuditoria delimita la consulta de la tabla Auditoria; los campos vacíos no
+// filtran. Hasta es exclusivo: se incluyen los registros anteriores a esa fecha.
+type FiltroAuditoria struct {
+	IdEmpleado *int
+	Tabla      string
+	IdRegistro *int
+	Accion     string
+	Desde      *time.Time
+	Hasta      *time.Time
+	// AntesDe es el cursor de la página: el campo Siguiente de la página anterior
+	AntesDe int
+	Tamanio int
+}
+
+// RegistroAuditoria es una fila de Auditoria con el nombre del empleado. Cambios solo
+// viene en las observaciones generadas por ObservacionesCambios.
+type RegistroAuditoria struct {
+	IdAuditoria    int              `json:"idAuditoria"`
+	IdEmpleado     int              `json:"idEmpleado"`
+	NombreEmpleado string           `json:"nombreEmpleado"`
+	Fecha          time.Time        `json:"fecha"`
+	Accion         string           `json:"accion"`
+	IdRegistro     int              `json:"idRegistro"`
+	Tabla          string           `json:"tabla"`
+	IdListItem     int              `json:"idListItem"`
+	NombrePC       string           `json:"nombrePC"`
+	Observaciones  string           `json:"observaciones"`
+	Cambios        *RegistroCambios `json:"cambios,omitempty"`
+}
+
+// PaginaAuditoria es una página de la consulta, del registro más reciente al más antiguo
+type PaginaAuditoria struct {
+	Tamanio   int                 `json:"tamanio"`
+	Registros []RegistroAuditoria `json:"registros"`
+	// Siguiente es el valor de antesDe para pedir la página siguiente; nil en la última
+	Siguiente *int `json:"siguiente"`
+}
+
+// normalizar aplica los valores por defecto y valida el filtro
+func (f *FiltroAuditoria) normalizar() error {
+	if f.Tamanio <= 0 {
+		f.Tamanio = TamanioPaginaPorDefecto
+	}
+	if f.Tamanio > tamanioPaginaMaximo {
+		f.Tamanio = tamanioPaginaMaximo
+	}
+	if f.AntesDe < 0 {
+		return fmt.Errorf("%w: el cursor de la página no es válido", ErrFiltroAuditoriaInvalido)
+	}
+
+	f.Tabla = strings.TrimSpace(f.Tabla)
+	f.Accion = strings.ToUpper(strings.TrimSpace(f.Accion))
+	switch f.Accion {
+	case "", AccionAgregar, AccionModificar, AccionEliminar:
+	default:
+		return fmt.Errorf("%w: la acción debe ser %s, %s o %s", ErrFiltroAuditoriaInvalido, AccionAgregar, AccionModificar, AccionEliminar)
+	}
+
+	if f.Desde != nil && f.Hasta != nil && !f.Desde.Before(*f.Hasta) {
+		return fmt.Errorf("%w: la fecha inicial debe ser anterior a la final", ErrFiltroAuditoriaInvalido)
+	}
+	return nil
+}
+
+// Buscar consulta la tabla Auditoria que escribe AuditoriaAgregarV, paginando por
+// IdAuditoria, con el nombre de cada empleado; queda vacío si el empleado no existe
+func (s *AuditoriaServicio) Buscar(ctx context.Context, filtro FiltroAuditoria) (*PaginaAuditoria, error) {
+	if err := filtro.normalizar(); err != nil {
+		return nil, err
+	}
+
+	// Se pide un registro más para saber si hay página siguiente
+	rows, err := s.db.EjecutarQuery(ctx, QueryBuscarAuditoria, false,
+		sql.Named("tamanio", filtro.Tamanio+1),
+		sql.Named("idEmpleado", filtro.IdEmpleado),
+		sql.Named("tabla", textoNulo(filtro.Tabla)),
+		sql.Named("idRegistro", filtro.IdRegistro),
+		sql.Named("accion", textoNulo(filtro.Accion)),
+		sql.Named("desde", filtro.Desde),
+		sql.Named("hasta", filtro.Hasta),
+		sql.Named("antesDe", enteroNulo(filtro.AntesDe)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar la auditoría: %w", err)
+	}
+	defer rows.Close()
+
+	pagina := &PaginaAuditoria{Tamanio: filtro.Tamanio, Registros: []RegistroAuditoria{}}
+	for rows.Next() {
+		var (
+			registro                                       RegistroAuditoria
+			idEmpleado, idRegistro, idListItem             sql.NullInt64
+			nombre, accion, tabla, nombrePC, observaciones sql.NullString
+		)
+		if err := rows.Scan(
+			&registro.IdAuditoria, &idEmpleado, &nombre, &registro.Fecha, &accion, &idRegistro,
+			&tabla, &idListItem, &nombrePC, &observaciones,
+		); err != nil {
+			return nil, err
+		}
+
+		registro.IdEmpleado = int(idEmpleado.Int64)
+		registro.NombreEmpleado = nombre.String
+		registro.Accion = accion.String
+		registro.IdRegistro = int(idRegistro.Int64)
+		registro.Tabla = tabla.String
+		registro.IdListItem = int(idListItem.Int64)
+		registro.NombrePC = nombrePC.String
+		registro.Observaciones = observaciones.String
+		if cambios, ok := LeerCambios(registro.Observaciones); ok {
+			registro.Cambios = cambios
+		}
+
+		pagina.Registros = append(pagina.Registros, registro)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(pagina.Registros) > filtro.Tamanio {
+		pagina.Registros = pagina.Registros[:filtro.Tamanio]
+		siguiente := pagina.Registros[filtro.Tamanio-1].IdAuditoria
+		pagina.Siguiente = &siguiente
+	}
+	return pagina, nil
+}
+
+func textoNulo(valor string) interface{} {
+	if valor == "" {
+		return nil
+	}
+	return valor
+}
+
+func enteroNulo(valor int) interface{} {
+	if valor == 0 {
+		return nil
+	}
+	return valor
+}
@@ -0,0 +1,44 @@
+package auditoria
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Contadores de auditoría del proceso, expuestos en el endpoint de estado de la API
+var (
+	registradas atomic.Int64
+	fallidas    atomic.Int64
+	ultimoFallo atomic.Int64
+)
+
+// Metricas resume los registros de auditoría desde que inició la API
+type Metricas struct {
+	Registradas int64      `json:"registradas"`
+	Fallidas    int64      `json:"fallidas"`
+	UltimoFallo *time.Time `json:"ultimoFallo,omitempty"`
+	// EnCola y EnSpool son los registros pendientes de la cola de auditoría
+	EnCola  int `json:"enCola"`
+	EnSpool int `json:"enSpool"`
+}
+
+// ObtenerMetricas retorna los contadores vigentes
+func ObtenerMetricas() Metricas {
+	metricas := Metricas{Registradas: registradas.Load(), Fallidas: fallidas.Load()}
+	if ultimo := ultimoFallo.Load(); ultimo > 0 {
+		fecha := time.Unix(ultimo, 0)
+		metricas.UltimoFallo = &fecha
+	}
+	if cola := colaActiva.Load(); cola != nil {
+		metricas.EnCola = len(cola.entradas)
+		metricas.EnSpool = cola.spool.cantidad()
+	}
+	return metricas
+}
+
+// ContarFallo registra en las métricas una auditoría que no se pudo realizar, p. ej.
+// cuando no se obtuvo el registro afectado
+func ContarFallo() {
+	fallidas.Add(1)
+	ultimoFallo.Store(time.Now().Unix())
+}
@@ -9,4 +9,36 @@ const (
     ) AS Usuario
   FROM Empleados
   WHERE IdEmpleado = @idUsuario`
+
+	// QueryBuscarAuditoria pagina la tabla Auditoria de SIGH por IdAuditoria descendente
+	// (keyset): @antesDe es el último IdAuditoria de la página anterior. Los filtros nulos
+	// no se aplican; RECOMPILE permite usar el índice adecuado según los que lleguen. El
+	// nombre del empleado se arma como en QueryObtenerNombreCompleto y queda nulo si el
+	// empleado no existe.
+	QueryBuscarAuditoria = `
+  SELECT TOP (@tamanio)
+    a.IdAuditoria,
+    a.IdEmpleado,
+    LEFT(
+      UPPER(LTRIM(RTRIM(e.ApellidoPaterno + ' ' + ISNULL(e.ApellidoMaterno, '') + ' ' + e.Nombres))),
+      30
+    ) AS NombreEmpleado,
+    a.Fecha,
+    a.Accion,
+    a.IdRegistro,
+    a.Tabla,
+    a.IdListItem,
+    a.NombrePC,
+    a.Observaciones
+  FROM Auditoria a
+  LEFT JOIN Empleados e ON e.IdEmpleado = a.IdEmpleado
+  WHERE (@idEmpleado IS NULL OR a.IdEmpleado = @idEmpleado)
+    AND (@tabla IS NULL OR a.Tabla = @tabla)
+    AND (@idRegistro IS NULL OR a.IdRegistro = @idRegistro)
+    AND (@accion IS NULL OR a.Accion = @accion)
+    AND (@desde IS NULL OR a.Fecha >= @desde)
+    AND (@hasta IS NULL OR a.Fecha < @hasta)
+    AND (@antesDe IS NULL OR a.IdAuditoria < @antesDe)
+  ORDER BY a.IdAuditoria DESC
+  OPTION (RECOMPILE)`
 )
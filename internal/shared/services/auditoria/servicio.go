@@ -3,8 +3,12 @@ package auditoria
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
 	"backend/internal/shared/database"
+	"backend/internal/shared/identidad"
 )
 
 // Constantes de acciones de auditoría
@@ -14,6 +18,8 @@ const (
 	AccionEliminar  = "E"
 )
 
+var ErrSinEmpleado = errors.New("el contexto no identifica al empleado que realiza la acción")
+
 type AuditoriaServicio struct {
 	db *database.ServicioDB
 }
@@ -41,21 +47,52 @@ func (s *AuditoriaServicio) ObtenerNombreEmpleado(ctx context.Context, idUsuario
 	return usuario.String, nil
 }
 
+// RegistrarAuditoria registra la acción a nombre del empleado y la estación de trabajo
+// que viajan en el contexto (middlewares de autenticación y estación). Los procesos del
+// sistema deben preparar el contexto con identidad.ComoSistema. Durante una suplencia la
+// acción queda a nombre del empleado suplido y observaciones identifica al supervisor.
+// Con la cola de auditoría iniciada solo encola el registro; sin ella ejecuta el SP.
 func (s *AuditoriaServicio) RegistrarAuditoria(
 	ctx context.Context,
-	idEmpleado int,
 	accion string,
 	idRegistro int,
 	tabla string,
 	idListItem int,
-	nombrePC string,
 	observaciones string,
 ) error {
-	return s.db.EjecutarSP(
+	empleado, ok := identidad.EmpleadoDesde(ctx)
+	if !ok {
+		ContarFallo()
+		return ErrSinEmpleado
+	}
+
+	nombrePC, ok := identidad.EstacionDesde(ctx)
+	if !ok {
+		nombrePC = identidad.EstacionSistema
+	}
+
+	if empleado.Actor != nil {
+		observaciones = fmt.Sprintf("[Suplencia: actor %d a nombre de %d] %s", empleado.Actor.IdEmpleado, empleado.IdEmpleado, observaciones)
+	}
+
+	if cola := colaActiva.Load(); cola != nil {
+		return cola.Encolar(Entrada{
+			IdEmpleado:    empleado.IdEmpleado,
+			Accion:        accion,
+			IdRegistro:    idRegistro,
+			Tabla:         tabla,
+			IdListItem:    idListItem,
+			NombrePC:      nombrePC,
+			Observaciones: observaciones,
+			Fecha:         time.Now(),
+		})
+	}
+
+	err := s.db.EjecutarSP(
 		ctx,
 		"AuditoriaAgregarV @IdEmpleado, @Accion, @IdRegistro, @Tabla, @idListItem, @nombrePC, @observaciones",
 		false,
-		sql.Named("IdEmpleado", idEmpleado),
+		sql.Named("IdEmpleado", empleado.IdEmpleado),
 		sql.Named("Accion", accion),
 		sql.Named("IdRegistro", idRegistro),
 		sql.Named("Tabla", tabla),
@@ -63,4 +100,10 @@ func (s *AuditoriaServicio) RegistrarAuditoria(
 		sql.Named("nombrePC", nombrePC),
 		sql.Named("observaciones", observaciones),
 	)
+	if err != nil {
+		ContarFallo()
+		return err
+	}
+	registradas.Add(1)
+	return nil
 }
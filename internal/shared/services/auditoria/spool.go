@@ -0,0 +1,227 @@
+package auditoria
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// spool es el archivo de solo anexado donde se guardan los registros que no se pudieron
+// enviar a la base de datos. Se reproduce en orden desde posicion, que se persiste en
+// un archivo ".pos" para no reenviar lo ya registrado si la API se reinicia.
+type spool struct {
+	ruta string
+
+	mu         sync.Mutex
+	posicion   int64
+	pendientes int
+}
+
+func abrirSpool(ruta string) (*spool, error) {
+	s := &spool{ruta: ruta}
+
+	if contenido, err := os.ReadFile(s.rutaPosicion()); err == nil {
+		if s.posicion, err = strconv.ParseInt(strings.TrimSpace(string(contenido)), 10, 64); err != nil {
+			return nil, fmt.Errorf("posición del spool de auditoría inválida: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error al leer la posición del spool de auditoría: %w", err)
+	}
+
+	archivo, err := os.OpenFile(ruta, os.O_RDONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir el spool de auditoría: %w", err)
+	}
+	defer archivo.Close()
+
+	if _, err := archivo.Seek(s.posicion, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error al leer el spool de auditoría: %w", err)
+	}
+	lector := bufio.NewScanner(archivo)
+	lector.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lector.Scan() {
+		s.pendientes++
+	}
+	if err := lector.Err(); err != nil {
+		return nil, fmt.Errorf("error al leer el spool de auditoría: %w", err)
+	}
+
+	// Una escritura interrumpida por una caída deja la última línea sin salto; se cierra
+	// para que los registros siguientes no se mezclen con ella
+	if info, err := archivo.Stat(); err == nil && info.Size() > 0 {
+		final := make([]byte, 1)
+		if _, err := archivo.ReadAt(final, info.Size()-1); err == nil && final[0] != '\n' {
+			if err := anexar(ruta, []byte("\n")); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if s.pendientes > 0 {
+		log.Printf("[Auditoria] El spool %s tiene %d registros pendientes de enviar", ruta, s.pendientes)
+	}
+	return s, nil
+}
+
+func (s *spool) rutaPosicion() string {
+	return s.ruta + ".pos"
+}
+
+// cantidad retorna los registros pendientes de reproducir
+func (s *spool) cantidad() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pendientes
+}
+
+// agregar anexa el lote al final del spool y sincroniza el archivo al disco
+func (s *spool) agregar(lote []Entrada) error {
+	var contenido bytes.Buffer
+	codificador := json.NewEncoder(&contenido)
+	for _, entrada := range lote {
+		if err := codificador.Encode(entrada); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := anexar(s.ruta, contenido.Bytes()); err != nil {
+		return err
+	}
+	s.pendientes += len(lote)
+	return nil
+}
+
+// anexar escribe al final del archivo y lo sincroniza al disco
+func anexar(ruta string, contenido []byte) error {
+	archivo, err := os.OpenFile(ruta, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("error al abrir el spool de auditoría: %w", err)
+	}
+	defer archivo.Close()
+
+	if _, err := archivo.Write(contenido); err != nil {
+		return fmt.Errorf("error al escribir el spool de auditoría: %w", err)
+	}
+	if err := archivo.Sync(); err != nil {
+		return fmt.Errorf("error al escribir el spool de auditoría: %w", err)
+	}
+	return nil
+}
+
+// reproducir envía los registros pendientes en orden, en lotes de tamaño lote. Se detiene
+// en el primer lote que enviar rechaza; lo ya enviado no se vuelve a enviar. Al vaciarse,
+// el spool se trunca. Las líneas ilegibles se descartan y cuentan como fallos. Solo
+// debe haber un reproductor a la vez: el lote se envía sin s.mu tomado para no frenar
+// a agregar mientras la base de datos responde.
+func (s *spool) reproducir(lote int, enviar func([]Entrada) error) error {
+	for {
+		terminado, err := s.reproducirLote(lote, enviar)
+		if err != nil || terminado {
+			return err
+		}
+	}
+}
+
+func (s *spool) reproducirLote(lote int, enviar func([]Entrada) error) (bool, error) {
+	entradas, leidos, lineas, err := s.leerLote(lote)
+	if err != nil {
+		return false, err
+	}
+	if lineas == 0 {
+		return true, nil
+	}
+
+	if len(entradas) > 0 {
+		if err := enviar(entradas); err != nil {
+			return false, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.posicion += leidos
+	s.pendientes = max(s.pendientes-lineas, 0)
+	if err := os.WriteFile(s.rutaPosicion(), []byte(strconv.FormatInt(s.posicion, 10)), 0o600); err != nil {
+		log.Printf("[Auditoria] No se pudo guardar la posición del spool %s: %v", s.ruta, err)
+	}
+	return false, nil
+}
+
+// leerLote lee hasta lote registros desde la posición actual. Si no queda ninguna línea,
+// vacía el spool antes de soltar s.mu para no perder lo que agregar anexe después.
+func (s *spool) leerLote(lote int) ([]Entrada, int64, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	archivo, err := os.Open(s.ruta)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("error al abrir el spool de auditoría: %w", err)
+	}
+	defer archivo.Close()
+
+	if _, err := archivo.Seek(s.posicion, io.SeekStart); err != nil {
+		return nil, 0, 0, fmt.Errorf("error al leer el spool de auditoría: %w", err)
+	}
+
+	lector := bufio.NewReader(archivo)
+	entradas := make([]Entrada, 0, lote)
+	leidos, lineas := int64(0), 0
+	for len(entradas) < lote {
+		linea, err := lector.ReadBytes('\n')
+		if err == io.EOF {
+			if len(linea) > 0 {
+				// Una línea sin salto es una escritura interrumpida por una caída
+				ContarFallo()
+				log.Printf("[Auditoria] Se descarta una línea incompleta del spool %s", s.ruta)
+				leidos += int64(len(linea))
+				lineas++
+			}
+			break
+		}
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("error al leer el spool de auditoría: %w", err)
+		}
+		leidos += int64(len(linea))
+		lineas++
+
+		var entrada Entrada
+		if err := json.Unmarshal(linea, &entrada); err != nil {
+			ContarFallo()
+			log.Printf("[Auditoria] Se descarta una línea ilegible del spool %s: %v", s.ruta, err)
+			continue
+		}
+		entradas = append(entradas, entrada)
+	}
+
+	if lineas == 0 {
+		return nil, 0, 0, s.vaciar()
+	}
+	return entradas, leidos, lineas, nil
+}
+
+// vaciar trunca el spool ya reproducido; requiere s.mu tomado. La posición se borra
+// primero: si la API cae entre ambos pasos, el siguiente arranque reenvía registros ya
+// enviados en lugar de saltarse los que se anexen detrás de una posición obsoleta.
+func (s *spool) vaciar() error {
+	if err := os.Remove(s.rutaPosicion()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error al vaciar el spool de auditoría: %w", err)
+	}
+	if err := os.Truncate(s.ruta, 0); err != nil {
+		return fmt.Errorf("error al vaciar el spool de auditoría: %w", err)
+	}
+	s.posicion = 0
+	s.pendientes = 0
+	return nil
+}
@@ -0,0 +1,34 @@
+package clavesapi
+
+const (
+	QueryCrearClave = `
+  INSERT INTO dbo.ClavesApi (Prefijo, HashClave, Nombre, Permisos, RangosIp, FechaExpiracion, IdEmpleadoCreador)
+  OUTPUT INSERTED.IdClave, INSERTED.FechaCreacion
+  VALUES (@prefijo, @hashClave, @nombre, @permisos, @rangosIp, @expira, @idEmpleado)`
+
+	columnasClave = `
+    IdClave, Prefijo, HashClave, Nombre, Permisos, RangosIp, FechaExpiracion,
+    IdEmpleadoCreador, FechaCreacion, FechaUltimoUso, Revocada, FechaRevocacion`
+
+	QueryObtenerClavePorPrefijo = `
+  SELECT` + columnasClave + `
+  FROM dbo.ClavesApi
+  WHERE Prefijo = @prefijo`
+
+	QueryListarClaves = `
+  SELECT` + columnasClave + `
+  FROM dbo.ClavesApi
+  WHERE (@incluirInactivas = 1 OR (Revocada = 0 AND FechaExpiracion > GETDATE()))
+  ORDER BY IdClave DESC`
+
+	QueryRevocarClave = `
+  UPDATE dbo.ClavesApi
+  SET Revocada = 1, IdEmpleadoRevoco = @idEmpleado, FechaRevocacion = GETDATE()
+  OUTPUT INSERTED.Prefijo
+  WHERE IdClave = @idClave AND Revocada = 0`
+
+	QueryRegistrarUsoClave = `
+  UPDATE dbo.ClavesApi
+  SET FechaUltimoUso = GETDATE()
+  WHERE IdClave = @idClave`
+)
@@ -0,0 +1,472 @@
+package clavesapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/shared/database"
+	"backend/internal/shared/errores"
+	"backend/internal/shared/estaciones"
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/services/auditoria"
+	"backend/internal/shared/services/permisos"
+)
+
+// Auditoría de la administración de claves
+const (
+	TablaClaves        = "ClavesApi"
+	IdListItemClaves   = 1306
+	prefijoFormato     = "sihce"
+	vigenciaPorDefecto = 365
+	vigenciaMaxima     = 730
+)
+
+// ttlCache es cuánto se reutiliza una clave verificada antes de volver a leerla; una clave
+// revocada en otra instancia de la API deja de aceptarse a más tardar tras este intervalo
+const ttlCache = time.Minute
+
+var (
+	ErrClaveInvalida               = errors.New("la clave de API no es válida, expiró o fue revocada")
+	ErrIpNoPermitida               = errors.New("la clave de API no admite solicitudes desde esta dirección")
+	ErrClaveNoEncontrada           = errors.New("la clave de API no existe o ya fue revocada")
+	ErrCuentaServicioNoConfigurada = errors.New("claves_api.id_empleado_servicio no está configurado; las claves de API están deshabilitadas")
+)
+
+// SolicitudClave son los datos para crear una clave de API
+type SolicitudClave struct {
+	Nombre       string   `json:"nombre"`
+	Permisos     []string `json:"permisos"`
+	RangosIp     []string `json:"rangosIp"`
+	DiasVigencia int      `json:"diasVigencia"`
+}
+
+// ClaveApi es una clave registrada; nunca incluye la parte secreta
+type ClaveApi struct {
+	IdClave           int        `json:"idClave"`
+	Prefijo           string     `json:"prefijo"`
+	Nombre            string     `json:"nombre"`
+	Permisos          []string   `json:"permisos"`
+	RangosIp          []string   `json:"rangosIp"`
+	FechaExpiracion   time.Time  `json:"fechaExpiracion"`
+	IdEmpleadoCreador int        `json:"idEmpleadoCreador"`
+	FechaCreacion     time.Time  `json:"fechaCreacion"`
+	FechaUltimoUso    *time.Time `json:"fechaUltimoUso"`
+	Revocada          bool       `json:"revocada"`
+	FechaRevocacion   *time.Time `json:"fechaRevocacion"`
+	hash              string
+	rangos            []netip.Prefix
+}
+
+// ClaveCreada es la respuesta de la creación; Clave se muestra esta única vez
+type ClaveCreada struct {
+	ClaveApi
+	Clave string `json:"clave"`
+}
+
+type claveCache struct {
+	clave  *ClaveApi
+	expira time.Time
+}
+
+type ClavesApiServicio struct {
+	db                 *database.ServicioDB
+	auditoria          *auditoria.AuditoriaServicio
+	permisos           *permisos.PermisosServicio
+	idEmpleadoServicio int
+
+	mu    sync.Mutex
+	cache map[string]claveCache
+}
+
+// NuevoServicio crea el servicio; idEmpleadoServicio es la cuenta de SIGH a cuyo nombre
+// se auditan las solicitudes autenticadas con clave. permisosServicio limita los permisos
+// que cada creador puede conceder a los que él mismo tiene.
+func NuevoServicio(
+	db *database.ServicioDB,
+	auditoriaServicio *auditoria.AuditoriaServicio,
+	permisosServicio *permisos.PermisosServicio,
+	idEmpleadoServicio int,
+) *ClavesApiServicio {
+	return &ClavesApiServicio{
+		db:                 db,
+		auditoria:          auditoriaServicio,
+		permisos:           permisosServicio,
+		idEmpleadoServicio: idEmpleadoServicio,
+		cache:              make(map[string]claveCache),
+	}
+}
+
+// Crear genera una clave con los permisos, rangos de IP y vigencia indicados. Solo se
+// conceden permisos que el creador tiene, para que la clave no amplíe sus privilegios.
+func (s *ClavesApiServicio) Crear(ctx context.Context, solicitud SolicitudClave) (*ClaveCreada, error) {
+	empleado, ok := identidad.EmpleadoDesde(ctx)
+	if !ok {
+		return nil, auditoria.ErrSinEmpleado
+	}
+
+	clave, err := validarSolicitud(solicitud)
+	if err != nil {
+		return nil, err
+	}
+
+	propios, err := s.permisos.Efectivos(ctx, empleado)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los permisos del creador: %w", err)
+	}
+	var ajenos []string
+	for _, permiso := range clave.Permisos {
+		if !propios.Tiene(permiso) {
+			ajenos = append(ajenos, permiso)
+		}
+	}
+	if len(ajenos) > 0 {
+		return nil, errores.Nuevo(http.StatusForbidden, errores.TipoProhibido,
+			"No puede conceder a la clave permisos que no tiene: "+strings.Join(ajenos, ", ")+".")
+	}
+
+	prefijo, secreto, err := generarClave()
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.db.EjecutarQueryRow(ctx, QueryCrearClave, false,
+		sql.Named("prefijo", prefijo),
+		sql.Named("hashClave", hashSecreto(secreto)),
+		sql.Named("nombre", clave.Nombre),
+		sql.Named("permisos", strings.Join(clave.Permisos, ",")),
+		sql.Named("rangosIp", strings.Join(clave.RangosIp, ",")),
+		sql.Named("expira", clave.FechaExpiracion),
+		sql.Named("idEmpleado", empleado.IdEmpleado),
+	)
+	if row == nil {
+		return nil, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+	if err := row.Scan(&clave.IdClave, &clave.FechaCreacion); err != nil {
+		return nil, fmt.Errorf("error al crear clave de API: %w", err)
+	}
+	clave.Prefijo = prefijo
+	clave.IdEmpleadoCreador = empleado.IdEmpleado
+
+	// La clave ya existe y su secreto solo se muestra en esta respuesta: un fallo de la
+	// auditoría no debe perderlo, queda en el log y en las métricas
+	if err := s.auditoria.RegistrarAuditoria(
+		ctx, auditoria.AccionAgregar, clave.IdClave, TablaClaves, IdListItemClaves,
+		fmt.Sprintf("Clave de API %s (%s) con permisos %s, vence %s",
+			prefijo, clave.Nombre, strings.Join(clave.Permisos, ","), clave.FechaExpiracion.Format("2006-01-02")),
+	); err != nil {
+		log.Printf("[ClavesApi] No se auditó la creación de la clave %s: %v", prefijo, err)
+	}
+
+	return &ClaveCreada{ClaveApi: *clave, Clave: formatearClave(prefijo, secreto)}, nil
+}
+
+// Listar retorna las claves vigentes o, con incluirInactivas, también las vencidas y revocadas
+func (s *ClavesApiServicio) Listar(ctx context.Context, incluirInactivas bool) ([]ClaveApi, error) {
+	rows, err := s.db.EjecutarQuery(ctx, QueryListarClaves, false, sql.Named("incluirInactivas", incluirInactivas))
+	if err != nil {
+		return nil, fmt.Errorf("error al listar claves de API: %w", err)
+	}
+	defer rows.Close()
+
+	claves := []ClaveApi{}
+	for rows.Next() {
+		clave, err := escanearClave(rows)
+		if err != nil {
+			return nil, err
+		}
+		claves = append(claves, *clave)
+	}
+	return claves, rows.Err()
+}
+
+// Revocar invalida la clave de inmediato en esta instancia
+func (s *ClavesApiServicio) Revocar(ctx context.Context, idClave int) error {
+	empleado, ok := identidad.EmpleadoDesde(ctx)
+	if !ok {
+		return auditoria.ErrSinEmpleado
+	}
+
+	row := s.db.EjecutarQueryRow(ctx, QueryRevocarClave, false,
+		sql.Named("idClave", idClave),
+		sql.Named("idEmpleado", empleado.IdEmpleado),
+	)
+	if row == nil {
+		return fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	var prefijo string
+	err := row.Scan(&prefijo)
+	if err == sql.ErrNoRows {
+		return ErrClaveNoEncontrada
+	}
+	if err != nil {
+		return fmt.Errorf("error al revocar clave de API: %w", err)
+	}
+
+	s.mu.Lock()
+	delete(s.cache, prefijo)
+	s.mu.Unlock()
+
+	// La clave ya quedó revocada y un reintento no la encontraría: un fallo de la
+	// auditoría queda en el log y en las métricas
+	if err := s.auditoria.RegistrarAuditoria(
+		ctx, auditoria.AccionEliminar, idClave, TablaClaves, IdListItemClaves,
+		fmt.Sprintf("Revocación de la clave de API %s", prefijo),
+	); err != nil {
+		log.Printf("[ClavesApi] No se auditó la revocación de la clave %s: %v", prefijo, err)
+	}
+	return nil
+}
+
+// Verificar valida la clave presentada desde la IP indicada y retorna la identidad de la
+// cuenta de servicio con la que se audita la solicitud, junto con la clave
+func (s *ClavesApiServicio) Verificar(ctx context.Context, valor string, ip string) (*identidad.Empleado, *ClaveApi, error) {
+	if s.idEmpleadoServicio <= 0 {
+		return nil, nil, ErrCuentaServicioNoConfigurada
+	}
+
+	prefijo, secreto, ok := separarClave(valor)
+	if !ok {
+		return nil, nil, ErrClaveInvalida
+	}
+
+	clave, err := s.obtener(ctx, prefijo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hash := hashSecreto(secreto)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(clave.hash)) != 1 ||
+		clave.Revocada || !time.Now().Before(clave.FechaExpiracion) {
+		return nil, nil, ErrClaveInvalida
+	}
+
+	if !clave.admiteIp(ip) {
+		return nil, nil, ErrIpNoPermitida
+	}
+
+	return &identidad.Empleado{
+		IdEmpleado: s.idEmpleadoServicio,
+		Usuario:    "api:" + clave.Prefijo,
+		Nombre:     clave.Nombre,
+	}, clave, nil
+}
+
+// obtener lee la clave con caché; en cada lectura de la base de datos actualiza la fecha
+// de último uso, que queda aproximada al intervalo de la caché
+func (s *ClavesApiServicio) obtener(ctx context.Context, prefijo string) (*ClaveApi, error) {
+	ahora := time.Now()
+
+	s.mu.Lock()
+	cache, ok := s.cache[prefijo]
+	s.mu.Unlock()
+	if ok && ahora.Before(cache.expira) {
+		return cache.clave, nil
+	}
+
+	rows, err := s.db.EjecutarQuery(ctx, QueryObtenerClavePorPrefijo, false, sql.Named("prefijo", prefijo))
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener clave de API: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrClaveInvalida
+	}
+	clave, err := escanearClave(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.EjecutarExec(ctx, QueryRegistrarUsoClave, false, sql.Named("idClave", clave.IdClave)); err != nil {
+		log.Printf("[ClavesApi] No se pudo registrar el uso de la clave %s: %v", prefijo, err)
+	}
+
+	s.mu.Lock()
+	for p, c := range s.cache {
+		if ahora.After(c.expira) {
+			delete(s.cache, p)
+		}
+	}
+	s.cache[prefijo] = claveCache{clave: clave, expira: ahora.Add(ttlCache)}
+	s.mu.Unlock()
+
+	return clave, nil
+}
+
+func (c *ClaveApi) admiteIp(ip string) bool {
+	if len(c.rangos) == 0 {
+		return true
+	}
+
+	direccion, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	direccion = direccion.Unmap()
+	for _, rango := range c.rangos {
+		if rango.Contains(direccion) {
+			return true
+		}
+	}
+	return false
+}
+
+func escanearClave(rows *sql.Rows) (*ClaveApi, error) {
+	var (
+		clave                 ClaveApi
+		permisos, rangos      string
+		ultimoUso, revocacion sql.NullTime
+	)
+	err := rows.Scan(
+		&clave.IdClave, &clave.Prefijo, &clave.hash, &clave.Nombre, &permisos, &rangos, &clave.FechaExpiracion,
+		&clave.IdEmpleadoCreador, &clave.FechaCreacion, &ultimoUso, &clave.Revocada, &revocacion,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	clave.Permisos = dividir(permisos)
+	clave.RangosIp = dividir(rangos)
+	if clave.rangos, err = parsearRangos(clave.RangosIp); err != nil {
+		return nil, fmt.Errorf("clave de API %s: %w", clave.Prefijo, err)
+	}
+	if ultimoUso.Valid {
+		clave.FechaUltimoUso = &ultimoUso.Time
+	}
+	if revocacion.Valid {
+		clave.FechaRevocacion = &revocacion.Time
+	}
+	return &clave, nil
+}
+
+func validarSolicitud(solicitud SolicitudClave) (*ClaveApi, error) {
+	nombre := strings.TrimSpace(solicitud.Nombre)
+	if nombre == "" || len([]rune(nombre)) > 100 {
+		return nil, errorValidacion("El nombre de la clave es obligatorio y admite hasta 100 caracteres.")
+	}
+
+	if len(solicitud.Permisos) == 0 {
+		return nil, errorValidacion("Debe indicar al menos un permiso para la clave.")
+	}
+	unicos := make(map[string]bool)
+	for _, permiso := range solicitud.Permisos {
+		permiso = strings.TrimSpace(permiso)
+		if permiso == permisos.Comodin {
+			return nil, errorValidacion("Las claves de API no admiten el permiso \"*\"; indique los permisos necesarios.")
+		}
+		if err := permisos.ValidarPermiso(permiso); err != nil {
+			return nil, errorValidacion(err.Error())
+		}
+		unicos[permiso] = true
+	}
+
+	dias := solicitud.DiasVigencia
+	if dias == 0 {
+		dias = vigenciaPorDefecto
+	}
+	if dias < 1 || dias > vigenciaMaxima {
+		return nil, errorValidacion("La vigencia debe estar entre 1 y " + strconv.Itoa(vigenciaMaxima) + " días.")
+	}
+
+	rangosIp := make([]string, 0, len(solicitud.RangosIp))
+	for _, rango := range solicitud.RangosIp {
+		prefijo, err := estaciones.ParsearRango(rango)
+		if err != nil {
+			return nil, errorValidacion(err.Error())
+		}
+		rangosIp = append(rangosIp, prefijo.String())
+	}
+
+	clave := &ClaveApi{
+		Nombre:          nombre,
+		Permisos:        make([]string, 0, len(unicos)),
+		RangosIp:        rangosIp,
+		FechaExpiracion: time.Now().AddDate(0, 0, dias),
+	}
+	for permiso := range unicos {
+		clave.Permisos = append(clave.Permisos, permiso)
+	}
+	sort.Strings(clave.Permisos)
+	return clave, nil
+}
+
+func errorValidacion(mensaje string) error {
+	return errores.Nuevo(http.StatusBadRequest, errores.TipoValidacion, mensaje)
+}
+
+func parsearRangos(rangos []string) ([]netip.Prefix, error) {
+	prefijos := make([]netip.Prefix, 0, len(rangos))
+	for _, rango := range rangos {
+		prefijo, err := estaciones.ParsearRango(rango)
+		if err != nil {
+			return nil, err
+		}
+		prefijos = append(prefijos, prefijo)
+	}
+	return prefijos, nil
+}
+
+func dividir(valor string) []string {
+	partes := []string{}
+	for _, parte := range strings.Split(valor, ",") {
+		if parte = strings.TrimSpace(parte); parte != "" {
+			partes = append(partes, parte)
+		}
+	}
+	return partes
+}
+
+// generarClave crea un prefijo público de 8 caracteres y un secreto de 256 bits
+func generarClave() (string, string, error) {
+	bytesPrefijo := make([]byte, 4)
+	bytesSecreto := make([]byte, 32)
+	if _, err := rand.Read(bytesPrefijo); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(bytesSecreto); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(bytesPrefijo), base64.RawURLEncoding.EncodeToString(bytesSecreto), nil
+}
+
+// formatearClave arma la clave entregada al integrador: sihce_<prefijo>_<secreto>
+func formatearClave(prefijo string, secreto string) string {
+	return prefijoFormato + "_" + prefijo + "_" + secreto
+}
+
+func separarClave(valor string) (string, string, bool) {
+	formato, resto, ok := strings.Cut(strings.TrimSpace(valor), "_")
+	if !ok || formato != prefijoFormato {
+		return "", "", false
+	}
+	prefijo, secreto, ok := strings.Cut(resto, "_")
+	if !ok || len(prefijo) != 8 || secreto == "" {
+		return "", "", false
+	}
+	return prefijo, secreto, true
+}
+
+// hashSecreto usa SHA-256: el secreto es aleatorio de 256 bits, no requiere bcrypt
+func hashSecreto(secreto string) string {
+	suma := sha256.Sum256([]byte(secreto))
+	return hex.EncodeToString(suma[:])
+}
@@ -0,0 +1,117 @@
+package elegibilidad
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Acciones clínicas sujetas a la política de financiamiento
+const (
+	AccionTriaje      = "triaje"
+	AccionConsulta    = "consulta"
+	AccionLaboratorio = "laboratorio"
+)
+
+var accionesValidas = map[string]bool{
+	AccionTriaje:      true,
+	AccionConsulta:    true,
+	AccionLaboratorio: true,
+}
+
+// Politica exige uno de los estados de facturación indicados a las atenciones con la
+// fuente o el tipo de financiamiento indicados. Una lista vacía no restringe ese criterio.
+type Politica struct {
+	Id                    string   `yaml:"id"`
+	Descripcion           string   `yaml:"descripcion"`
+	Acciones              []string `yaml:"acciones"`
+	FuentesFinanciamiento []int    `yaml:"fuentes_financiamiento"`
+	TiposFinanciamiento   []int    `yaml:"tipos_financiamiento"`
+	EstadosFacturacion    []int    `yaml:"estados_facturacion"`
+	Mensaje               string   `yaml:"mensaje"`
+}
+
+// Politicas representa el archivo versionado de políticas de elegibilidad
+type Politicas struct {
+	Version            string     `yaml:"version"`
+	PermitirPorDefecto bool       `yaml:"permitir_por_defecto"`
+	Politicas          []Politica `yaml:"politicas"`
+}
+
+// CargarPoliticas lee y valida el archivo de políticas
+func CargarPoliticas(ruta string) (*Politicas, error) {
+	contenido, err := os.ReadFile(ruta)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer políticas de elegibilidad: %w", err)
+	}
+
+	var politicas Politicas
+	if err := yaml.Unmarshal(contenido, &politicas); err != nil {
+		return nil, fmt.Errorf("error al parsear políticas de elegibilidad: %w", err)
+	}
+
+	if err := politicas.validar(); err != nil {
+		return nil, fmt.Errorf("políticas de elegibilidad inválidas: %w", err)
+	}
+
+	return &politicas, nil
+}
+
+func (p *Politicas) validar() error {
+	if p.Version == "" {
+		return fmt.Errorf("falta la versión")
+	}
+
+	for _, politica := range p.Politicas {
+		if politica.Id == "" {
+			return fmt.Errorf("existe una política sin id")
+		}
+		if len(politica.Acciones) == 0 {
+			return fmt.Errorf("política %s: debe indicar al menos una acción", politica.Id)
+		}
+		for _, accion := range politica.Acciones {
+			if !accionesValidas[accion] {
+				return fmt.Errorf("política %s: acción %q desconocida", politica.Id, accion)
+			}
+		}
+		if len(politica.FuentesFinanciamiento) == 0 && len(politica.TiposFinanciamiento) == 0 {
+			return fmt.Errorf("política %s: debe indicar fuentes o tipos de financiamiento", politica.Id)
+		}
+		if len(politica.EstadosFacturacion) == 0 {
+			return fmt.Errorf("política %s: debe indicar los estados de facturación permitidos", politica.Id)
+		}
+	}
+
+	return nil
+}
+
+func contiene(valores []int, valor int) bool {
+	for _, v := range valores {
+		if v == valor {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Politica) aplica(accion string, idFuente int, idTipo int) bool {
+	aplicaAccion := false
+	for _, a := range p.Acciones {
+		if a == accion {
+			aplicaAccion = true
+			break
+		}
+	}
+	if !aplicaAccion {
+		return false
+	}
+
+	if len(p.FuentesFinanciamiento) > 0 && !contiene(p.FuentesFinanciamiento, idFuente) {
+		return false
+	}
+	if len(p.TiposFinanciamiento) > 0 && !contiene(p.TiposFinanciamiento, idTipo) {
+		return false
+	}
+	return true
+}
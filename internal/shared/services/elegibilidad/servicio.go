@@ -0,0 +1,109 @@
+package elegibilidad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"backend/internal/shared/errores"
+	"backend/internal/shared/recarga"
+	"backend/internal/shared/services/atenciones"
+)
+
+// RutaPorDefecto se usa cuando config.yml no define elegibilidad.politicas
+const RutaPorDefecto = "elegibilidad.yml"
+
+var ErrAccionDesconocida = errors.New("la acción clínica no es válida")
+
+// Decision es el resultado de evaluar una acción clínica contra las políticas vigentes
+type Decision struct {
+	Permitido              bool    `json:"permitido"`
+	Accion                 string  `json:"accion"`
+	IdAtencion             int     `json:"idAtencion"`
+	IdFuenteFinanciamiento int     `json:"idFuenteFinanciamiento"`
+	IdTipoFinanciamiento   int     `json:"idTipoFinanciamiento"`
+	IdEstadoFacturacion    int     `json:"idEstadoFacturacion"`
+	Politica               *string `json:"politica"`
+	VersionPoliticas       string  `json:"versionPoliticas"`
+	Mensaje                string  `json:"mensaje"`
+}
+
+type ElegibilidadServicio struct {
+	atenciones *atenciones.AtencionesServicio
+	archivo    *recarga.Archivo[Politicas]
+}
+
+func NuevoServicio(atencionesServicio *atenciones.AtencionesServicio, rutaPoliticas string) *ElegibilidadServicio {
+	return &ElegibilidadServicio{
+		atenciones: atencionesServicio,
+		archivo:    recarga.Nuevo("políticas de elegibilidad", rutaPoliticas, CargarPoliticas),
+	}
+}
+
+// Evaluar decide si la acción está permitida para la atención según su financiamiento.
+// Se aplica la primera política que coincide con la acción, la fuente y el tipo.
+func (s *ElegibilidadServicio) Evaluar(ctx context.Context, idAtencion int, accion string) (*Decision, error) {
+	if !accionesValidas[accion] {
+		return nil, fmt.Errorf("%w: %q", ErrAccionDesconocida, accion)
+	}
+
+	politicas, err := s.archivo.Obtener()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.atenciones.ObtenerInfoFacturacionAtencion(ctx, idAtencion)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &Decision{
+		Accion:                 accion,
+		IdAtencion:             idAtencion,
+		IdFuenteFinanciamiento: info.IdFuenteFinanciamiento,
+		IdTipoFinanciamiento:   info.IdTipoFinanciamiento,
+		IdEstadoFacturacion:    info.IdEstadoFacturacion,
+		VersionPoliticas:       politicas.Version,
+	}
+
+	for _, politica := range politicas.Politicas {
+		if !politica.aplica(accion, info.IdFuenteFinanciamiento, info.IdTipoFinanciamiento) {
+			continue
+		}
+
+		id := politica.Id
+		decision.Politica = &id
+		decision.Permitido = contiene(politica.EstadosFacturacion, info.IdEstadoFacturacion)
+		if decision.Permitido {
+			decision.Mensaje = politica.Descripcion
+		} else {
+			decision.Mensaje = politica.Mensaje
+			if decision.Mensaje == "" {
+				decision.Mensaje = fmt.Sprintf("La atención no cumple la política %s para %s.", politica.Id, accion)
+			}
+		}
+		return decision, nil
+	}
+
+	decision.Permitido = politicas.PermitirPorDefecto
+	if decision.Permitido {
+		decision.Mensaje = "Ninguna política aplica; la acción se permite por defecto."
+	} else {
+		decision.Mensaje = "Ninguna política de financiamiento permite esta acción para la atención."
+	}
+	return decision, nil
+}
+
+// Verificar retorna un ErrorApi 403 de tipo NOT_ELIGIBLE si la acción no está permitida
+func (s *ElegibilidadServicio) Verificar(ctx context.Context, idAtencion int, accion string) error {
+	decision, err := s.Evaluar(ctx, idAtencion, accion)
+	if err != nil {
+		return err
+	}
+
+	if !decision.Permitido {
+		return errores.Nuevo(http.StatusForbidden, errores.TipoNoElegible, decision.Mensaje).ConDetalles(decision)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package permisos
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Comodin concede todos los permisos; "recurso:*" concede todas las acciones del recurso
+const Comodin = "*"
+
+// formatoPermiso exige la forma recurso:accion, p. ej. triaje:escribir
+var formatoPermiso = regexp.MustCompile(`^[a-z_]+:([a-z_]+|\*)$`)
+
+// Mapa representa el archivo versionado que asigna permisos a los roles de SIGH
+type Mapa struct {
+	Version string `yaml:"version"`
+	// Roles asigna permisos a cada rol por su nombre en la tabla Roles de SIGH
+	Roles map[string][]string `yaml:"roles"`
+	// Empleados asigna roles por IdEmpleado; solo se usa cuando las tablas de roles
+	// de SIGH no están disponibles
+	Empleados map[int][]string `yaml:"empleados"`
+	// RolesPorDefecto se asignan a los empleados que no figuran en Empleados
+	RolesPorDefecto []string `yaml:"roles_por_defecto"`
+}
+
+// ValidarPermiso verifica que el permiso tenga la forma recurso:accion
+func ValidarPermiso(permiso string) error {
+	if permiso != Comodin && !formatoPermiso.MatchString(permiso) {
+		return fmt.Errorf("permiso %q inválido: debe tener la forma recurso:accion", permiso)
+	}
+	return nil
+}
+
+// normalizarRol permite escribir los roles del archivo sin respetar mayúsculas de SIGH
+func normalizarRol(rol string) string {
+	return strings.ToUpper(strings.TrimSpace(rol))
+}
+
+// CargarMapa lee y valida el archivo de permisos
+func CargarMapa(ruta string) (*Mapa, error) {
+	contenido, err := os.ReadFile(ruta)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el mapa de permisos: %w", err)
+	}
+
+	var mapa Mapa
+	if err := yaml.Unmarshal(contenido, &mapa); err != nil {
+		return nil, fmt.Errorf("error al parsear el mapa de permisos: %w", err)
+	}
+
+	if err := mapa.validar(); err != nil {
+		return nil, fmt.Errorf("mapa de permisos inválido: %w", err)
+	}
+
+	return &mapa, nil
+}
+
+func (m *Mapa) validar() error {
+	if m.Version == "" {
+		return fmt.Errorf("falta la versión")
+	}
+
+	roles := make(map[string][]string, len(m.Roles))
+	for rol, permisos := range m.Roles {
+		nombre := normalizarRol(rol)
+		if nombre == "" {
+			return fmt.Errorf("existe un rol sin nombre")
+		}
+		if _, ok := roles[nombre]; ok {
+			return fmt.Errorf("rol %s repetido", nombre)
+		}
+		for _, permiso := range permisos {
+			if err := ValidarPermiso(permiso); err != nil {
+				return fmt.Errorf("rol %s: %w", nombre, err)
+			}
+		}
+		roles[nombre] = permisos
+	}
+	m.Roles = roles
+
+	for idEmpleado, asignados := range m.Empleados {
+		for i, rol := range asignados {
+			asignados[i] = normalizarRol(rol)
+			if _, ok := m.Roles[asignados[i]]; !ok {
+				return fmt.Errorf("empleado %d: rol %q no definido", idEmpleado, rol)
+			}
+		}
+	}
+
+	for i, rol := range m.RolesPorDefecto {
+		m.RolesPorDefecto[i] = normalizarRol(rol)
+		if _, ok := m.Roles[m.RolesPorDefecto[i]]; !ok {
+			return fmt.Errorf("rol por defecto %q no definido", rol)
+		}
+	}
+
+	return nil
+}
+
+// permisosDe reúne los permisos de los roles indicados; los roles sin entrada no conceden nada
+func (m *Mapa) permisosDe(roles []string) map[string]bool {
+	permisos := make(map[string]bool)
+	for _, rol := range roles {
+		for _, permiso := range m.Roles[normalizarRol(rol)] {
+			permisos[permiso] = true
+		}
+	}
+	return permisos
+}
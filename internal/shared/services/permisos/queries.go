@@ -0,0 +1,14 @@
+package permisos
+
+const (
+	// QueryObtenerRolesEmpleado retorna una fila con Disponible = 0 si la base de datos no
+	// tiene las tablas de roles de SIGH; en otro caso, una fila por rol del empleado
+	QueryObtenerRolesEmpleado = `
+  IF OBJECT_ID('dbo.UsuariosRoles', 'U') IS NULL OR OBJECT_ID('dbo.Roles', 'U') IS NULL
+    SELECT CAST(0 AS BIT) AS Disponible, CAST(NULL AS VARCHAR(100)) AS Rol
+  ELSE
+    SELECT CAST(1 AS BIT) AS Disponible, UPPER(LTRIM(RTRIM(r.Nombre))) AS Rol
+    FROM dbo.UsuariosRoles ur
+    INNER JOIN dbo.Roles r ON ur.IdRol = r.IdRol
+    WHERE ur.IdEmpleado = @idEmpleado`
+)
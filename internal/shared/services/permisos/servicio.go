@@ -0,0 +1,200 @@
+package permisos
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/shared/database"
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/recarga"
+)
+
+// Valores por defecto cuando config.yml no define la sección permisos
+const (
+	RutaPorDefecto = "permisos.yml"
+	TTLPorDefecto  = 5 * time.Minute
+)
+
+// Orígenes de los roles de un empleado
+const (
+	OrigenSigh     = "sigh"
+	OrigenLocal    = "local"
+	OrigenClaveApi = "clave_api"
+)
+
+// Conjunto son los roles y permisos efectivos de un empleado
+type Conjunto struct {
+	IdEmpleado int      `json:"idEmpleado"`
+	Roles      []string `json:"roles"`
+	Permisos   []string `json:"permisos"`
+	Origen     string   `json:"origen"`
+	permisos   map[string]bool
+}
+
+// Tiene indica si el conjunto concede el permiso, directamente o por comodín
+func (c *Conjunto) Tiene(permiso string) bool {
+	if c.permisos[Comodin] || c.permisos[permiso] {
+		return true
+	}
+	recurso, _, _ := strings.Cut(permiso, ":")
+	return c.permisos[recurso+":"+Comodin]
+}
+
+type rolesCache struct {
+	roles  []string
+	origen string
+	expira time.Time
+}
+
+type PermisosServicio struct {
+	db      *database.ServicioDB
+	archivo *recarga.Archivo[Mapa]
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[int]rolesCache
+}
+
+// NuevoServicio crea el servicio; ttl es cuánto se reutilizan los roles consultados
+// de cada empleado antes de volver a leerlos de SIGH
+func NuevoServicio(db *database.ServicioDB, rutaMapa string, ttl time.Duration) *PermisosServicio {
+	if ttl <= 0 {
+		ttl = TTLPorDefecto
+	}
+	return &PermisosServicio{
+		db:      db,
+		archivo: recarga.Nuevo("mapa de permisos", rutaMapa, CargarMapa),
+		ttl:     ttl,
+		cache:   make(map[int]rolesCache),
+	}
+}
+
+// Efectivos retorna los permisos con que se autoriza la solicitud: los del alcance de la
+// clave de API si la solicitud se autenticó con una, o los de los roles del empleado
+func (s *PermisosServicio) Efectivos(ctx context.Context, empleado *identidad.Empleado) (*Conjunto, error) {
+	alcance, ok := identidad.AlcanceDesde(ctx)
+	if !ok {
+		return s.Obtener(ctx, empleado.IdEmpleado)
+	}
+
+	conjunto := &Conjunto{
+		IdEmpleado: empleado.IdEmpleado,
+		Roles:      []string{},
+		Origen:     OrigenClaveApi,
+		permisos:   make(map[string]bool, len(alcance)),
+	}
+	for _, permiso := range alcance {
+		conjunto.permisos[permiso] = true
+	}
+	conjunto.listar()
+	return conjunto, nil
+}
+
+// Obtener retorna los permisos efectivos del empleado. Los roles se leen de las tablas
+// de SIGH o, si no existen, de las asignaciones locales del mapa de permisos.
+func (s *PermisosServicio) Obtener(ctx context.Context, idEmpleado int) (*Conjunto, error) {
+	mapa, err := s.archivo.Obtener()
+	if err != nil {
+		return nil, err
+	}
+
+	roles, origen, err := s.roles(ctx, idEmpleado, mapa)
+	if err != nil {
+		return nil, err
+	}
+
+	conjunto := &Conjunto{
+		IdEmpleado: idEmpleado,
+		Roles:      roles,
+		Origen:     origen,
+		permisos:   mapa.permisosDe(roles),
+	}
+	conjunto.listar()
+
+	return conjunto, nil
+}
+
+// listar llena Permisos en orden para la respuesta JSON
+func (c *Conjunto) listar() {
+	c.Permisos = make([]string, 0, len(c.permisos))
+	for permiso := range c.permisos {
+		c.Permisos = append(c.Permisos, permiso)
+	}
+	sort.Strings(c.Permisos)
+}
+
+// Invalidar descarta los roles en caché del empleado (p. ej. tras cambiarle el rol en SIGH)
+func (s *PermisosServicio) Invalidar(idEmpleado int) {
+	s.mu.Lock()
+	delete(s.cache, idEmpleado)
+	s.mu.Unlock()
+}
+
+// roles se cachea por empleado; los permisos se recalculan en cada llamada para que un
+// cambio en el mapa de permisos aplique sin esperar al TTL
+func (s *PermisosServicio) roles(ctx context.Context, idEmpleado int, mapa *Mapa) ([]string, string, error) {
+	ahora := time.Now()
+
+	s.mu.Lock()
+	cache, ok := s.cache[idEmpleado]
+	s.mu.Unlock()
+	if ok && ahora.Before(cache.expira) {
+		return cache.roles, cache.origen, nil
+	}
+
+	roles, disponible, err := s.rolesSigh(ctx, idEmpleado)
+	if err != nil {
+		return nil, "", err
+	}
+
+	origen := OrigenSigh
+	if !disponible {
+		origen = OrigenLocal
+		if asignados, ok := mapa.Empleados[idEmpleado]; ok {
+			roles = asignados
+		} else {
+			roles = mapa.RolesPorDefecto
+		}
+	}
+
+	s.mu.Lock()
+	for id, c := range s.cache {
+		if ahora.After(c.expira) {
+			delete(s.cache, id)
+		}
+	}
+	s.cache[idEmpleado] = rolesCache{roles: roles, origen: origen, expira: ahora.Add(s.ttl)}
+	s.mu.Unlock()
+
+	return roles, origen, nil
+}
+
+func (s *PermisosServicio) rolesSigh(ctx context.Context, idEmpleado int) ([]string, bool, error) {
+	rows, err := s.db.EjecutarQuery(ctx, QueryObtenerRolesEmpleado, false, sql.Named("idEmpleado", idEmpleado))
+	if err != nil {
+		return nil, false, fmt.Errorf("error al obtener roles del empleado: %w", err)
+	}
+	defer rows.Close()
+
+	disponible := true
+	roles := []string{}
+	for rows.Next() {
+		var rol sql.NullString
+		if err := rows.Scan(&disponible, &rol); err != nil {
+			return nil, false, err
+		}
+		if rol.Valid && rol.String != "" {
+			roles = append(roles, rol.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	return roles, disponible, nil
+}
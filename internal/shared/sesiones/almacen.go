@@ -0,0 +1,37 @@
+package sesiones
+
+import (
+	"context"
+	"time"
+)
+
+// Estados de un token de refresco al intentar consumirlo
+const (
+	EstadoVigente     = "vigente"
+	EstadoReutilizado = "reutilizado"
+	EstadoDesconocido = "desconocido"
+)
+
+// TokenRefresco es el registro persistido de un token de refresco emitido
+type TokenRefresco struct {
+	Id         string
+	Familia    string
+	IdEmpleado int
+	Expira     time.Time
+}
+
+// Almacen persiste los tokens de refresco emitidos y su revocación. En producción se
+// usa AlmacenSQL; AlmacenMemoria sirve para pruebas y entornos sin base de datos.
+type Almacen interface {
+	// Registrar guarda un token de refresco recién emitido
+	Registrar(ctx context.Context, token TokenRefresco) error
+	// Consumir marca el token como usado de forma atómica. Retorna EstadoVigente si era
+	// la primera vez, EstadoReutilizado si ya estaba usado o revocado, o EstadoDesconocido.
+	Consumir(ctx context.Context, id string) (string, *TokenRefresco, error)
+	// RevocarFamilia invalida todos los tokens rotados desde un mismo inicio de sesión
+	RevocarFamilia(ctx context.Context, familia string) error
+	// RevocarEmpleado invalida todas las sesiones del empleado y retorna cuántas había activas
+	RevocarEmpleado(ctx context.Context, idEmpleado int) (int, error)
+	// FamiliaActiva indica si la familia no fue revocada
+	FamiliaActiva(ctx context.Context, familia string) (bool, error)
+}
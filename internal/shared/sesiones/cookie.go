@@ -0,0 +1,176 @@
+package sesiones
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"backend/internal/shared/identidad"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCookiesNoConfiguradas = errors.New("security.session_secret no está configurado; el modo cookie está deshabilitado")
+	ErrCookieInvalida        = errors.New("la cookie de sesión no es válida o expiró")
+)
+
+// ConfigCookies define la firma y duración de las sesiones por cookie
+type ConfigCookies struct {
+	// Secreto es SecurityConfig.SessionSecret
+	Secreto string
+	// Inactividad es el tiempo sin solicitudes tras el cual la cookie expira
+	Inactividad time.Duration
+	// Maxima es la duración absoluta de la sesión desde el inicio de sesión
+	Maxima time.Duration
+}
+
+// SesionCookie es el contenido firmado de la cookie de sesión
+type SesionCookie struct {
+	Familia    string `json:"fam"`
+	IdEmpleado int    `json:"id"`
+	Usuario    string `json:"usr"`
+	Nombre     string `json:"nom"`
+	Expira     int64  `json:"exp"`
+	Limite     int64  `json:"lim"`
+}
+
+// Empleado retorna la identidad contenida en la cookie
+func (s *SesionCookie) Empleado() *identidad.Empleado {
+	return &identidad.Empleado{IdEmpleado: s.IdEmpleado, Usuario: s.Usuario, Nombre: s.Nombre}
+}
+
+// ExpiraEn es el vencimiento por inactividad de la cookie
+func (s *SesionCookie) ExpiraEn() time.Time {
+	return time.Unix(s.Expira, 0)
+}
+
+// IniciarCookie abre una nueva sesión para el modo cookie. La familia se registra en el
+// almacén como las de los tokens, de modo que Cerrar, RevocarEmpleado y la revocación
+// por reutilización aplican igual; su token de refresco nunca sale del servidor.
+func (g *GestorSesiones) IniciarCookie(ctx context.Context, empleado *identidad.Empleado) (string, *SesionCookie, error) {
+	if g.cookies.Secreto == "" {
+		return "", nil, ErrCookiesNoConfiguradas
+	}
+
+	ahora := time.Now()
+	sesion := &SesionCookie{
+		Familia:    uuid.NewString(),
+		IdEmpleado: empleado.IdEmpleado,
+		Usuario:    empleado.Usuario,
+		Nombre:     empleado.Nombre,
+		Limite:     ahora.Add(g.cookies.Maxima).Unix(),
+	}
+
+	err := g.almacen.Registrar(ctx, TokenRefresco{
+		Id:         uuid.NewString(),
+		Familia:    sesion.Familia,
+		IdEmpleado: empleado.IdEmpleado,
+		Expira:     time.Unix(sesion.Limite, 0),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	valor, err := g.firmarCookie(sesion, ahora)
+	if err != nil {
+		return "", nil, err
+	}
+	return valor, sesion, nil
+}
+
+// VerificarCookie valida la firma, la expiración y que la sesión no haya sido revocada.
+// Si pasó la mitad del tiempo de inactividad retorna también el valor renovado de la
+// cookie, que el llamador debe volver a enviar; en otro caso retorna "".
+func (g *GestorSesiones) VerificarCookie(ctx context.Context, valor string) (*SesionCookie, string, error) {
+	if g.cookies.Secreto == "" {
+		return nil, "", ErrCookiesNoConfiguradas
+	}
+
+	sesion, err := g.leerCookie(valor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ahora := time.Now()
+	if ahora.Unix() >= sesion.Expira || ahora.Unix() >= sesion.Limite {
+		return nil, "", ErrCookieInvalida
+	}
+
+	activa, err := g.familiaActiva(ctx, sesion.Familia)
+	if err != nil {
+		return nil, "", err
+	}
+	if !activa {
+		return nil, "", ErrSesionRevocada
+	}
+
+	renovada := ""
+	if time.Until(sesion.ExpiraEn()) < g.cookies.Inactividad/2 {
+		if renovada, err = g.firmarCookie(sesion, ahora); err != nil {
+			return nil, "", err
+		}
+	}
+	return sesion, renovada, nil
+}
+
+// CerrarCookie revoca la sesión de la cookie. Una cookie ya expirada o revocada no es error.
+func (g *GestorSesiones) CerrarCookie(ctx context.Context, valor string) error {
+	sesion, err := g.leerCookie(valor)
+	if err != nil {
+		return nil
+	}
+	return g.revocarFamilia(ctx, sesion.Familia)
+}
+
+// TokenCsrf deriva el token CSRF de la sesión. Al estar ligado a la familia, un atacante
+// que logre fijar la cookie CSRF en el navegador no puede generar uno válido.
+func (g *GestorSesiones) TokenCsrf(sesion *SesionCookie) string {
+	return g.firma("csrf:" + sesion.Familia)
+}
+
+// VerificarCsrf compara en tiempo constante el token recibido con el de la sesión
+func (g *GestorSesiones) VerificarCsrf(sesion *SesionCookie, token string) bool {
+	return token != "" && hmac.Equal([]byte(token), []byte(g.TokenCsrf(sesion)))
+}
+
+// firmarCookie extiende la expiración por inactividad sin pasar del límite y firma
+func (g *GestorSesiones) firmarCookie(sesion *SesionCookie, ahora time.Time) (string, error) {
+	sesion.Expira = min(ahora.Add(g.cookies.Inactividad).Unix(), sesion.Limite)
+
+	contenido, err := json.Marshal(sesion)
+	if err != nil {
+		return "", err
+	}
+	datos := base64.RawURLEncoding.EncodeToString(contenido)
+	return datos + "." + g.firma(datos), nil
+}
+
+func (g *GestorSesiones) leerCookie(valor string) (*SesionCookie, error) {
+	datos, firma, ok := strings.Cut(valor, ".")
+	if !ok || !hmac.Equal([]byte(firma), []byte(g.firma(datos))) {
+		return nil, ErrCookieInvalida
+	}
+
+	contenido, err := base64.RawURLEncoding.DecodeString(datos)
+	if err != nil {
+		return nil, ErrCookieInvalida
+	}
+
+	var sesion SesionCookie
+	if err := json.Unmarshal(contenido, &sesion); err != nil || sesion.Familia == "" || sesion.IdEmpleado <= 0 {
+		return nil, ErrCookieInvalida
+	}
+	return &sesion, nil
+}
+
+func (g *GestorSesiones) firma(datos string) string {
+	mac := hmac.New(sha256.New, []byte(g.cookies.Secreto))
+	mac.Write([]byte(datos))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
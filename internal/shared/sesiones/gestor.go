@@ -0,0 +1,202 @@
+package sesiones
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/tokens"
+
+	"github.com/google/uuid"
+)
+
+// ttlFamilias es cuánto se confía en la última consulta de revocación de una familia.
+// Un token de acceso revocado deja de aceptarse a más tardar tras este intervalo.
+const ttlFamilias = 30 * time.Second
+
+var (
+	ErrSesionInvalida = errors.New("la sesión no es válida o expiró")
+	ErrSesionRevocada = errors.New("la sesión fue revocada")
+	// ErrReutilizacion indica que se presentó un token de refresco ya rotado; toda la
+	// familia queda revocada porque el token pudo haber sido robado
+	ErrReutilizacion = errors.New("se detectó la reutilización de un token de refresco")
+)
+
+type familiaCache struct {
+	activa bool
+	expira time.Time
+}
+
+// GestorSesiones combina la firma de tokens con el almacén de sesiones: rota los tokens
+// de refresco en cada uso, detecta su reutilización y permite revocar sesiones.
+// También emite y verifica las cookies firmadas del modo cookie.
+type GestorSesiones struct {
+	tokens  *tokens.GestorTokens
+	almacen Almacen
+	cookies ConfigCookies
+
+	mu       sync.Mutex
+	familias map[string]familiaCache
+}
+
+func NuevoGestor(gestorTokens *tokens.GestorTokens, almacen Almacen, cookies ConfigCookies) *GestorSesiones {
+	return &GestorSesiones{
+		tokens:   gestorTokens,
+		almacen:  almacen,
+		cookies:  cookies,
+		familias: make(map[string]familiaCache),
+	}
+}
+
+// Iniciar abre una nueva sesión (familia) para el empleado autenticado
+func (g *GestorSesiones) Iniciar(ctx context.Context, empleado *identidad.Empleado) (*tokens.ParTokens, error) {
+	return g.emitir(ctx, empleado, uuid.NewString())
+}
+
+func (g *GestorSesiones) emitir(ctx context.Context, empleado *identidad.Empleado, familia string) (*tokens.ParTokens, error) {
+	par, claims, err := g.tokens.Emitir(empleado, familia)
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.almacen.Registrar(ctx, TokenRefresco{
+		Id:         claims.ID,
+		Familia:    familia,
+		IdEmpleado: empleado.IdEmpleado,
+		Expira:     claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return par, nil
+}
+
+// Rotar consume el token de refresco y emite un nuevo par de la misma familia. Si el token
+// ya había sido usado o su sesión fue revocada, revoca la familia completa.
+// empleado obtiene los datos vigentes del titular del token (p. ej. para rechazar bajas).
+func (g *GestorSesiones) Rotar(ctx context.Context, refreshToken string, empleado func(*tokens.Claims) (*identidad.Empleado, error)) (*tokens.ParTokens, error) {
+	claims, err := g.tokens.VerificarRefresco(refreshToken)
+	if errors.Is(err, tokens.ErrSecretoNoConfigurado) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, ErrSesionInvalida
+	}
+
+	// Se consulta antes de consumir para que un fallo transitorio no deje el token usado
+	actual, err := empleado(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	estado, registro, err := g.almacen.Consumir(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case estado == EstadoDesconocido:
+		return nil, ErrSesionInvalida
+	case registro.Familia != claims.Familia || registro.IdEmpleado != claims.IdEmpleado:
+		return nil, ErrSesionInvalida
+	case estado == EstadoReutilizado:
+		if err := g.revocarFamilia(ctx, claims.Familia); err != nil {
+			return nil, err
+		}
+		log.Printf("[Sesiones] Reutilización del token de refresco %s del empleado %d; sesión %s revocada", claims.ID, claims.IdEmpleado, claims.Familia)
+		return nil, ErrReutilizacion
+	}
+
+	return g.emitir(ctx, actual, claims.Familia)
+}
+
+// Cerrar revoca la sesión a la que pertenece el token de refresco
+func (g *GestorSesiones) Cerrar(ctx context.Context, refreshToken string) error {
+	claims, err := g.tokens.VerificarRefresco(refreshToken)
+	if errors.Is(err, tokens.ErrSecretoNoConfigurado) {
+		return err
+	}
+	if err != nil {
+		return ErrSesionInvalida
+	}
+	return g.revocarFamilia(ctx, claims.Familia)
+}
+
+// RevocarEmpleado cierra todas las sesiones del empleado y retorna cuántas había activas
+func (g *GestorSesiones) RevocarEmpleado(ctx context.Context, idEmpleado int) (int, error) {
+	revocadas, err := g.almacen.RevocarEmpleado(ctx, idEmpleado)
+	if err != nil {
+		return 0, err
+	}
+
+	// Las familias del empleado no se conocen aquí; se invalida toda la caché
+	g.mu.Lock()
+	g.familias = make(map[string]familiaCache)
+	g.mu.Unlock()
+
+	return revocadas, nil
+}
+
+// ClavesPublicas lista las claves públicas con las que otros sistemas verifican los tokens
+func (g *GestorSesiones) ClavesPublicas() ([]tokens.ClavePublica, error) {
+	return g.tokens.ClavesPublicas()
+}
+
+// VerificarAcceso valida un token de acceso y que su sesión no haya sido revocada
+func (g *GestorSesiones) VerificarAcceso(ctx context.Context, token string) (*tokens.Claims, error) {
+	claims, err := g.tokens.VerificarAcceso(token)
+	if err != nil {
+		return nil, err
+	}
+
+	activa, err := g.familiaActiva(ctx, claims.Familia)
+	if err != nil {
+		return nil, fmt.Errorf("error al verificar la sesión: %w", err)
+	}
+	if !activa {
+		return nil, ErrSesionRevocada
+	}
+	return claims, nil
+}
+
+func (g *GestorSesiones) familiaActiva(ctx context.Context, familia string) (bool, error) {
+	ahora := time.Now()
+
+	g.mu.Lock()
+	cache, ok := g.familias[familia]
+	g.mu.Unlock()
+	if ok && ahora.Before(cache.expira) {
+		return cache.activa, nil
+	}
+
+	activa, err := g.almacen.FamiliaActiva(ctx, familia)
+	if err != nil {
+		return false, err
+	}
+
+	g.mu.Lock()
+	for id, c := range g.familias {
+		if ahora.After(c.expira) {
+			delete(g.familias, id)
+		}
+	}
+	g.familias[familia] = familiaCache{activa: activa, expira: ahora.Add(ttlFamilias)}
+	g.mu.Unlock()
+
+	return activa, nil
+}
+
+func (g *GestorSesiones) revocarFamilia(ctx context.Context, familia string) error {
+	if err := g.almacen.RevocarFamilia(ctx, familia); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	g.familias[familia] = familiaCache{activa: false, expira: time.Now().Add(ttlFamilias)}
+	g.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,172 @@
+package sesiones
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"backend/internal/config"
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/tokens"
+)
+
+func nuevoGestorPrueba(t *testing.T) (*GestorSesiones, *AlmacenMemoria) {
+	t.Helper()
+	almacen := NuevoAlmacenMemoria()
+	gestorTokens := tokens.NuevoGestor(config.JWTConfig{
+		AccessSecret:           "secreto-de-acceso-para-pruebas",
+		RefreshSecret:          "secreto-de-refresco-para-pruebas",
+		AccessTokenExpiration:  60,
+		RefreshTokenExpiration: 3600,
+	})
+	return NuevoGestor(gestorTokens, almacen, ConfigCookies{}), almacen
+}
+
+func empleadoPrueba(idEmpleado int) func(*tokens.Claims) (*identidad.Empleado, error) {
+	return func(*tokens.Claims) (*identidad.Empleado, error) {
+		return &identidad.Empleado{IdEmpleado: idEmpleado, Usuario: "prueba"}, nil
+	}
+}
+
+func TestRotarEmiteNuevoParDeLaMismaFamilia(t *testing.T) {
+	ctx := context.Background()
+	gestor, _ := nuevoGestorPrueba(t)
+
+	inicial, err := gestor.Iniciar(ctx, &identidad.Empleado{IdEmpleado: 7, Usuario: "prueba"})
+	if err != nil {
+		t.Fatalf("Iniciar: %v", err)
+	}
+
+	rotado, err := gestor.Rotar(ctx, inicial.RefreshToken, empleadoPrueba(7))
+	if err != nil {
+		t.Fatalf("Rotar: %v", err)
+	}
+	if rotado.RefreshToken == inicial.RefreshToken {
+		t.Fatal("la rotación debe emitir un token de refresco distinto")
+	}
+
+	claimsInicial, err := gestor.VerificarAcceso(ctx, inicial.AccessToken)
+	if err != nil {
+		t.Fatalf("VerificarAcceso del token inicial: %v", err)
+	}
+	claimsRotado, err := gestor.VerificarAcceso(ctx, rotado.AccessToken)
+	if err != nil {
+		t.Fatalf("VerificarAcceso del token rotado: %v", err)
+	}
+	if claimsInicial.Familia != claimsRotado.Familia {
+		t.Fatalf("familia %q tras rotar, se esperaba %q", claimsRotado.Familia, claimsInicial.Familia)
+	}
+
+	if _, err := gestor.Rotar(ctx, rotado.RefreshToken, empleadoPrueba(7)); err != nil {
+		t.Fatalf("el token rotado debe poder rotarse a su vez: %v", err)
+	}
+}
+
+func TestReutilizarTokenRevocaLaFamilia(t *testing.T) {
+	ctx := context.Background()
+	gestor, _ := nuevoGestorPrueba(t)
+
+	inicial, err := gestor.Iniciar(ctx, &identidad.Empleado{IdEmpleado: 7, Usuario: "prueba"})
+	if err != nil {
+		t.Fatalf("Iniciar: %v", err)
+	}
+	rotado, err := gestor.Rotar(ctx, inicial.RefreshToken, empleadoPrueba(7))
+	if err != nil {
+		t.Fatalf("Rotar: %v", err)
+	}
+
+	if _, err := gestor.Rotar(ctx, inicial.RefreshToken, empleadoPrueba(7)); !errors.Is(err, ErrReutilizacion) {
+		t.Fatalf("reutilizar el token rotado: error %v, se esperaba ErrReutilizacion", err)
+	}
+
+	// El token legítimo más reciente también queda invalidado
+	if _, err := gestor.Rotar(ctx, rotado.RefreshToken, empleadoPrueba(7)); !errors.Is(err, ErrReutilizacion) {
+		t.Fatalf("rotar tras la reutilización: error %v, se esperaba ErrReutilizacion", err)
+	}
+	if _, err := gestor.VerificarAcceso(ctx, rotado.AccessToken); !errors.Is(err, ErrSesionRevocada) {
+		t.Fatalf("acceso tras la reutilización: error %v, se esperaba ErrSesionRevocada", err)
+	}
+}
+
+func TestRotarRechazaTokenDesconocido(t *testing.T) {
+	ctx := context.Background()
+	gestor, _ := nuevoGestorPrueba(t)
+	otro, _ := nuevoGestorPrueba(t)
+
+	// Firmado con los mismos secretos pero registrado en otro almacén
+	par, err := otro.Iniciar(ctx, &identidad.Empleado{IdEmpleado: 7, Usuario: "prueba"})
+	if err != nil {
+		t.Fatalf("Iniciar: %v", err)
+	}
+
+	if _, err := gestor.Rotar(ctx, par.RefreshToken, empleadoPrueba(7)); !errors.Is(err, ErrSesionInvalida) {
+		t.Fatalf("error %v, se esperaba ErrSesionInvalida", err)
+	}
+	if _, err := gestor.Rotar(ctx, "no-es-un-jwt", empleadoPrueba(7)); !errors.Is(err, ErrSesionInvalida) {
+		t.Fatalf("token mal formado: error %v, se esperaba ErrSesionInvalida", err)
+	}
+}
+
+func TestCerrarRevocaLaSesion(t *testing.T) {
+	ctx := context.Background()
+	gestor, _ := nuevoGestorPrueba(t)
+
+	par, err := gestor.Iniciar(ctx, &identidad.Empleado{IdEmpleado: 7, Usuario: "prueba"})
+	if err != nil {
+		t.Fatalf("Iniciar: %v", err)
+	}
+
+	if err := gestor.Cerrar(ctx, par.RefreshToken); err != nil {
+		t.Fatalf("Cerrar: %v", err)
+	}
+	if _, err := gestor.VerificarAcceso(ctx, par.AccessToken); !errors.Is(err, ErrSesionRevocada) {
+		t.Fatalf("acceso tras cerrar: error %v, se esperaba ErrSesionRevocada", err)
+	}
+	if _, err := gestor.Rotar(ctx, par.RefreshToken, empleadoPrueba(7)); !errors.Is(err, ErrReutilizacion) {
+		t.Fatalf("rotar tras cerrar: error %v, se esperaba ErrReutilizacion", err)
+	}
+}
+
+func TestRevocarEmpleadoCierraSoloSusSesiones(t *testing.T) {
+	ctx := context.Background()
+	gestor, _ := nuevoGestorPrueba(t)
+
+	primera, err := gestor.Iniciar(ctx, &identidad.Empleado{IdEmpleado: 7, Usuario: "prueba"})
+	if err != nil {
+		t.Fatalf("Iniciar: %v", err)
+	}
+	segunda, err := gestor.Iniciar(ctx, &identidad.Empleado{IdEmpleado: 7, Usuario: "prueba"})
+	if err != nil {
+		t.Fatalf("Iniciar: %v", err)
+	}
+	ajena, err := gestor.Iniciar(ctx, &identidad.Empleado{IdEmpleado: 8, Usuario: "otro"})
+	if err != nil {
+		t.Fatalf("Iniciar: %v", err)
+	}
+
+	// Se verifica antes de revocar para comprobar que la caché de familias se invalida
+	if _, err := gestor.VerificarAcceso(ctx, primera.AccessToken); err != nil {
+		t.Fatalf("VerificarAcceso: %v", err)
+	}
+
+	revocadas, err := gestor.RevocarEmpleado(ctx, 7)
+	if err != nil {
+		t.Fatalf("RevocarEmpleado: %v", err)
+	}
+	if revocadas != 2 {
+		t.Fatalf("%d sesiones revocadas, se esperaban 2", revocadas)
+	}
+
+	for nombre, par := range map[string]*tokens.ParTokens{"primera": primera, "segunda": segunda} {
+		if _, err := gestor.VerificarAcceso(ctx, par.AccessToken); !errors.Is(err, ErrSesionRevocada) {
+			t.Fatalf("sesión %s: error %v, se esperaba ErrSesionRevocada", nombre, err)
+		}
+	}
+	if _, err := gestor.VerificarAcceso(ctx, ajena.AccessToken); err != nil {
+		t.Fatalf("la sesión de otro empleado no debe revocarse: %v", err)
+	}
+
+	if revocadas, err := gestor.RevocarEmpleado(ctx, 7); err != nil || revocadas != 0 {
+		t.Fatalf("revocar de nuevo: %d sesiones, error %v; se esperaban 0", revocadas, err)
+	}
+}
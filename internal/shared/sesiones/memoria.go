@@ -0,0 +1,127 @@
+package sesiones
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// retencionSinTokens es cuánto se recuerda la revocación de una familia de la que el
+// almacén no conoce tokens; cubre los tokens de acceso que aún puedan circular
+const retencionSinTokens = 24 * time.Hour
+
+type registroMemoria struct {
+	token    TokenRefresco
+	usado    bool
+	revocado bool
+}
+
+// AlmacenMemoria guarda las sesiones en el proceso; se pierden al reiniciar la API
+type AlmacenMemoria struct {
+	mu     sync.Mutex
+	tokens map[string]*registroMemoria
+	// revocadas guarda hasta cuándo recordar cada familia revocada: el vencimiento de su
+	// último token, y como mínimo retencionSinTokens desde la revocación
+	revocadas map[string]time.Time
+}
+
+func NuevoAlmacenMemoria() *AlmacenMemoria {
+	return &AlmacenMemoria{
+		tokens:    make(map[string]*registroMemoria),
+		revocadas: make(map[string]time.Time),
+	}
+}
+
+func (a *AlmacenMemoria) Registrar(_ context.Context, token TokenRefresco) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.purgar(time.Now())
+	hasta, revocada := a.revocadas[token.Familia]
+	if revocada && token.Expira.After(hasta) {
+		a.revocadas[token.Familia] = token.Expira
+	}
+	a.tokens[token.Id] = &registroMemoria{token: token, revocado: revocada}
+	return nil
+}
+
+func (a *AlmacenMemoria) Consumir(_ context.Context, id string) (string, *TokenRefresco, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	registro, ok := a.tokens[id]
+	if !ok {
+		return EstadoDesconocido, nil, nil
+	}
+
+	token := registro.token
+	if registro.usado || registro.revocado {
+		return EstadoReutilizado, &token, nil
+	}
+
+	registro.usado = true
+	return EstadoVigente, &token, nil
+}
+
+func (a *AlmacenMemoria) RevocarFamilia(_ context.Context, familia string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.revocar(map[string]bool{familia: true}, time.Now())
+	return nil
+}
+
+func (a *AlmacenMemoria) RevocarEmpleado(_ context.Context, idEmpleado int) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	familias := make(map[string]bool)
+	for _, registro := range a.tokens {
+		if _, revocada := a.revocadas[registro.token.Familia]; registro.token.IdEmpleado == idEmpleado && !revocada {
+			familias[registro.token.Familia] = true
+		}
+	}
+
+	a.revocar(familias, time.Now())
+	return len(familias), nil
+}
+
+func (a *AlmacenMemoria) FamiliaActiva(_ context.Context, familia string) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, revocada := a.revocadas[familia]
+	return !revocada, nil
+}
+
+// revocar marca las familias y sus tokens como revocados; debe llamarse con a.mu tomado
+func (a *AlmacenMemoria) revocar(familias map[string]bool, ahora time.Time) {
+	for familia := range familias {
+		if _, ok := a.revocadas[familia]; !ok {
+			a.revocadas[familia] = ahora.Add(retencionSinTokens)
+		}
+	}
+	for _, registro := range a.tokens {
+		if familias[registro.token.Familia] {
+			registro.revocado = true
+			if registro.token.Expira.After(a.revocadas[registro.token.Familia]) {
+				a.revocadas[registro.token.Familia] = registro.token.Expira
+			}
+		}
+	}
+}
+
+// purgar elimina los tokens expirados y las revocaciones que ya no protegen ningún
+// token; debe llamarse con a.mu tomado
+func (a *AlmacenMemoria) purgar(ahora time.Time) {
+	for id, registro := range a.tokens {
+		if ahora.After(registro.token.Expira) {
+			delete(a.tokens, id)
+		}
+	}
+	for familia, hasta := range a.revocadas {
+		if ahora.After(hasta) {
+			delete(a.revocadas, familia)
+		}
+	}
+}
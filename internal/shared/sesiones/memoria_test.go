@@ -0,0 +1,80 @@
+package sesiones
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAlmacenMemoriaPurgaRevocacionesVencidas(t *testing.T) {
+	ctx := context.Background()
+	almacen := NuevoAlmacenMemoria()
+	ahora := time.Now()
+
+	almacen.Registrar(ctx, TokenRefresco{Id: "t1", Familia: "f1", IdEmpleado: 7, Expira: ahora.Add(time.Hour)})
+	almacen.Registrar(ctx, TokenRefresco{Id: "t2", Familia: "f1", IdEmpleado: 7, Expira: ahora.Add(48 * time.Hour)})
+	if err := almacen.RevocarFamilia(ctx, "f1"); err != nil {
+		t.Fatalf("RevocarFamilia: %v", err)
+	}
+	// Familia de la que el almacén no conoce tokens
+	if err := almacen.RevocarFamilia(ctx, "f2"); err != nil {
+		t.Fatalf("RevocarFamilia: %v", err)
+	}
+
+	for _, familia := range []string{"f1", "f2"} {
+		if activa, _ := almacen.FamiliaActiva(ctx, familia); activa {
+			t.Fatalf("la familia %s debe estar revocada", familia)
+		}
+	}
+
+	casos := []struct {
+		nombre    string
+		momento   time.Time
+		esperadas []string
+	}{
+		{"antes de vencer", ahora.Add(time.Minute), []string{"f1", "f2"}},
+		{"vencida la retención sin tokens", ahora.Add(retencionSinTokens + time.Minute), []string{"f1"}},
+		{"vencido el último token", ahora.Add(48*time.Hour + time.Minute), nil},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			almacen.mu.Lock()
+			almacen.purgar(caso.momento)
+			revocadas := len(almacen.revocadas)
+			almacen.mu.Unlock()
+
+			if revocadas != len(caso.esperadas) {
+				t.Fatalf("%d familias revocadas en memoria, se esperaban %d", revocadas, len(caso.esperadas))
+			}
+			for _, familia := range caso.esperadas {
+				if activa, _ := almacen.FamiliaActiva(ctx, familia); activa {
+					t.Fatalf("la familia %s debe seguir revocada", familia)
+				}
+			}
+		})
+	}
+}
+
+func TestAlmacenMemoriaConsumirUnaSolaVez(t *testing.T) {
+	ctx := context.Background()
+	almacen := NuevoAlmacenMemoria()
+	almacen.Registrar(ctx, TokenRefresco{Id: "t1", Familia: "f1", IdEmpleado: 7, Expira: time.Now().Add(time.Hour)})
+
+	casos := []struct {
+		id     string
+		estado string
+	}{
+		{"t1", EstadoVigente},
+		{"t1", EstadoReutilizado},
+		{"t9", EstadoDesconocido},
+	}
+	for _, caso := range casos {
+		estado, _, err := almacen.Consumir(ctx, caso.id)
+		if err != nil {
+			t.Fatalf("Consumir(%s): %v", caso.id, err)
+		}
+		if estado != caso.estado {
+			t.Fatalf("Consumir(%s) = %s, se esperaba %s", caso.id, estado, caso.estado)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package sesiones
+
+const (
+	QueryRegistrarToken = `
+  INSERT INTO dbo.SesionesRefresco (IdToken, Familia, IdEmpleado, FechaExpiracion)
+  VALUES (@idToken, @familia, @idEmpleado, @expira)`
+
+	// QueryConsumirToken marca el token como usado y retorna su estado anterior en la
+	// misma sentencia, de modo que dos refrescos simultáneos no puedan usarlo ambos
+	QueryConsumirToken = `
+  UPDATE dbo.SesionesRefresco
+  SET Usado = 1, FechaUso = ISNULL(FechaUso, GETDATE())
+  OUTPUT DELETED.Usado, DELETED.Revocado, DELETED.Familia, DELETED.IdEmpleado, DELETED.FechaExpiracion
+  WHERE IdToken = @idToken`
+
+	QueryRevocarFamilia = `
+  UPDATE dbo.SesionesRefresco
+  SET Revocado = 1, FechaRevocacion = GETDATE()
+  WHERE Familia = @familia AND Revocado = 0`
+
+	QueryRevocarEmpleado = `
+  UPDATE dbo.SesionesRefresco
+  SET Revocado = 1, FechaRevocacion = GETDATE()
+  OUTPUT INSERTED.Familia
+  WHERE IdEmpleado = @idEmpleado AND Revocado = 0`
+
+	QueryFamiliaActiva = `
+  SELECT CASE WHEN EXISTS (
+    SELECT 1 FROM dbo.SesionesRefresco WHERE Familia = @familia AND Revocado = 1
+  ) THEN CAST(0 AS BIT) ELSE CAST(1 AS BIT) END`
+
+	QueryPurgarSesiones = `
+  DELETE FROM dbo.SesionesRefresco
+  WHERE FechaExpiracion < DATEADD(DAY, -30, GETDATE())`
+)
@@ -0,0 +1,116 @@
+package sesiones
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/shared/database"
+)
+
+// intervaloPurga es la frecuencia con la que se eliminan los tokens expirados hace más de 30 días
+const intervaloPurga = 6 * time.Hour
+
+// AlmacenSQL persiste las sesiones en la BD principal (tabla dbo.SesionesRefresco)
+type AlmacenSQL struct {
+	db          *database.ServicioDB
+	mu          sync.Mutex
+	ultimaPurga time.Time
+}
+
+func NuevoAlmacenSQL(db *database.ServicioDB) *AlmacenSQL {
+	return &AlmacenSQL{db: db}
+}
+
+func (a *AlmacenSQL) Registrar(ctx context.Context, token TokenRefresco) error {
+	_, err := a.db.EjecutarExec(ctx, QueryRegistrarToken, false,
+		sql.Named("idToken", token.Id),
+		sql.Named("familia", token.Familia),
+		sql.Named("idEmpleado", token.IdEmpleado),
+		sql.Named("expira", token.Expira),
+	)
+	if err != nil {
+		return fmt.Errorf("error al registrar sesión: %w", err)
+	}
+
+	a.purgar(ctx)
+	return nil
+}
+
+func (a *AlmacenSQL) Consumir(ctx context.Context, id string) (string, *TokenRefresco, error) {
+	row := a.db.EjecutarQueryRow(ctx, QueryConsumirToken, false, sql.Named("idToken", id))
+	if row == nil {
+		return "", nil, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	token := TokenRefresco{Id: id}
+	var usado, revocado bool
+	err := row.Scan(&usado, &revocado, &token.Familia, &token.IdEmpleado, &token.Expira)
+	if err == sql.ErrNoRows {
+		return EstadoDesconocido, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if usado || revocado {
+		return EstadoReutilizado, &token, nil
+	}
+	return EstadoVigente, &token, nil
+}
+
+func (a *AlmacenSQL) RevocarFamilia(ctx context.Context, familia string) error {
+	if _, err := a.db.EjecutarExec(ctx, QueryRevocarFamilia, false, sql.Named("familia", familia)); err != nil {
+		return fmt.Errorf("error al revocar sesión: %w", err)
+	}
+	return nil
+}
+
+func (a *AlmacenSQL) RevocarEmpleado(ctx context.Context, idEmpleado int) (int, error) {
+	rows, err := a.db.EjecutarQuery(ctx, QueryRevocarEmpleado, false, sql.Named("idEmpleado", idEmpleado))
+	if err != nil {
+		return 0, fmt.Errorf("error al revocar sesiones del empleado: %w", err)
+	}
+	defer rows.Close()
+
+	familias := make(map[string]bool)
+	for rows.Next() {
+		var familia string
+		if err := rows.Scan(&familia); err != nil {
+			return 0, err
+		}
+		familias[familia] = true
+	}
+	return len(familias), rows.Err()
+}
+
+func (a *AlmacenSQL) FamiliaActiva(ctx context.Context, familia string) (bool, error) {
+	row := a.db.EjecutarQueryRow(ctx, QueryFamiliaActiva, false, sql.Named("familia", familia))
+	if row == nil {
+		return false, fmt.Errorf("error al obtener conexión a la base de datos")
+	}
+
+	var activa bool
+	if err := row.Scan(&activa); err != nil {
+		return false, err
+	}
+	return activa, nil
+}
+
+// purgar elimina periódicamente los tokens expirados para que la tabla no crezca sin límite
+func (a *AlmacenSQL) purgar(ctx context.Context) {
+	a.mu.Lock()
+	if time.Since(a.ultimaPurga) < intervaloPurga {
+		a.mu.Unlock()
+		return
+	}
+	a.ultimaPurga = time.Now()
+	a.mu.Unlock()
+
+	if _, err := a.db.EjecutarExec(ctx, QueryPurgarSesiones, false); err != nil {
+		log.Printf("[Sesiones] No se pudieron purgar las sesiones expiradas: %v", err)
+	}
+}
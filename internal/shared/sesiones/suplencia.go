@@ -0,0 +1,52 @@
+package sesiones
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend/internal/shared/identidad"
+
+	"github.com/google/uuid"
+)
+
+var ErrNoEsSuplencia = errors.New("el token no corresponde a una suplencia")
+
+// IniciarSuplencia abre una sesión corta en la que empleado.Actor actúa a nombre de
+// empleado. Solo emite un token de acceso: al vencer, el supervisor vuelve a su propia
+// sesión. La familia se registra a nombre del supervisor, de modo que revocar sus
+// sesiones también termina las suplencias que abrió.
+func (g *GestorSesiones) IniciarSuplencia(ctx context.Context, empleado *identidad.Empleado, duracion time.Duration) (string, time.Time, error) {
+	if empleado.Actor == nil {
+		return "", time.Time{}, ErrNoEsSuplencia
+	}
+
+	familia := uuid.NewString()
+	token, claims, err := g.tokens.EmitirSuplencia(empleado, familia, duracion)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	err = g.almacen.Registrar(ctx, TokenRefresco{
+		Id:         claims.ID,
+		Familia:    familia,
+		IdEmpleado: empleado.Actor.IdEmpleado,
+		Expira:     claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, claims.ExpiresAt.Time, nil
+}
+
+// FinalizarSuplencia revoca la sesión de suplencia del token de acceso antes de su vencimiento
+func (g *GestorSesiones) FinalizarSuplencia(ctx context.Context, token string) error {
+	claims, err := g.VerificarAcceso(ctx, token)
+	if err != nil {
+		return ErrSesionInvalida
+	}
+	if claims.Actor == nil {
+		return ErrNoEsSuplencia
+	}
+	return g.revocarFamilia(ctx, claims.Familia)
+}
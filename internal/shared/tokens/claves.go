@@ -0,0 +1,220 @@
+package tokens
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Algoritmos admitidos en el juego de claves
+const (
+	AlgoritmoHS256 = "HS256"
+	AlgoritmoRS256 = "RS256"
+	AlgoritmoEdDSA = "EdDSA"
+)
+
+// longitudMinimaSecreto evita secretos HS256 adivinables en el juego de claves
+const longitudMinimaSecreto = 32
+
+// Clave es una entrada del juego de claves. Las claves HS256 usan Secreto; las RS256 y
+// EdDSA leen la clave privada (y opcionalmente la pública) de archivos PEM.
+type Clave struct {
+	Kid       string `yaml:"kid"`
+	Algoritmo string `yaml:"algoritmo"`
+	Secreto   string `yaml:"secreto"`
+	// Privada es la ruta del PEM de la clave privada; se requiere solo para firmar
+	Privada string `yaml:"privada"`
+	// Publica es la ruta del PEM de la clave pública; si falta se deriva de Privada
+	Publica string `yaml:"publica"`
+	// Retirada deja de aceptar los tokens firmados con la clave
+	Retirada bool `yaml:"retirada"`
+
+	metodo       jwt.SigningMethod
+	firma        any
+	verificacion any
+}
+
+// JuegoClaves es el archivo de claves de firma de los tokens. Los tokens nuevos se firman
+// con la clave Activa y llevan su kid en el encabezado; se aceptan los firmados con
+// cualquier clave no retirada. Para rotar: agregar la clave nueva, marcarla activa y
+// retirar la anterior cuando venzan sus tokens de refresco.
+type JuegoClaves struct {
+	Activa string  `yaml:"activa"`
+	Claves []Clave `yaml:"claves"`
+
+	porKid map[string]*Clave
+}
+
+// CargarJuegoClaves lee y valida el juego de claves. Las variables ${VAR} del archivo se
+// reemplazan por su valor, como en config.yml, para no guardar secretos en él.
+func CargarJuegoClaves(ruta string) (*JuegoClaves, error) {
+	contenido, err := os.ReadFile(ruta)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el juego de claves: %w", err)
+	}
+
+	var juego JuegoClaves
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(contenido))), &juego); err != nil {
+		return nil, fmt.Errorf("error al parsear el juego de claves: %w", err)
+	}
+
+	if err := juego.validar(); err != nil {
+		return nil, fmt.Errorf("juego de claves inválido: %w", err)
+	}
+
+	return &juego, nil
+}
+
+func (j *JuegoClaves) validar() error {
+	j.porKid = make(map[string]*Clave, len(j.Claves))
+	for i := range j.Claves {
+		clave := &j.Claves[i]
+		clave.Kid = strings.TrimSpace(clave.Kid)
+		if clave.Kid == "" {
+			return fmt.Errorf("existe una clave sin kid")
+		}
+		if _, ok := j.porKid[clave.Kid]; ok {
+			return fmt.Errorf("kid %s repetido", clave.Kid)
+		}
+		if err := clave.preparar(); err != nil {
+			return fmt.Errorf("clave %s: %w", clave.Kid, err)
+		}
+		j.porKid[clave.Kid] = clave
+	}
+
+	activa, ok := j.porKid[j.Activa]
+	switch {
+	case !ok:
+		return fmt.Errorf("la clave activa %q no existe", j.Activa)
+	case activa.Retirada:
+		return fmt.Errorf("la clave activa %s está retirada", j.Activa)
+	case activa.firma == nil:
+		return fmt.Errorf("la clave activa %s no tiene clave privada", j.Activa)
+	}
+	return nil
+}
+
+// preparar lee el material de la clave según su algoritmo
+func (c *Clave) preparar() error {
+	switch c.Algoritmo {
+	case AlgoritmoHS256:
+		if len(c.Secreto) < longitudMinimaSecreto {
+			return fmt.Errorf("el secreto HS256 debe tener al menos %d caracteres", longitudMinimaSecreto)
+		}
+		c.metodo = jwt.SigningMethodHS256
+		c.firma = []byte(c.Secreto)
+		c.verificacion = c.firma
+		return nil
+	case AlgoritmoRS256:
+		c.metodo = jwt.SigningMethodRS256
+		return c.leerPEM(
+			func(pem []byte) (crypto.Signer, error) { return jwt.ParseRSAPrivateKeyFromPEM(pem) },
+			func(pem []byte) (crypto.PublicKey, error) { return jwt.ParseRSAPublicKeyFromPEM(pem) },
+		)
+	case AlgoritmoEdDSA:
+		c.metodo = jwt.SigningMethodEdDSA
+		return c.leerPEM(
+			func(pem []byte) (crypto.Signer, error) {
+				privada, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+				if err != nil {
+					return nil, err
+				}
+				return privada.(crypto.Signer), nil
+			},
+			jwt.ParseEdPublicKeyFromPEM,
+		)
+	}
+	return fmt.Errorf("algoritmo %q no soportado (use %s, %s o %s)", c.Algoritmo, AlgoritmoHS256, AlgoritmoRS256, AlgoritmoEdDSA)
+}
+
+func (c *Clave) leerPEM(privada func([]byte) (crypto.Signer, error), publica func([]byte) (crypto.PublicKey, error)) error {
+	if c.Privada == "" && c.Publica == "" {
+		return fmt.Errorf("debe indicar el archivo PEM de la clave privada o de la pública")
+	}
+
+	if c.Privada != "" {
+		contenido, err := os.ReadFile(c.Privada)
+		if err != nil {
+			return fmt.Errorf("error al leer la clave privada: %w", err)
+		}
+		firmante, err := privada(contenido)
+		if err != nil {
+			return fmt.Errorf("clave privada inválida: %w", err)
+		}
+		c.firma = firmante
+		c.verificacion = firmante.Public()
+	}
+
+	if c.Publica != "" {
+		contenido, err := os.ReadFile(c.Publica)
+		if err != nil {
+			return fmt.Errorf("error al leer la clave pública: %w", err)
+		}
+		clave, err := publica(contenido)
+		if err != nil {
+			return fmt.Errorf("clave pública inválida: %w", err)
+		}
+		c.verificacion = clave
+	}
+	return nil
+}
+
+// activa retorna la clave con la que se firman los tokens nuevos
+func (j *JuegoClaves) activa() *Clave {
+	return j.porKid[j.Activa]
+}
+
+// vigente retorna la clave del kid si existe y no está retirada
+func (j *JuegoClaves) vigente(kid string) (*Clave, bool) {
+	clave, ok := j.porKid[kid]
+	if !ok || clave.Retirada {
+		return nil, false
+	}
+	return clave, true
+}
+
+// ClavePublica es una clave en formato JWK (RFC 7517) para que otros sistemas del
+// hospital verifiquen los tokens firmados con RS256 o EdDSA
+type ClavePublica struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// publicas lista las claves asimétricas no retiradas; las HS256 nunca se publican
+func (j *JuegoClaves) publicas() []ClavePublica {
+	publicas := []ClavePublica{}
+	for _, clave := range j.Claves {
+		if clave.Retirada {
+			continue
+		}
+
+		switch publica := clave.verificacion.(type) {
+		case *rsa.PublicKey:
+			publicas = append(publicas, ClavePublica{
+				Kty: "RSA", Kid: clave.Kid, Alg: AlgoritmoRS256, Use: "sig",
+				N: base64.RawURLEncoding.EncodeToString(publica.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publica.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			publicas = append(publicas, ClavePublica{
+				Kty: "OKP", Kid: clave.Kid, Alg: AlgoritmoEdDSA, Use: "sig",
+				Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(publica),
+			})
+		}
+	}
+	return publicas
+}
@@ -0,0 +1,243 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/shared/identidad"
+	"backend/internal/shared/recarga"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Tipos de token emitidos por la API
+const (
+	TipoAcceso   = "acceso"
+	TipoRefresco = "refresco"
+)
+
+const emisor = "sihce_backend"
+
+var (
+	ErrTokenInvalido        = errors.New("el token no es válido o expiró")
+	ErrSecretoNoConfigurado = errors.New("los secretos JWT no están configurados")
+)
+
+// Claims es el contenido firmado de los tokens de acceso y de refresco
+type Claims struct {
+	IdEmpleado int    `json:"idEmpleado"`
+	Usuario    string `json:"usuario"`
+	Nombre     string `json:"nombre"`
+	Tipo       string `json:"tipo"`
+	Familia    string `json:"fam"`
+	// Actor es el supervisor que actúa a nombre del empleado en una suplencia
+	Actor *identidad.Empleado `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Empleado retorna la identidad contenida en el token
+func (c *Claims) Empleado() *identidad.Empleado {
+	return &identidad.Empleado{IdEmpleado: c.IdEmpleado, Usuario: c.Usuario, Nombre: c.Nombre, Actor: c.Actor}
+}
+
+// ParTokens es la respuesta de inicio de sesión y de refresco
+type ParTokens struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
+
+// GestorTokens emite y verifica los tokens firmados. Con JWTConfig.Claves usa el juego de
+// claves con kid, que se recarga al modificar el archivo; los tokens sin kid se verifican
+// con los secretos AccessSecret/RefreshSecret mientras estén configurados.
+// La revocación de sesiones la resuelve el paquete sesiones.
+type GestorTokens struct {
+	cfg    config.JWTConfig
+	claves *recarga.Archivo[JuegoClaves]
+}
+
+func NuevoGestor(cfg config.JWTConfig) *GestorTokens {
+	gestor := &GestorTokens{cfg: cfg}
+	if cfg.Claves != "" {
+		gestor.claves = recarga.Nuevo("juego de claves JWT", cfg.Claves, CargarJuegoClaves)
+	}
+	return gestor
+}
+
+func (g *GestorTokens) duracion(tipo string) time.Duration {
+	if tipo == TipoRefresco {
+		return time.Duration(g.cfg.RefreshTokenExpiration) * time.Second
+	}
+	return time.Duration(g.cfg.AccessTokenExpiration) * time.Second
+}
+
+// secreto retorna el secreto HS256 sin kid del tipo de token, "" si no está configurado
+func (g *GestorTokens) secreto(tipo string) string {
+	if tipo == TipoRefresco {
+		return g.cfg.RefreshSecret
+	}
+	return g.cfg.AccessSecret
+}
+
+func (g *GestorTokens) juego() (*JuegoClaves, error) {
+	juego, err := g.claves.Obtener()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSecretoNoConfigurado, err)
+	}
+	return juego, nil
+}
+
+func (g *GestorTokens) firmar(empleado *identidad.Empleado, familia string, tipo string, ahora time.Time) (*Claims, string, error) {
+	return g.firmarPor(empleado, familia, tipo, ahora, g.duracion(tipo))
+}
+
+func (g *GestorTokens) firmarPor(empleado *identidad.Empleado, familia string, tipo string, ahora time.Time, duracion time.Duration) (*Claims, string, error) {
+	claims := &Claims{
+		IdEmpleado: empleado.IdEmpleado,
+		Usuario:    empleado.Usuario,
+		Nombre:     empleado.Nombre,
+		Tipo:       tipo,
+		Familia:    familia,
+		Actor:      empleado.Actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    emisor,
+			Subject:   strconv.Itoa(empleado.IdEmpleado),
+			IssuedAt:  jwt.NewNumericDate(ahora),
+			NotBefore: jwt.NewNumericDate(ahora),
+			ExpiresAt: jwt.NewNumericDate(ahora.Add(duracion)),
+		},
+	}
+
+	var (
+		firmado string
+		err     error
+	)
+	if g.claves != nil {
+		juego, errJuego := g.juego()
+		if errJuego != nil {
+			return nil, "", errJuego
+		}
+		clave := juego.activa()
+		token := jwt.NewWithClaims(clave.metodo, claims)
+		token.Header["kid"] = clave.Kid
+		firmado, err = token.SignedString(clave.firma)
+	} else {
+		secreto := g.secreto(tipo)
+		if secreto == "" {
+			return nil, "", ErrSecretoNoConfigurado
+		}
+		firmado, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secreto))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return claims, firmado, nil
+}
+
+// ClavesPublicas lista en formato JWK las claves asimétricas vigentes del juego de claves
+func (g *GestorTokens) ClavesPublicas() ([]ClavePublica, error) {
+	if g.claves == nil {
+		return []ClavePublica{}, nil
+	}
+	juego, err := g.juego()
+	if err != nil {
+		return nil, err
+	}
+	return juego.publicas(), nil
+}
+
+// Emitir genera un token de acceso y uno de refresco de la familia indicada. Todos los
+// tokens rotados a partir de un mismo inicio de sesión comparten familia.
+// Retorna también los claims del token de refresco para registrarlo como sesión.
+func (g *GestorTokens) Emitir(empleado *identidad.Empleado, familia string) (*ParTokens, *Claims, error) {
+	ahora := time.Now()
+
+	_, acceso, err := g.firmar(empleado, familia, TipoAcceso, ahora)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claimsRefresco, refresco, err := g.firmar(empleado, familia, TipoRefresco, ahora)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &ParTokens{
+		AccessToken:  acceso,
+		RefreshToken: refresco,
+		TokenType:    "Bearer",
+		ExpiresIn:    g.cfg.AccessTokenExpiration,
+	}, claimsRefresco, nil
+}
+
+// EmitirSuplencia firma un token de acceso sin token de refresco, con la duración
+// indicada, a nombre del empleado suplido; empleado.Actor debe ser el supervisor real
+func (g *GestorTokens) EmitirSuplencia(empleado *identidad.Empleado, familia string, duracion time.Duration) (string, *Claims, error) {
+	claims, firmado, err := g.firmarPor(empleado, familia, TipoAcceso, time.Now(), duracion)
+	if err != nil {
+		return "", nil, err
+	}
+	return firmado, claims, nil
+}
+
+func (g *GestorTokens) verificar(token string, tipo string) (*Claims, error) {
+	if g.claves == nil && g.secreto(tipo) == "" {
+		return nil, ErrSecretoNoConfigurado
+	}
+
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		return g.claveVerificacion(t, tipo)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(emisor),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.Tipo != tipo || claims.ID == "" || claims.Familia == "" {
+		return nil, ErrTokenInvalido
+	}
+
+	return &claims, nil
+}
+
+// claveVerificacion elige la clave por el kid del token; el algoritmo debe ser el de la
+// clave para que un token no pueda usar una clave pública como secreto HS256
+func (g *GestorTokens) claveVerificacion(t *jwt.Token, tipo string) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		secreto := g.secreto(tipo)
+		if secreto == "" || t.Method != jwt.SigningMethodHS256 {
+			return nil, ErrTokenInvalido
+		}
+		return []byte(secreto), nil
+	}
+
+	if g.claves == nil {
+		return nil, ErrTokenInvalido
+	}
+	juego, err := g.juego()
+	if err != nil {
+		return nil, err
+	}
+	clave, ok := juego.vigente(kid)
+	if !ok || t.Method.Alg() != clave.metodo.Alg() {
+		return nil, ErrTokenInvalido
+	}
+	return clave.verificacion, nil
+}
+
+// VerificarAcceso valida la firma, la expiración y el tipo de un token de acceso
+func (g *GestorTokens) VerificarAcceso(token string) (*Claims, error) {
+	return g.verificar(token, TipoAcceso)
+}
+
+// VerificarRefresco valida la firma, la expiración y el tipo de un token de refresco
+func (g *GestorTokens) VerificarRefresco(token string) (*Claims, error) {
+	return g.verificar(token, TipoRefresco)
+}
@@ -0,0 +1,11 @@
+-- V000: control de las migraciones aplicadas (dbo.MigracionesApi)
+-- Las tablas propias de la API se crean con los scripts V*.sql de esta carpeta, en
+-- orden y con un usuario con permisos DDL (ver aplicar.bat). La API no ejecuta DDL: al
+-- iniciar solo verifica que las tablas existan y, si falta alguna, indica su migración.
+-- Cada script es idempotente y registra su versión en esta tabla.
+IF OBJECT_ID('dbo.MigracionesApi', 'U') IS NULL
+  CREATE TABLE dbo.MigracionesApi (
+    Version INT NOT NULL PRIMARY KEY,
+    Nombre VARCHAR(100) NOT NULL,
+    FechaAplicacion DATETIME NOT NULL DEFAULT GETDATE()
+  );
@@ -0,0 +1,26 @@
+-- V001: Prioridad del triaje calculada con las reglas versionadas (dbo.TriajeClasificacion)
+-- Idempotente: si la tabla ya existe (creada por una versión anterior de la API) solo
+-- registra la migración.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM dbo.MigracionesApi WHERE Version = 1)
+BEGIN
+  IF OBJECT_ID('dbo.TriajeClasificacion', 'U') IS NULL
+  BEGIN
+    CREATE TABLE dbo.TriajeClasificacion (
+      IdAtencion INT NOT NULL PRIMARY KEY,
+      Nivel TINYINT NOT NULL,
+      IdRegla VARCHAR(50) NULL,
+      DescripcionRegla VARCHAR(250) NULL,
+      VersionReglas VARCHAR(20) NOT NULL,
+      MotivoConsulta VARCHAR(500) NULL,
+      Discriminadores VARCHAR(500) NULL,
+      FechaRegistro DATETIME NOT NULL DEFAULT GETDATE()
+    );
+  END
+
+  INSERT INTO dbo.MigracionesApi (Version, Nombre) VALUES (1, 'triaje_clasificacion');
+END
+
+COMMIT TRANSACTION;
@@ -0,0 +1,28 @@
+-- V002: Resultados de hemoglobina ajustada por altitud (dbo.TamizajeAnemia)
+-- Idempotente: si la tabla ya existe (creada por una versión anterior de la API) solo
+-- registra la migración.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM dbo.MigracionesApi WHERE Version = 2)
+BEGIN
+  IF OBJECT_ID('dbo.TamizajeAnemia', 'U') IS NULL
+  BEGIN
+    CREATE TABLE dbo.TamizajeAnemia (
+      IdAtencion INT NOT NULL PRIMARY KEY,
+      IdPaciente INT NOT NULL,
+      Hemoglobina DECIMAL(4,1) NOT NULL,
+      AltitudMsnm INT NOT NULL,
+      FactorAjuste DECIMAL(3,1) NOT NULL,
+      HemoglobinaAjustada DECIMAL(4,1) NOT NULL,
+      Clasificacion VARCHAR(20) NOT NULL,
+      Gestante BIT NOT NULL DEFAULT 0,
+      IdUsuario INT NOT NULL,
+      FechaRegistro DATETIME NOT NULL DEFAULT GETDATE()
+    );
+  END
+
+  INSERT INTO dbo.MigracionesApi (Version, Nombre) VALUES (2, 'tamizaje_anemia');
+END
+
+COMMIT TRANSACTION;
@@ -0,0 +1,25 @@
+-- V003: Historial de revisiones del triaje con editor y motivo (dbo.TriajeRevision)
+-- Idempotente: si la tabla ya existe (creada por una versión anterior de la API) solo
+-- registra la migración.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM dbo.MigracionesApi WHERE Version = 3)
+BEGIN
+  IF OBJECT_ID('dbo.TriajeRevision', 'U') IS NULL
+  BEGIN
+    CREATE TABLE dbo.TriajeRevision (
+      IdAtencion INT NOT NULL,
+      NroRevision INT NOT NULL,
+      IdEmpleado INT NOT NULL,
+      Motivo VARCHAR(500) NULL,
+      Datos NVARCHAR(MAX) NOT NULL,
+      FechaRegistro DATETIME NOT NULL DEFAULT GETDATE(),
+      CONSTRAINT PK_TriajeRevision PRIMARY KEY (IdAtencion, NroRevision)
+    );
+  END
+
+  INSERT INTO dbo.MigracionesApi (Version, Nombre) VALUES (3, 'triaje_revision');
+END
+
+COMMIT TRANSACTION;
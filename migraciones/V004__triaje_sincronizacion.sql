@@ -0,0 +1,24 @@
+-- V004: Registros de triaje sincronizados desde los clientes fuera de línea (dbo.TriajeSincronizacion)
+-- Idempotente: si la tabla ya existe (creada por una versión anterior de la API) solo
+-- registra la migración.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM dbo.MigracionesApi WHERE Version = 4)
+BEGIN
+  IF OBJECT_ID('dbo.TriajeSincronizacion', 'U') IS NULL
+  BEGIN
+    CREATE TABLE dbo.TriajeSincronizacion (
+      Uuid CHAR(36) NOT NULL PRIMARY KEY,
+      IdAtencion INT NOT NULL,
+      NroRevision INT NOT NULL,
+      IdEmpleado INT NOT NULL,
+      FechaCaptura DATETIME NULL,
+      FechaRegistro DATETIME NOT NULL DEFAULT GETDATE()
+    );
+  END
+
+  INSERT INTO dbo.MigracionesApi (Version, Nombre) VALUES (4, 'triaje_sincronizacion');
+END
+
+COMMIT TRANSACTION;
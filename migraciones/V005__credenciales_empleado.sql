@@ -0,0 +1,25 @@
+-- V005: Hash bcrypt de las claves de los empleados de SIGH (dbo.CredencialesEmpleado)
+-- Guarda el hash bcrypt de la clave sin modificar Empleados.Clave, que sigue usando el
+-- sistema SIGH de escritorio. HuellaClaveSigh permite detectar que la clave fue cambiada
+-- en SIGH y debe volver a migrarse.
+-- Idempotente: si la tabla ya existe (creada por una versión anterior de la API) solo
+-- registra la migración.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM dbo.MigracionesApi WHERE Version = 5)
+BEGIN
+  IF OBJECT_ID('dbo.CredencialesEmpleado', 'U') IS NULL
+  BEGIN
+    CREATE TABLE dbo.CredencialesEmpleado (
+      IdEmpleado INT NOT NULL PRIMARY KEY,
+      HashClave VARCHAR(100) NOT NULL,
+      HuellaClaveSigh CHAR(64) NOT NULL,
+      FechaActualizacion DATETIME NOT NULL DEFAULT GETDATE()
+    );
+  END
+
+  INSERT INTO dbo.MigracionesApi (Version, Nombre) VALUES (5, 'credenciales_empleado');
+END
+
+COMMIT TRANSACTION;
@@ -0,0 +1,29 @@
+-- V006: Tokens de refresco y sus familias para la rotación y revocación de sesiones (dbo.SesionesRefresco)
+-- Idempotente: si la tabla ya existe (creada por una versión anterior de la API) solo
+-- registra la migración.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM dbo.MigracionesApi WHERE Version = 6)
+BEGIN
+  IF OBJECT_ID('dbo.SesionesRefresco', 'U') IS NULL
+  BEGIN
+    CREATE TABLE dbo.SesionesRefresco (
+      IdToken CHAR(36) NOT NULL PRIMARY KEY,
+      Familia CHAR(36) NOT NULL,
+      IdEmpleado INT NOT NULL,
+      FechaEmision DATETIME NOT NULL DEFAULT GETDATE(),
+      FechaExpiracion DATETIME NOT NULL,
+      Usado BIT NOT NULL DEFAULT 0,
+      FechaUso DATETIME NULL,
+      Revocado BIT NOT NULL DEFAULT 0,
+      FechaRevocacion DATETIME NULL
+    );
+    CREATE INDEX IX_SesionesRefresco_Familia ON dbo.SesionesRefresco (Familia);
+    CREATE INDEX IX_SesionesRefresco_IdEmpleado ON dbo.SesionesRefresco (IdEmpleado);
+  END
+
+  INSERT INTO dbo.MigracionesApi (Version, Nombre) VALUES (6, 'sesiones_refresco');
+END
+
+COMMIT TRANSACTION;
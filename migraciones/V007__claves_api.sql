@@ -0,0 +1,34 @@
+-- V007: Claves de API de las integraciones (dbo.ClavesApi)
+-- Guarda solo el hash SHA-256 de la parte secreta de cada clave; Prefijo es la parte
+-- pública que permite ubicarla y mostrarla en los listados.
+-- Idempotente: si la tabla ya existe (creada por una versión anterior de la API) solo
+-- registra la migración.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM dbo.MigracionesApi WHERE Version = 7)
+BEGIN
+  IF OBJECT_ID('dbo.ClavesApi', 'U') IS NULL
+  BEGIN
+    CREATE TABLE dbo.ClavesApi (
+      IdClave INT IDENTITY(1,1) NOT NULL PRIMARY KEY,
+      Prefijo CHAR(8) NOT NULL,
+      HashClave CHAR(64) NOT NULL,
+      Nombre NVARCHAR(100) NOT NULL,
+      Permisos NVARCHAR(1000) NOT NULL,
+      RangosIp NVARCHAR(1000) NOT NULL DEFAULT '',
+      FechaExpiracion DATETIME NOT NULL,
+      IdEmpleadoCreador INT NOT NULL,
+      FechaCreacion DATETIME NOT NULL DEFAULT GETDATE(),
+      FechaUltimoUso DATETIME NULL,
+      Revocada BIT NOT NULL DEFAULT 0,
+      IdEmpleadoRevoco INT NULL,
+      FechaRevocacion DATETIME NULL
+    );
+    CREATE UNIQUE INDEX UX_ClavesApi_Prefijo ON dbo.ClavesApi (Prefijo);
+  END
+
+  INSERT INTO dbo.MigracionesApi (Version, Nombre) VALUES (7, 'claves_api');
+END
+
+COMMIT TRANSACTION;
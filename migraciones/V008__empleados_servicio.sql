@@ -0,0 +1,26 @@
+-- V008: Personal y jefaturas de cada servicio para las suplencias (dbo.EmpleadosServicio)
+-- Asigna el personal a los servicios de SIGH (Servicios.IdServicio) y marca a las
+-- jefaturas, pues SIGH solo registra el servicio de los médicos programados. La mantiene
+-- la jefatura de informática.
+-- Idempotente: si la tabla ya existe (creada por una versión anterior de la API) solo
+-- registra la migración.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM dbo.MigracionesApi WHERE Version = 8)
+BEGIN
+  IF OBJECT_ID('dbo.EmpleadosServicio', 'U') IS NULL
+  BEGIN
+    CREATE TABLE dbo.EmpleadosServicio (
+      IdServicio INT NOT NULL,
+      IdEmpleado INT NOT NULL,
+      EsSupervisor BIT NOT NULL DEFAULT 0,
+      FechaRegistro DATETIME NOT NULL DEFAULT GETDATE(),
+      CONSTRAINT PK_EmpleadosServicio PRIMARY KEY (IdServicio, IdEmpleado)
+    );
+  END
+
+  INSERT INTO dbo.MigracionesApi (Version, Nombre) VALUES (8, 'empleados_servicio');
+END
+
+COMMIT TRANSACTION;
@@ -0,0 +1,25 @@
+@echo off
+REM --- Aplica en orden las migraciones V*.sql a la base de datos principal de SIGH ---
+REM Uso: migraciones\aplicar.bat SERVIDOR BASE_DE_DATOS [USUARIO]
+REM Sin USUARIO usa la autenticación de Windows; con USUARIO toma la contraseña de
+REM DB_PASSWORD. El usuario debe tener permisos DDL; la API no los necesita.
+setlocal
+
+if "%~2"=="" (
+  echo Uso: aplicar.bat SERVIDOR BASE_DE_DATOS [USUARIO]
+  exit /b 1
+)
+
+set AUTENTICACION=-E
+if not "%~3"=="" set AUTENTICACION=-U %~3 -P %DB_PASSWORD%
+
+for /f "delims=" %%f in ('dir /b /on "%~dp0V*.sql"') do (
+  echo Aplicando %%f
+  sqlcmd -S %1 -d %2 %AUTENTICACION% -b -i "%~dp0%%f"
+  if errorlevel 1 (
+    echo Error al aplicar %%f; se detienen las migraciones.
+    exit /b 1
+  )
+)
+
+echo Migraciones aplicadas.